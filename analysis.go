@@ -0,0 +1,103 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// AnalysisValues are audio analysis results measured by the
+// host for a single signal source. All values are in dB (LUFS
+// for loudness).
+type AnalysisValues struct {
+	RMS  float64
+	Peak float64
+	LUFS float64
+}
+
+// AnalysisProvider is a value provider feeding analysis results
+// measured by the host into expressions. For a source named "vox",
+// variables "rms_vox", "peak_vox" and "lufs_vox" are provided. It
+// is safe for concurrent use, so the host can update measurements
+// from its own threads.
+type AnalysisProvider struct {
+	mu     sync.Mutex
+	values map[string]AnalysisValues
+}
+
+// NewAnalysisProvider creates a new, empty analysis provider
+func NewAnalysisProvider() *AnalysisProvider {
+	return &AnalysisProvider{values: make(map[string]AnalysisValues)}
+}
+
+// Set updates analysis results for a source. Source name must be
+// a valid identifier, so that it can be used in variable names.
+func (ap *AnalysisProvider) Set(source string, v AnalysisValues) error {
+	if !isIdentifier(source) {
+		return fmt.Errorf("Source name '%v' is not a valid identifier", source)
+	}
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	ap.values[source] = v
+	return nil
+}
+
+// Remove removes analysis results for a source
+func (ap *AnalysisProvider) Remove(source string) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	delete(ap.values, source)
+}
+
+// Values implements ValueProvider
+func (ap *AnalysisProvider) Values() (map[string]interface{}, error) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	values := make(map[string]interface{})
+	for source, v := range ap.values {
+		values["rms_"+source] = v.RMS
+		values["peak_"+source] = v.Peak
+		values["lufs_"+source] = v.LUFS
+	}
+	return values, nil
+}
+
+func setUpLoudnessFuncs(lvc *LV2HostConfig) {
+	lvc.FunctionMap["lufs_gain"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 && len(args) != 4 {
+			return math.NaN(), fmt.Errorf("Function 'lufs_gain' expects 2 or 4 arguments")
+		}
+		vals := make([]float64, len(args))
+		for i, arg := range args {
+			v, err := convert.Float32(arg)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
+			}
+			vals[i] = float64(v)
+		}
+		gain := vals[1] - vals[0]
+		// don't let the gain push true peak over the ceiling
+		if len(vals) == 4 {
+			if margin := vals[3] - vals[2]; margin < gain {
+				gain = margin
+			}
+		}
+		return gain, nil
+	}
+	lvc.FunctionMap["tp_margin"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return math.NaN(), fmt.Errorf("Function 'tp_margin' expects exactly 2 arguments")
+		}
+		peak, err := convert.Float32(args[0])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
+		}
+		ceiling, err := convert.Float32(args[1])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[1])
+		}
+		return float64(ceiling) - float64(peak), nil
+	}
+}
@@ -0,0 +1,149 @@
+package lv2hostconfig_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+func TestMergeParameterMaps(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, `common: &common
+  gain: "1.0"
+  freq: "100"
+boost: &boost
+  gain: "6.0"
+  drive: "0.5"
+
+plugins:
+- pluginUri: urn:test:eq
+  parameters:
+    <<: *common
+    q: "0.7"
+- pluginUri: urn:test:eq
+  parameters:
+    <<: *common
+    gain: "2.0"
+- pluginUri: urn:test:eq
+  parameters:
+    <<: [*boost, *common]
+`)
+	lv2hostconfigtest.MustEvaluate(t, c)
+	lv2hostconfigtest.AssertValues(t, c, 0, map[string]float32{"gain": 1, "freq": 100, "q": 0.7}, 1e-6)
+	// explicit keys take precedence over merged ones
+	lv2hostconfigtest.AssertValues(t, c, 1, map[string]float32{"gain": 2, "freq": 100}, 1e-6)
+	// earlier mappings of a merge list take precedence over later ones
+	lv2hostconfigtest.AssertValues(t, c, 2, map[string]float32{"gain": 6, "freq": 100, "drive": 0.5}, 1e-6)
+	for i, n := range []int{3, 2, 3} {
+		if len(c.Plugins[i].Data) != n {
+			t.Errorf("Plugin %v has parameters %v, want %v of them", i, c.Plugins[i].Data, n)
+		}
+	}
+}
+
+func TestMergePluginEntries(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, `eq: &eq
+  pluginUri: urn:test:eq
+  label: EQ
+  parameters:
+    gain: "1.0"
+    freq: "100"
+
+plugins:
+- <<: *eq
+  name: left
+- <<: *eq
+  name: right
+  parameters:
+    gain: "3.0"
+`)
+	lv2hostconfigtest.MustEvaluate(t, c)
+	for i, name := range []string{"left", "right"} {
+		pc := c.Plugins[i]
+		if pc.Name != name || pc.PluginURI != "urn:test:eq" || pc.Label != "EQ" {
+			t.Errorf("Plugin %v is '%v' (%v, '%v'), want '%v' (urn:test:eq, 'EQ')", i, pc.Name, pc.PluginURI, pc.Label, name)
+		}
+	}
+	lv2hostconfigtest.AssertValues(t, c, 0, map[string]float32{"gain": 1, "freq": 100}, 1e-6)
+	// merges are shallow, so own parameters replace merged ones
+	lv2hostconfigtest.AssertValues(t, c, 1, map[string]float32{"gain": 3}, 1e-6)
+	if _, ok := c.Plugins[1].Data["freq"]; ok {
+		t.Errorf("Parameter 'freq' was merged into plugin with own parameters")
+	}
+}
+
+func TestAliasesAcrossPlugins(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, `plugins:
+- name: left
+  pluginUri: urn:test:eq
+  parameters: &params
+    gain: reference + 1
+    freq: "100"
+- name: right
+  pluginUri: urn:test:eq
+  parameters: *params
+- name: center
+  pluginUri: urn:test:eq
+  parameters: *params
+`)
+	lv2hostconfigtest.MustEvaluate(t, c)
+	for i := range c.Plugins {
+		lv2hostconfigtest.AssertValues(t, c, i, map[string]float32{"gain": 1, "freq": 100}, 1e-6)
+	}
+	// aliased values are copied, so plugins don't share them
+	c.Plugins[1].DataFmt["gain"] = "5"
+	lv2hostconfigtest.MustEvaluate(t, c)
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", 1, 1e-6)
+	lv2hostconfigtest.AssertValue(t, c, 1, "gain", 5, 1e-6)
+	lv2hostconfigtest.AssertValue(t, c, 2, "gain", 1, 1e-6)
+}
+
+func TestFieldMapThroughMerges(t *testing.T) {
+	c := lv2hostconfig.NewLV2HostConfig()
+	c.Fields = lv2hostconfig.FieldMap{
+		"pluginUri":  "uri",
+		"parameters": "params",
+		"plugins":    "chain",
+	}
+	_, err := c.ReadFrom(strings.NewReader(`base: &base
+  uri: urn:test:eq
+  params: &params
+    gain: "1.0"
+    freq: "100"
+more: &more
+  q: "0.7"
+
+chain:
+- <<: *base
+  name: first
+- <<: *base
+  name: second
+  params:
+    <<: [*params, *more]
+    gain: "2.0"
+`))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	lv2hostconfigtest.MustEvaluate(t, c)
+	if len(c.Plugins) != 2 {
+		t.Fatalf("Got %v plugins, want 2", len(c.Plugins))
+	}
+	for i := range c.Plugins {
+		if c.Plugins[i].PluginURI != "urn:test:eq" {
+			t.Errorf("Plugin %v has URI '%v', want 'urn:test:eq'", i, c.Plugins[i].PluginURI)
+		}
+	}
+	lv2hostconfigtest.AssertValues(t, c, 0, map[string]float32{"gain": 1, "freq": 100}, 1e-6)
+	lv2hostconfigtest.AssertValues(t, c, 1, map[string]float32{"gain": 2, "freq": 100, "q": 0.7}, 1e-6)
+	// renaming fields of merged entries must not change the
+	// anchored originals, which are kept as unrecognized fields
+	base, ok := c.Extras["base"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Anchored entry is %#v, want a mapping", c.Extras["base"])
+	}
+	if _, ok := base["uri"]; !ok {
+		t.Errorf("Anchored entry was renamed: %v", base)
+	}
+}
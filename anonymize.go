@@ -0,0 +1,96 @@
+package lv2hostconfig
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RedactedValue is what Anonymize replaces identifying strings with
+const RedactedValue = "redacted"
+
+// propertyRef matches variable references in string properties
+var propertyRef = regexp.MustCompile(`\$\$|\$\{[^}]*\}|\$[A-Za-z0-9_]+`)
+
+// redactProperty replaces everything but variable references in
+// a string property with placeholders
+func redactProperty(value string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range propertyRef.FindAllStringIndex(value, -1) {
+		if m[0] > last {
+			b.WriteString(RedactedValue)
+		}
+		b.WriteString(value[m[0]:m[1]])
+		last = m[1]
+	}
+	if last < len(value) {
+		b.WriteString(RedactedValue)
+	}
+	return b.String()
+}
+
+// redactExtra replaces all strings in an unrecognized field value
+func redactExtra(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return RedactedValue
+	case map[string]interface{}:
+		for k, mv := range t {
+			t[k] = redactExtra(mv)
+		}
+	case map[interface{}]interface{}:
+		for k, mv := range t {
+			t[k] = redactExtra(mv)
+		}
+	case []interface{}:
+		for i, mv := range t {
+			t[i] = redactExtra(mv)
+		}
+	}
+	return v
+}
+
+// anonymizePlugin strips identifying data from a plugin config
+func anonymizePlugin(pc *LV2PluginConfig) {
+	pc.source = nil
+	pc.Notes = ""
+	pc.ParamNotes = nil
+	for k, v := range pc.PropertiesFmt {
+		pc.PropertiesFmt[k] = redactProperty(v)
+	}
+	for k := range pc.Properties {
+		pc.Properties[k] = RedactedValue
+	}
+	pc.Extras = redactExtra(pc.Extras).(map[string]interface{})
+}
+
+// Anonymize returns a copy of the config that is safe to attach
+// to bug reports. Notes (including comments of the file config was
+// read from) are stripped, string values of variables and of
+// unrecognized fields are replaced with placeholders, as are string
+// properties apart from variable references in them. Environment
+// the config was produced in is dropped, and is not captured again
+// when the copy is written. Numeric
+// values, expressions, plugin URIs and the structure of the config
+// are kept as is, so that the problem can still be reproduced.
+func (c *LV2HostConfig) Anonymize() *LV2HostConfig {
+	nc := c.Clone()
+	nc.source = nil
+	nc.Environment = nil
+	nc.RecordEnvironment = false
+	for k, v := range nc.ValueMap {
+		if _, ok := v.(string); ok {
+			nc.ValueMap[k] = RedactedValue
+		}
+	}
+	nc.Extras = redactExtra(nc.Extras).(map[string]interface{})
+	for i := range nc.Plugins {
+		anonymizePlugin(&nc.Plugins[i])
+	}
+	for _, pcs := range nc.Chains {
+		for i := range pcs {
+			anonymizePlugin(&pcs[i])
+		}
+	}
+	return nc
+}
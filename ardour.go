@@ -0,0 +1,123 @@
+package lv2hostconfig
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ardourSession is the part of Ardour session file describing
+// plugins of routes (tracks and busses)
+type ardourSession struct {
+	Routes []ardourRoute `xml:"Routes>Route"`
+}
+
+type ardourRoute struct {
+	Name       string            `xml:"name,attr"`
+	Processors []ardourProcessor `xml:"Processor"`
+}
+
+type ardourProcessor struct {
+	Name     string       `xml:"name,attr"`
+	Type     string       `xml:"type,attr"`
+	UniqueID string       `xml:"unique-id,attr"`
+	Active   string       `xml:"active,attr"`
+	Ports    []ardourPort `xml:"lv2>Port"`
+}
+
+type ardourPort struct {
+	Symbol string `xml:"symbol,attr"`
+	Value  string `xml:"value,attr"`
+}
+
+// readArdour parses an Ardour session file
+func readArdour(file string) (*ardourSession, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read Ardour session: %v", err)
+	}
+	defer f.Close()
+	var session ardourSession
+	if err := xml.NewDecoder(f).Decode(&session); err != nil {
+		return nil, fmt.Errorf("Failed to parse Ardour session: %v", err)
+	}
+	return &session, nil
+}
+
+// lv2Processors returns active LV2 plugins of a route
+func (r *ardourRoute) lv2Processors() []ardourProcessor {
+	procs := make([]ardourProcessor, 0)
+	for _, p := range r.Processors {
+		if p.Type == "lv2" && p.Active != "0" {
+			procs = append(procs, p)
+		}
+	}
+	return procs
+}
+
+// ArdourRoutes returns names of routes (tracks and busses) of an
+// Ardour session that have LV2 plugins, in session order
+func ArdourRoutes(file string) ([]string, error) {
+	session, err := readArdour(file)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for i := range session.Routes {
+		if len(session.Routes[i].lv2Processors()) != 0 {
+			names = append(names, session.Routes[i].Name)
+		}
+	}
+	return names, nil
+}
+
+// ReadArdour reads plugin chain of a route (track or bus) from an
+// Ardour session file (.ardour), for migrating plugin settings out
+// of Ardour. Active LV2 plugins of the route become plugins of the
+// chain, in the order Ardour runs them, each labeled with its name in
+// Ardour and with port values of the session as parameters. Other
+// processors, and plugins that are deactivated, are left out. Just
+// like ReadFile, this replaces any plugins already in the config,
+// and the values are not evaluated until Evaluate is called.
+func (c *LV2HostConfig) ReadArdour(file, route string) error {
+	session, err := readArdour(file)
+	if err != nil {
+		return err
+	}
+	var r *ardourRoute
+	for i := range session.Routes {
+		if session.Routes[i].Name == route {
+			r = &session.Routes[i]
+			break
+		}
+	}
+	if r == nil {
+		return fmt.Errorf("Failed to parse Ardour session: route '%v' not found in '%v'", route, file)
+	}
+
+	// parsing should be atomic, so operate on a copy
+	pcs := make([]LV2PluginConfig, 0)
+	for _, p := range r.lv2Processors() {
+		if p.UniqueID == "" {
+			return fmt.Errorf("Failed to parse Ardour session: plugin '%v' has no URI", p.Name)
+		}
+		pc := NewLV2PluginConfig()
+		pc.PluginURI = p.UniqueID
+		pc.Label = p.Name
+		for _, port := range p.Ports {
+			if _, err := strconv.ParseFloat(port.Value, 32); err != nil {
+				return fmt.Errorf("Failed to parse Ardour session: plugin '%v' has invalid value '%v' of port '%v'",
+					p.Name, port.Value, port.Symbol)
+			}
+			if _, ok := pc.DataFmt[port.Symbol]; !ok {
+				pc.ParamOrder = append(pc.ParamOrder, port.Symbol)
+			}
+			pc.DataFmt[port.Symbol] = port.Value
+		}
+		pcs = append(pcs, pc)
+	}
+
+	c.Plugins = pcs
+	return nil
+}
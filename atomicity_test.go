@@ -0,0 +1,219 @@
+package lv2hostconfig_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+const goodConfig = `referenceLevel: -18.5
+plugins:
+  - name: eq
+    pluginUri: urn:test:eq
+    parameters:
+      gain: reference + 3
+      freq: 1000
+  - name: comp
+    pluginUri: urn:test:comp
+    parameters:
+      threshold: -20
+`
+
+// writeTemp writes a file into a temporary directory of the test
+func writeTemp(t *testing.T, name, data string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "lv2hostconfig")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	return file
+}
+
+// evaluatedConfig returns a config read from goodConfig and
+// evaluated, with an extra variable set
+func evaluatedConfig(t *testing.T) *lv2hostconfig.LV2HostConfig {
+	t.Helper()
+	c := lv2hostconfigtest.Parse(t, goodConfig)
+	c.ValueMap["extra"] = 2.0
+	lv2hostconfigtest.MustEvaluate(t, c)
+	return c
+}
+
+// assertUntouched checks that plugins and ValueMap of c are the
+// same as those of want
+func assertUntouched(t *testing.T, c, want *lv2hostconfig.LV2HostConfig) {
+	t.Helper()
+	if !reflect.DeepEqual(c.Plugins, want.Plugins) {
+		t.Errorf("Plugins were modified: got %+v, want %+v", c.Plugins, want.Plugins)
+	}
+	if !reflect.DeepEqual(c.ValueMap, want.ValueMap) {
+		t.Errorf("ValueMap was modified: got %v, want %v", c.ValueMap, want.ValueMap)
+	}
+}
+
+func TestReadFileFailureKeepsConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"malformed.yaml", "plugins: [\n"},
+		{"badoutput.yaml", "plugins:\n  - pluginUri: urn:test:eq\n    outputs:\n      level: loud\n"},
+		{"badtype.yaml", "plugins:\n  - pluginUri: urn:test:eq\n    types:\n      gain: complex\n"},
+		{"badreference.yaml", "referenceLevel: loud\nplugins: []\n"},
+		{"malformed.toml", "[[plugins]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := evaluatedConfig(t)
+			want := c.Clone()
+			if err := c.ReadFile(writeTemp(t, tt.name, tt.data)); err == nil {
+				t.Fatalf("Reading '%v' succeeded", tt.name)
+			}
+			assertUntouched(t, c, want)
+		})
+	}
+}
+
+func TestReadFileMissingKeepsConfig(t *testing.T) {
+	c := evaluatedConfig(t)
+	want := c.Clone()
+	if err := c.ReadFile(filepath.Join(os.TempDir(), "lv2hostconfig-missing.yaml")); err == nil {
+		t.Fatal("Reading missing file succeeded")
+	}
+	assertUntouched(t, c, want)
+}
+
+func TestEvaluateFailureKeepsValues(t *testing.T) {
+	tests := []struct {
+		plugin int
+		symbol string
+		expr   string
+	}{
+		{1, "threshold", "unknown_var * 2"},
+		{1, "threshold", "1 +"},
+		{0, "freq", "sqrt(1, 2)"},
+		{1, "threshold", "[eq.missing]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c := evaluatedConfig(t)
+			c.Plugins[tt.plugin].DataFmt[tt.symbol] = tt.expr
+			want := c.Clone()
+			err := c.Evaluate()
+			if err == nil {
+				t.Fatalf("Evaluating '%v' succeeded", tt.expr)
+			}
+			var ce *lv2hostconfig.ConfigError
+			if !errors.As(err, &ce) {
+				t.Fatalf("Error '%v' is not a ConfigError", err)
+			}
+			if ce.Param != tt.symbol {
+				t.Errorf("Error is about parameter '%v', want '%v'", ce.Param, tt.symbol)
+			}
+			assertUntouched(t, c, want)
+			lv2hostconfigtest.AssertValue(t, c, 0, "gain", -15.5, 1e-6)
+			lv2hostconfigtest.AssertValue(t, c, 1, "threshold", -20, 1e-6)
+		})
+	}
+}
+
+func TestReadINIFailureKeepsConfig(t *testing.T) {
+	uris := map[string]string{"eq": "urn:test:eq"}
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"section.ini", "[eq\ngain=1\n"},
+		{"noeq.ini", "eq.gain\n"},
+		{"nouri.ini", "eq.gain=1\ncomp.threshold=-20\n"},
+		{"twice.ini", "eq.gain=1\n[eq]\ngain=2\n"},
+		{"noref.ini", "gain=1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := evaluatedConfig(t)
+			want := c.Clone()
+			if err := c.ReadINI(writeTemp(t, tt.name, tt.data), uris); err == nil {
+				t.Fatalf("Reading '%v' succeeded", tt.name)
+			}
+			assertUntouched(t, c, want)
+		})
+	}
+}
+
+func TestReadCarlaFailureKeepsConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"malformed.carxp", "<CARLA-PROJECT><Plugin>"},
+		{"nouri.carxp", `<CARLA-PROJECT>
+ <Plugin><Info><Type>LV2</Type><Name>EQ</Name><URI>urn:test:eq</URI></Info></Plugin>
+ <Plugin><Info><Type>LV2</Type><Name>Comp</Name></Info></Plugin>
+</CARLA-PROJECT>`},
+		{"badvalue.carxp", `<CARLA-PROJECT>
+ <Plugin><Info><Type>LV2</Type><Name>EQ</Name><URI>urn:test:eq</URI></Info>
+  <Data><Parameter><Symbol>gain</Symbol><Value>loud</Value></Parameter></Data></Plugin>
+</CARLA-PROJECT>`},
+		{"nosymbol.carxp", `<CARLA-PROJECT>
+ <Plugin><Info><Type>LV2</Type><Name>EQ</Name><URI>urn:test:eq</URI></Info>
+  <Data><Parameter><Value>1</Value></Parameter></Data></Plugin>
+</CARLA-PROJECT>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := evaluatedConfig(t)
+			want := c.Clone()
+			if err := c.ReadCarla(writeTemp(t, tt.name, tt.data)); err == nil {
+				t.Fatalf("Reading '%v' succeeded", tt.name)
+			}
+			assertUntouched(t, c, want)
+		})
+	}
+}
+
+func TestReferenceLevel(t *testing.T) {
+	c := evaluatedConfig(t)
+	// reference is stored as float64, so that expressions can do
+	// arithmetic on it
+	if ref, ok := c.ValueMap["reference"].(float64); !ok || ref != -18.5 {
+		t.Fatalf("Reference is %#v, want float64 -18.5", c.ValueMap["reference"])
+	}
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", -15.5, 1e-6)
+
+	data, err := c.WriteBytes()
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	nc := lv2hostconfig.NewLV2HostConfig()
+	if err := nc.ReadBytes(data); err != nil {
+		t.Fatalf("Failed to read written config: %v", err)
+	}
+	if !reflect.DeepEqual(nc.ValueMap["reference"], c.ValueMap["reference"]) {
+		t.Errorf("Reference is %#v after round trip, want %#v", nc.ValueMap["reference"], c.ValueMap["reference"])
+	}
+
+	// a failed read must not reset reference level to zero
+	if err := nc.ReadBytes([]byte("referenceLevel: -6\nplugins: [\n")); err == nil {
+		t.Fatal("Reading malformed config succeeded")
+	}
+	if nc.ValueMap["reference"] != -18.5 {
+		t.Errorf("Reference is %#v after failed read, want -18.5", nc.ValueMap["reference"])
+	}
+
+	// a config without referenceLevel has reference at zero
+	nc = lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      gain: reference - 3\n")
+	lv2hostconfigtest.MustEvaluate(t, nc)
+	lv2hostconfigtest.AssertValue(t, nc, 0, "gain", -3, 1e-6)
+}
@@ -0,0 +1,126 @@
+package lv2hostconfig
+
+import (
+	"sync"
+
+	"github.com/Knetic/govaluate"
+)
+
+// DefaultAuditLimit is the number of entries audit log keeps,
+// unless told otherwise
+const DefaultAuditLimit = 1024
+
+// AuditEntry is a single function call made while evaluating
+// an expression
+type AuditEntry struct {
+	Expression string
+	Function   string
+	Args       []interface{}
+}
+
+// AuditLog records function calls made by expressions during
+// evaluation, so that operators can find out which functions
+// a third-party config actually used. Log is cleared on every
+// Evaluate. At most Limit entries are kept, and the rest are
+// only counted. If Redact is set, arguments are passed through
+// it before being recorded.
+type AuditLog struct {
+	Limit  int
+	Redact func(function string, args []interface{}) []interface{}
+
+	mu      sync.Mutex
+	entries []AuditEntry
+	dropped int
+}
+
+// NewAuditLog creates new audit log keeping at most limit entries
+func NewAuditLog(limit int) *AuditLog {
+	return &AuditLog{Limit: limit}
+}
+
+// clone makes a copy of the log, so that calls made by a copy of
+// the config are recorded separately
+func (a *AuditLog) clone() *AuditLog {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &AuditLog{
+		Limit:   a.Limit,
+		Redact:  a.Redact,
+		entries: append([]AuditEntry(nil), a.entries...),
+		dropped: a.dropped,
+	}
+}
+
+func (a *AuditLog) record(expr string, function string, args []interface{}) {
+	args = append([]interface{}(nil), args...)
+	if a.Redact != nil {
+		args = a.Redact(function, args)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	limit := a.Limit
+	if limit <= 0 {
+		limit = DefaultAuditLimit
+	}
+	if len(a.entries) >= limit {
+		a.dropped++
+		return
+	}
+	a.entries = append(a.entries, AuditEntry{expr, function, args})
+}
+
+// Entries returns recorded function calls, in order they were made
+func (a *AuditLog) Entries() []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AuditEntry(nil), a.entries...)
+}
+
+// Dropped returns the number of calls that were not recorded
+// because the log was full
+func (a *AuditLog) Dropped() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+// Called checks if any of the recorded calls were made to function
+func (a *AuditLog) Called(function string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, e := range a.entries {
+		if e.Function == function {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset clears the log
+func (a *AuditLog) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = nil
+	a.dropped = 0
+}
+
+// auditedFunctions wraps config's functions so that calls made by
+// an expression are recorded in the audit log
+func (c *LV2HostConfig) auditedFunctions(expr string) map[string]govaluate.ExpressionFunction {
+	if c.Audit == nil {
+		return c.functions()
+	}
+	a := c.Audit
+	funcs := make(map[string]govaluate.ExpressionFunction, len(c.FunctionMap))
+	for name, f := range c.functions() {
+		name, f := name, f
+		funcs[name] = func(args ...interface{}) (interface{}, error) {
+			a.record(expr, name, args)
+			return f(args...)
+		}
+	}
+	return funcs
+}
@@ -0,0 +1,29 @@
+package lv2hostconfig_test
+
+import (
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+func TestCloneHasOwnAuditLog(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      gain: decibel(1)\n")
+	c.Audit = lv2hostconfig.NewAuditLog(0)
+	lv2hostconfigtest.MustEvaluate(t, c)
+	nc := c.Clone()
+	if nc.Audit == c.Audit {
+		t.Fatal("Clone shares audit log with the original")
+	}
+	nc.Plugins[0].DataFmt["gain"] = "linear(0)"
+	lv2hostconfigtest.MustEvaluate(t, nc)
+	if c.Audit.Called("linear") {
+		t.Error("Call made by the clone was recorded in log of the original")
+	}
+	if !c.Audit.Called("decibel") {
+		t.Error("Call made by the original is missing from its log")
+	}
+	if !nc.Audit.Called("linear") {
+		t.Error("Call made by the clone is missing from its log")
+	}
+}
@@ -0,0 +1,139 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BatchOptions controls what ProcessDir does with config files.
+// Each file is read (and migrated, if Migrate is set), then
+// optionally evaluated, and then optionally written back.
+type BatchOptions struct {
+	// NewConfig creates config each file is read into, so that
+	// values, functions and validators can be set up. Defaults
+	// to NewLV2HostConfig.
+	NewConfig func() *LV2HostConfig
+
+	// Extensions of files to process, defaults to .yaml, .yml,
+	// .json and .toml
+	Extensions []string
+
+	// Migrate, if set, is called on every config after reading
+	// it, e.g. to rename parameters of upgraded plugins
+	Migrate func(c *LV2HostConfig) error
+
+	// Evaluate makes sure each config evaluates and passes
+	// validators
+	Evaluate bool
+
+	// Write writes each config back to its file, which also
+	// reformats it. If Convert is set, config is written in
+	// Format to a file with the same name and the extension
+	// of that format instead, and original file is kept.
+	Write   bool
+	Convert bool
+	Format  Format
+}
+
+// BatchResult is the outcome of processing a single file. Output
+// is the file config was written to, if any.
+type BatchResult struct {
+	File   string
+	Output string
+	Err    error
+}
+
+// defaultBatchExtensions are extensions of config files
+var defaultBatchExtensions = []string{".yaml", ".yml", ".json", ".toml"}
+
+// formatExtension returns file extension used by a format
+func formatExtension(format Format) string {
+	switch format {
+	case FormatJSON:
+		return ".json"
+	case FormatTOML:
+		return ".toml"
+	}
+	return ".yaml"
+}
+
+// processFile processes a single config file
+func processFile(file string, opts *BatchOptions) BatchResult {
+	res := BatchResult{File: file}
+	c := opts.NewConfig()
+	if err := c.ReadFile(file); err != nil {
+		res.Err = err
+		return res
+	}
+	if opts.Migrate != nil {
+		if err := opts.Migrate(c); err != nil {
+			res.Err = fmt.Errorf("Failed to migrate config: %v", err)
+			return res
+		}
+	}
+	if opts.Evaluate {
+		if err := c.Evaluate(); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+	if !opts.Write {
+		return res
+	}
+	out, format := file, FormatOf(file)
+	if opts.Convert {
+		format = opts.Format
+		out = strings.TrimSuffix(file, filepath.Ext(file)) + formatExtension(format)
+	}
+	if err := c.writeFile(out, format); err != nil {
+		res.Err = err
+		return res
+	}
+	res.Output = out
+	return res
+}
+
+// ProcessDir validates, migrates, reformats or converts every config
+// file under a directory tree, in lexical order. Files are processed
+// independently, and failure to process one of them doesn't stop
+// processing of the others, so result is reported for every file.
+// Error is only returned if the directory tree can't be walked.
+func ProcessDir(path string, opts BatchOptions) ([]BatchResult, error) {
+	if opts.NewConfig == nil {
+		opts.NewConfig = NewLV2HostConfig
+	}
+	exts := opts.Extensions
+	if len(exts) == 0 {
+		exts = defaultBatchExtensions
+	}
+
+	// collect files first, so that converted files are not picked up
+	files := make([]string, 0)
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(file))
+		for _, e := range exts {
+			if ext == strings.ToLower(e) {
+				files = append(files, file)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to process directory: %v", err)
+	}
+
+	results := make([]BatchResult, 0, len(files))
+	for _, file := range files {
+		results = append(results, processFile(file, &opts))
+	}
+	return results, nil
+}
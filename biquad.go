@@ -0,0 +1,112 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// biquadCoeffs are biquad filter coefficients, normalized so that
+// a0 is 1. Sign convention is that of RBJ's Audio EQ Cookbook:
+//
+//	y[n] = b0*x[n] + b1*x[n-1] + b2*x[n-2] - a1*y[n-1] - a2*y[n-2]
+type biquadCoeffs struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+// designBiquad computes filter coefficients according to RBJ's
+// Audio EQ Cookbook. Gain (in dB) is only used by peaking and
+// shelving filters.
+func designBiquad(filter string, freq, q, gain, sampleRate float64) (biquadCoeffs, error) {
+	if sampleRate <= 0 {
+		return biquadCoeffs{}, fmt.Errorf("Sample rate '%v' is invalid", sampleRate)
+	}
+	if freq <= 0 || freq >= sampleRate/2 {
+		return biquadCoeffs{}, fmt.Errorf("Frequency '%v' is not within range '0-%v'", freq, sampleRate/2)
+	}
+	if q <= 0 {
+		return biquadCoeffs{}, fmt.Errorf("Q '%v' is invalid", q)
+	}
+	A := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * freq / sampleRate
+	cosw := math.Cos(w0)
+	alpha := math.Sin(w0) / (2 * q)
+
+	var b0, b1, b2, a0, a1, a2 float64
+	switch filter {
+	case "lowpass":
+		b0, b1, b2 = (1-cosw)/2, 1-cosw, (1-cosw)/2
+		a0, a1, a2 = 1+alpha, -2*cosw, 1-alpha
+	case "highpass":
+		b0, b1, b2 = (1+cosw)/2, -(1 + cosw), (1+cosw)/2
+		a0, a1, a2 = 1+alpha, -2*cosw, 1-alpha
+	case "bandpass":
+		b0, b1, b2 = alpha, 0, -alpha
+		a0, a1, a2 = 1+alpha, -2*cosw, 1-alpha
+	case "notch":
+		b0, b1, b2 = 1, -2*cosw, 1
+		a0, a1, a2 = 1+alpha, -2*cosw, 1-alpha
+	case "allpass":
+		b0, b1, b2 = 1-alpha, -2*cosw, 1+alpha
+		a0, a1, a2 = 1+alpha, -2*cosw, 1-alpha
+	case "peak":
+		b0, b1, b2 = 1+alpha*A, -2*cosw, 1-alpha*A
+		a0, a1, a2 = 1+alpha/A, -2*cosw, 1-alpha/A
+	case "lowshelf":
+		sq := 2 * math.Sqrt(A) * alpha
+		b0 = A * ((A + 1) - (A-1)*cosw + sq)
+		b1 = 2 * A * ((A - 1) - (A+1)*cosw)
+		b2 = A * ((A + 1) - (A-1)*cosw - sq)
+		a0 = (A + 1) + (A-1)*cosw + sq
+		a1 = -2 * ((A - 1) + (A+1)*cosw)
+		a2 = (A + 1) + (A-1)*cosw - sq
+	case "highshelf":
+		sq := 2 * math.Sqrt(A) * alpha
+		b0 = A * ((A + 1) + (A-1)*cosw + sq)
+		b1 = -2 * A * ((A - 1) + (A+1)*cosw)
+		b2 = A * ((A + 1) + (A-1)*cosw - sq)
+		a0 = (A + 1) - (A-1)*cosw + sq
+		a1 = 2 * ((A - 1) - (A+1)*cosw)
+		a2 = (A + 1) - (A-1)*cosw - sq
+	default:
+		return biquadCoeffs{}, fmt.Errorf("Unknown filter type '%v'", filter)
+	}
+	return biquadCoeffs{b0 / a0, b1 / a0, b2 / a0, a1 / a0, a2 / a0}, nil
+}
+
+// biquadFunc makes an expression function returning one of the
+// biquad coefficients
+func biquadFunc(name string, coeff func(biquadCoeffs) float64) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 5 {
+			return math.NaN(), fmt.Errorf("Function '%v' expects exactly 5 arguments", name)
+		}
+		filter, ok := args[0].(string)
+		if !ok {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a string", args[0])
+		}
+		var freq, q, gain, sampleRate float32
+		floatPtrs := []*float32{&freq, &q, &gain, &sampleRate}
+		for i, arg := range args[1:] {
+			v, err := convert.Float32(arg)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
+			}
+			*floatPtrs[i] = v
+		}
+		c, err := designBiquad(filter, float64(freq), float64(q), float64(gain), float64(sampleRate))
+		if err != nil {
+			return math.NaN(), err
+		}
+		return coeff(c), nil
+	}
+}
+
+func setUpBiquadFuncs(lvc *LV2HostConfig) {
+	lvc.FunctionMap["biquad_b0"] = biquadFunc("biquad_b0", func(c biquadCoeffs) float64 { return c.b0 })
+	lvc.FunctionMap["biquad_b1"] = biquadFunc("biquad_b1", func(c biquadCoeffs) float64 { return c.b1 })
+	lvc.FunctionMap["biquad_b2"] = biquadFunc("biquad_b2", func(c biquadCoeffs) float64 { return c.b2 })
+	lvc.FunctionMap["biquad_a1"] = biquadFunc("biquad_a1", func(c biquadCoeffs) float64 { return c.a1 })
+	lvc.FunctionMap["biquad_a2"] = biquadFunc("biquad_a2", func(c biquadCoeffs) float64 { return c.a2 })
+}
@@ -0,0 +1,129 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// EvalTiming is time it took to evaluate a single value
+type EvalTiming struct {
+	Plugin     int
+	Symbol     string
+	Expression string
+	Duration   time.Duration
+}
+
+func (t EvalTiming) String() string {
+	return fmt.Sprintf("plugin %v value '%v' ('%v'): %v", t.Plugin, t.Symbol, t.Expression, t.Duration)
+}
+
+// BudgetError is returned when evaluation takes longer than
+// allowed by ParamBudget or TotalBudget. Slowest lists values
+// that took the longest to evaluate before evaluation stopped.
+type BudgetError struct {
+	Timing  EvalTiming
+	Budget  time.Duration
+	Total   bool
+	Slowest []EvalTiming
+}
+
+func (e *BudgetError) Error() string {
+	if e.Total {
+		return fmt.Sprintf("Evaluation exceeded total budget of %v at plugin %v value '%v'", e.Budget, e.Timing.Plugin, e.Timing.Symbol)
+	}
+	return fmt.Sprintf("Evaluation of plugin %v value '%v' ('%v') exceeded budget of %v", e.Timing.Plugin, e.Timing.Symbol, e.Timing.Expression, e.Budget)
+}
+
+// number of slowest values reported in budget errors
+const budgetSlowest = 5
+
+// evalRun keeps track of time spent in a single evaluation
+type evalRun struct {
+	paramBudget time.Duration
+	totalBudget time.Duration
+	start       time.Time
+	plugin      int
+	timings     []EvalTiming
+}
+
+func (c *LV2HostConfig) newEvalRun() *evalRun {
+	return &evalRun{
+		paramBudget: c.ParamBudget,
+		totalBudget: c.TotalBudget,
+		start:       c.now(),
+		timings:     make([]EvalTiming, 0),
+	}
+}
+
+// slowest returns n slowest timings, slowest first
+func slowest(timings []EvalTiming, n int) []EvalTiming {
+	res := append([]EvalTiming(nil), timings...)
+	sort.SliceStable(res, func(a, b int) bool { return res[a].Duration > res[b].Duration })
+	if n >= 0 && len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
+
+type evalResult struct {
+	value float32
+	err   error
+}
+
+// evaluateTimed evaluates a single value, keeping track of time
+// it took and enforcing evaluation budgets. Go has no way of
+// stopping a running function, so an expression that runs over
+// budget keeps running in the background, but its result is
+// discarded. Timings are measured with Clock of the config.
+func (c *LV2HostConfig) evaluateTimed(run *evalRun, symbol string, value string, vars map[string]interface{}) (float32, error) {
+	t := EvalTiming{Plugin: run.plugin, Symbol: symbol, Expression: value}
+
+	budget := run.paramBudget
+	total := false
+	if run.totalBudget > 0 {
+		left := run.totalBudget - c.now().Sub(run.start)
+		if left <= 0 {
+			return 0, &BudgetError{t, run.totalBudget, true, slowest(run.timings, budgetSlowest)}
+		}
+		if budget <= 0 || left < budget {
+			budget = left
+			total = true
+		}
+	}
+
+	start := c.now()
+	var res evalResult
+	if budget <= 0 {
+		res.value, res.err = c.evaluateExpr(value, vars)
+	} else {
+		done := make(chan evalResult, 1)
+		go func() {
+			v, err := c.evaluateExpr(value, vars)
+			done <- evalResult{v, err}
+		}()
+		timer := time.NewTimer(budget)
+		defer timer.Stop()
+		select {
+		case res = <-done:
+		case <-timer.C:
+			t.Duration = c.now().Sub(start)
+			run.timings = append(run.timings, t)
+			limit := run.paramBudget
+			if total {
+				limit = run.totalBudget
+			}
+			return 0, &BudgetError{t, limit, total, slowest(run.timings, budgetSlowest)}
+		}
+	}
+	t.Duration = c.now().Sub(start)
+	run.timings = append(run.timings, t)
+	return res.value, res.err
+}
+
+// Timings returns time it took to evaluate each value during
+// last successful evaluation, slowest first. If n is not
+// negative, only n slowest values are returned.
+func (c *LV2HostConfig) Timings(n int) []EvalTiming {
+	return slowest(c.timings, n)
+}
@@ -0,0 +1,53 @@
+package lv2hostconfig_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+// tickingConfig returns a config whose parameters take a second
+// each to evaluate, according to its fake clock
+func tickingConfig(t *testing.T) *lv2hostconfig.LV2HostConfig {
+	t.Helper()
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      a: tick(1)\n      b: tick(2)\n      c: tick(3)\n")
+	clock := lv2hostconfigtest.NewFakeClock(time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC))
+	c.Clock = clock
+	err := c.RegisterFunction("tick", func(args ...interface{}) (interface{}, error) {
+		clock.Advance(time.Second)
+		return args[0], nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register function: %v", err)
+	}
+	return c
+}
+
+func TestBudgetTimingsUseClock(t *testing.T) {
+	c := tickingConfig(t)
+	lv2hostconfigtest.MustEvaluate(t, c)
+	timings := c.Timings(-1)
+	if len(timings) != 3 {
+		t.Fatalf("Got %v timings, want 3", len(timings))
+	}
+	for _, tm := range timings {
+		if tm.Duration != time.Second {
+			t.Errorf("Value '%v' took %v, want %v", tm.Symbol, tm.Duration, time.Second)
+		}
+	}
+}
+
+func TestTotalBudgetUsesClock(t *testing.T) {
+	c := tickingConfig(t)
+	c.TotalBudget = 1500 * time.Millisecond
+	var be *lv2hostconfig.BudgetError
+	if err := c.Evaluate(); !errors.As(err, &be) {
+		t.Fatalf("Evaluating returned '%v', want a BudgetError", err)
+	}
+	if !be.Total || be.Timing.Symbol != "c" {
+		t.Errorf("Budget was exceeded at '%v' (total %v), want total budget exceeded at 'c'", be.Timing.Symbol, be.Total)
+	}
+}
@@ -0,0 +1,70 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// speedOfSound returns speed of sound in dry air (in m/s) at given
+// temperature (in degrees Celsius)
+func speedOfSound(celsius float64) (float64, error) {
+	if celsius <= -273.15 {
+		return math.NaN(), fmt.Errorf("Temperature '%v' is below absolute zero", celsius)
+	}
+	return 331.3 * math.Sqrt(1+celsius/273.15), nil
+}
+
+// floatArgs converts all function arguments to floats
+func floatArgs(args []interface{}) ([]float64, error) {
+	vals := make([]float64, len(args))
+	for i, arg := range args {
+		v, err := convert.Float32(arg)
+		if err != nil {
+			return nil, fmt.Errorf("Value '%v' was not a float", arg)
+		}
+		vals[i] = float64(v)
+	}
+	return vals, nil
+}
+
+func setUpCalibrationFuncs(lvc *LV2HostConfig) {
+	lvc.FunctionMap["spl_gain"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return math.NaN(), fmt.Errorf("Function 'spl_gain' expects exactly 2 arguments")
+		}
+		vals, err := floatArgs(args)
+		if err != nil {
+			return math.NaN(), err
+		}
+		return vals[1] - vals[0], nil
+	}
+	lvc.FunctionMap["speed_of_sound"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return math.NaN(), fmt.Errorf("Function 'speed_of_sound' expects exactly 1 argument")
+		}
+		vals, err := floatArgs(args)
+		if err != nil {
+			return math.NaN(), err
+		}
+		return speedOfSound(vals[0])
+	}
+	lvc.FunctionMap["delay_ms"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return math.NaN(), fmt.Errorf("Function 'delay_ms' expects exactly 2 arguments")
+		}
+		vals, err := floatArgs(args)
+		if err != nil {
+			return math.NaN(), err
+		}
+		if vals[0] < 0 {
+			return math.NaN(), fmt.Errorf("Distance '%v' is negative", vals[0])
+		}
+		c, err := speedOfSound(vals[1])
+		if err != nil {
+			return math.NaN(), err
+		}
+		return vals[0] / c * 1000, nil
+	}
+}
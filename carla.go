@@ -0,0 +1,78 @@
+package lv2hostconfig
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// carlaProject is the part of Carla project file describing its
+// plugins, in rack order
+type carlaProject struct {
+	Plugins []carlaPlugin `xml:"Plugin"`
+}
+
+type carlaPlugin struct {
+	Type       string           `xml:"Info>Type"`
+	Name       string           `xml:"Info>Name"`
+	URI        string           `xml:"Info>URI"`
+	Active     string           `xml:"Data>Active"`
+	Parameters []carlaParameter `xml:"Data>Parameter"`
+}
+
+type carlaParameter struct {
+	Symbol string `xml:"Symbol"`
+	Value  string `xml:"Value"`
+}
+
+// ReadCarla reads plugin chain of a Carla project file (.carxp),
+// for migrating plugin settings out of Carla. LV2 plugins of the
+// project become plugins of the chain, in the order Carla runs
+// them, each labeled with its name in Carla and with parameter
+// values of the project as parameters. Plugins of other types, and
+// plugins that are deactivated, are left out. Just like ReadFile,
+// this replaces any plugins already in the config, and the values
+// are not evaluated until Evaluate is called.
+func (c *LV2HostConfig) ReadCarla(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read Carla project: %v", err)
+	}
+	defer f.Close()
+	var project carlaProject
+	if err := xml.NewDecoder(f).Decode(&project); err != nil {
+		return fmt.Errorf("Failed to parse Carla project: %v", err)
+	}
+
+	// parsing should be atomic, so operate on a copy
+	pcs := make([]LV2PluginConfig, 0)
+	for _, p := range project.Plugins {
+		if p.Type != "LV2" || p.Active == "No" {
+			continue
+		}
+		if p.URI == "" {
+			return fmt.Errorf("Failed to parse Carla project: plugin '%v' has no URI", p.Name)
+		}
+		pc := NewLV2PluginConfig()
+		pc.PluginURI = p.URI
+		pc.Label = p.Name
+		for _, param := range p.Parameters {
+			if param.Symbol == "" {
+				return fmt.Errorf("Failed to parse Carla project: plugin '%v' has a parameter without symbol", p.Name)
+			}
+			if _, err := strconv.ParseFloat(param.Value, 32); err != nil {
+				return fmt.Errorf("Failed to parse Carla project: plugin '%v' has invalid value '%v' of parameter '%v'",
+					p.Name, param.Value, param.Symbol)
+			}
+			if _, ok := pc.DataFmt[param.Symbol]; !ok {
+				pc.ParamOrder = append(pc.ParamOrder, param.Symbol)
+			}
+			pc.DataFmt[param.Symbol] = param.Value
+		}
+		pcs = append(pcs, pc)
+	}
+
+	c.Plugins = pcs
+	return nil
+}
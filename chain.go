@@ -0,0 +1,155 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+
+	"github.com/Knetic/govaluate"
+)
+
+// findPluginIndex looks up plugin by its identifier, which is
+// either plugin name, its UUID, its URI or its instance ID (see
+// InstanceID). Names and UUIDs take precedence, and if more than
+// one plugin matches, first one is returned.
+func (c *LV2HostConfig) findPluginIndex(id string) (int, error) {
+	for i := range c.Plugins {
+		if c.Plugins[i].Name != "" && c.Plugins[i].Name == id {
+			return i, nil
+		}
+	}
+	for i := range c.Plugins {
+		if c.Plugins[i].UUID != "" && c.Plugins[i].UUID == id {
+			return i, nil
+		}
+	}
+	for i := range c.Plugins {
+		if c.Plugins[i].PluginURI == id {
+			return i, nil
+		}
+	}
+	if i, ok := c.instanceIndex(id); ok {
+		return i, nil
+	}
+	return -1, fmt.Errorf("Plugin '%v' not found", id)
+}
+
+// findPlugin is like findPluginIndex, but returns the plugin. Plugins
+// not found in Plugins are looked up in named chains, in order of
+// chain names.
+func (c *LV2HostConfig) findPlugin(id string) (*LV2PluginConfig, error) {
+	i, err := c.findPluginIndex(id)
+	if err == nil {
+		return &c.Plugins[i], nil
+	}
+	for _, name := range c.ChainNames() {
+		chain := &LV2HostConfig{Plugins: c.Chains[name]}
+		if j, cerr := chain.findPluginIndex(id); cerr == nil {
+			return &c.Chains[name][j], nil
+		}
+	}
+	return nil, err
+}
+
+// Clone makes a deep copy of the config. Values in ValueMap
+// are copied as is, as are functions and providers.
+func (c *LV2HostConfig) Clone() *LV2HostConfig {
+	nc := c.cloneEmpty()
+	for _, pc := range c.Plugins {
+		nc.Plugins = append(nc.Plugins, pc.clone())
+	}
+	nc.Chains = copyChains(c.Chains)
+	nc.Connections = copyConnections(c.Connections)
+	return nc
+}
+
+// cloneEmpty makes a copy of the config without any plugins
+func (c *LV2HostConfig) cloneEmpty() *LV2HostConfig {
+	nc := LV2HostConfig{
+		Plugins:     make([]LV2PluginConfig, 0),
+		ValueMap:    make(map[string]interface{}),
+		FunctionMap: make(map[string]govaluate.ExpressionFunction),
+		Providers:   append([]ValueProvider(nil), c.Providers...),
+		Clock:       c.Clock,
+		EvaluatedAt: c.EvaluatedAt,
+		AutoUUID:    c.AutoUUID,
+		Validators:  append([]Validator(nil), c.Validators...),
+		Schemas:     copySchemas(c.Schemas),
+		Enums:       copyEnums(c.Enums),
+		Scenes:      copyScenes(c.Scenes),
+		Scene:       c.Scene,
+		Variations:  copyVariations(c.Variations),
+		Exports:     copyStringMap(c.Exports),
+		Imports:     copyStringMap(c.Imports),
+		OSC:         copyStringMap(c.OSC),
+		Extras:      copyExtras(c.Extras),
+		Memoize:     c.Memoize,
+		Audit:       c.Audit.clone(),
+		ParamBudget: c.ParamBudget,
+		TotalBudget: c.TotalBudget,
+		Fields:      copyStringMap(c.Fields),
+		Constants:   copyConstants(c.Constants),
+		Strict:      c.Strict,
+		RangePolicy: c.RangePolicy,
+
+		Tempo:         c.Tempo,
+		TimeSignature: c.TimeSignature,
+		SilenceFloor:  c.SilenceFloor,
+
+		Environment:       c.Environment.clone(),
+		RecordEnvironment: c.RecordEnvironment,
+
+		source: c.source,
+		memo:   newExprMemo(),
+	}
+	for k, v := range c.ValueMap {
+		nc.ValueMap[k] = v
+	}
+	for k, v := range c.FunctionMap {
+		nc.FunctionMap[k] = v
+	}
+	return &nc
+}
+
+// Subchain carves out a part of plugin chain, starting at plugin
+// from and ending at plugin to (inclusive), into a standalone
+// config. Plugins are identified by name or URI.
+func (c *LV2HostConfig) Subchain(from, to string) (*LV2HostConfig, error) {
+	i, err := c.findPluginIndex(from)
+	if err != nil {
+		return nil, err
+	}
+	j, err := c.findPluginIndex(to)
+	if err != nil {
+		return nil, err
+	}
+	if j < i {
+		return nil, fmt.Errorf("Plugin '%v' comes before plugin '%v'", to, from)
+	}
+	return c.slice(i, j+1), nil
+}
+
+// SplitAt splits plugin chain into two standalone configs, with
+// second one starting at plugin name.
+func (c *LV2HostConfig) SplitAt(name string) (*LV2HostConfig, *LV2HostConfig, error) {
+	i, err := c.findPluginIndex(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.slice(0, i), c.slice(i, len(c.Plugins)), nil
+}
+
+func (c *LV2HostConfig) slice(from, to int) *LV2HostConfig {
+	nc := c.cloneEmpty()
+	for _, pc := range c.Plugins[from:to] {
+		nc.Plugins = append(nc.Plugins, pc.clone())
+	}
+	// keep connections between plugins that were carved out
+	if edges, err := c.resolveConnections(); err == nil && c.Connections != nil {
+		nc.remapConnections(edges, func(i int) int {
+			if i < from || i >= to {
+				return -1
+			}
+			return i - from
+		})
+	}
+	return nc
+}
@@ -0,0 +1,188 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+)
+
+// chainsFromRaw converts raw named chains to plugin configs
+func chainsFromRaw(raw map[string][]lv2PluginRaw, sources *configSource) (map[string][]LV2PluginConfig, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	res := make(map[string][]LV2PluginConfig, len(raw))
+	for name, rpds := range raw {
+		if name == "" {
+			return nil, fmt.Errorf("Failed to parse config: chain name must not be empty")
+		}
+		var srcs []*pluginSource
+		if sources != nil {
+			srcs = sources.chains[name]
+		}
+		pcs := make([]LV2PluginConfig, 0, len(rpds))
+		for i := range rpds {
+			var src *pluginSource
+			if i < len(srcs) {
+				src = srcs[i]
+			}
+			pc, err := pluginFromRaw(&rpds[i], src)
+			if err != nil {
+				return nil, err
+			}
+			pcs = append(pcs, pc)
+		}
+		res[name] = pcs
+	}
+	return res, nil
+}
+
+// chainsToRaw converts named chains to their raw form
+func (c *LV2HostConfig) chainsToRaw() map[string][]lv2PluginRaw {
+	if len(c.Chains) == 0 {
+		return nil
+	}
+	res := make(map[string][]lv2PluginRaw, len(c.Chains))
+	for name, pcs := range c.Chains {
+		rpds := make([]lv2PluginRaw, 0, len(pcs))
+		for i := range pcs {
+			rpds = append(rpds, c.pluginToRaw(&pcs[i]))
+		}
+		res[name] = rpds
+	}
+	return res
+}
+
+// chainSourceList returns sources of plugins of named chains
+func (c *LV2HostConfig) chainSourceList() map[string][]*pluginSource {
+	res := make(map[string][]*pluginSource, len(c.Chains))
+	for name, pcs := range c.Chains {
+		for i := range pcs {
+			res[name] = append(res[name], pcs[i].source)
+		}
+	}
+	return res
+}
+
+// copyChains makes a deep copy of named chains
+func copyChains(chains map[string][]LV2PluginConfig) map[string][]LV2PluginConfig {
+	if chains == nil {
+		return nil
+	}
+	res := make(map[string][]LV2PluginConfig, len(chains))
+	for name, pcs := range chains {
+		npcs := make([]LV2PluginConfig, 0, len(pcs))
+		for _, pc := range pcs {
+			npcs = append(npcs, pc.clone())
+		}
+		res[name] = npcs
+	}
+	return res
+}
+
+// ChainNames returns names of named chains, sorted
+func (c *LV2HostConfig) ChainNames() []string {
+	return sortedKeys(c.Chains)
+}
+
+// Chain returns a named chain as a standalone config, sharing
+// variables, functions and everything else with this one, which
+// can be edited with all the methods working on Plugins and then
+// stored back with SetChain
+func (c *LV2HostConfig) Chain(name string) (*LV2HostConfig, error) {
+	pcs, ok := c.Chains[name]
+	if !ok {
+		return nil, fmt.Errorf("Chain '%v' not found", name)
+	}
+	nc := c.cloneEmpty()
+	for _, pc := range pcs {
+		nc.Plugins = append(nc.Plugins, pc.clone())
+	}
+	return nc, nil
+}
+
+// SetChain replaces (or adds) named chain with plugins of another config
+func (c *LV2HostConfig) SetChain(name string, chain *LV2HostConfig) error {
+	if name == "" {
+		return fmt.Errorf("Chain name must not be empty")
+	}
+	if c.Chains == nil {
+		c.Chains = make(map[string][]LV2PluginConfig)
+	}
+	pcs := make([]LV2PluginConfig, 0, len(chain.Plugins))
+	for _, pc := range chain.Plugins {
+		pcs = append(pcs, pc.clone())
+	}
+	c.Chains[name] = pcs
+	return nil
+}
+
+// mapChains calls fn with plugins of every named chain, in order of
+// chain names, and returns new named chains made of the results, so
+// that everything done to Plugins can be done to named chains the
+// same way. Returns nil if there are no named chains.
+func (c *LV2HostConfig) mapChains(fn func(name string, pcs []LV2PluginConfig) ([]LV2PluginConfig, error)) (map[string][]LV2PluginConfig, error) {
+	if c.Chains == nil {
+		return nil, nil
+	}
+	res := make(map[string][]LV2PluginConfig, len(c.Chains))
+	for _, name := range c.ChainNames() {
+		pcs, err := fn(name, c.Chains[name])
+		if err != nil {
+			return nil, err
+		}
+		res[name] = pcs
+	}
+	return res, nil
+}
+
+// eachPlugin calls fn with every plugin of Plugins, and then of
+// named chains, in order of chain names
+func (c *LV2HostConfig) eachPlugin(fn func(pc *LV2PluginConfig)) {
+	for i := range c.Plugins {
+		fn(&c.Plugins[i])
+	}
+	for _, name := range c.ChainNames() {
+		chain := c.Chains[name]
+		for i := range chain {
+			fn(&chain[i])
+		}
+	}
+}
+
+// evaluateChain evaluates plugins of a chain
+func (c *LV2HostConfig) evaluateChain(chain []LV2PluginConfig, vars map[string]interface{}, run *evalRun) ([]LV2PluginConfig, error) {
+	pcs := make([]LV2PluginConfig, 0, len(chain))
+	for i := range chain {
+		run.plugin = i
+		pc, err := c.evaluatePlugin(&chain[i], vars, run)
+		if err != nil {
+			return nil, err
+		}
+		pcs = append(pcs, pc)
+	}
+	return pcs, nil
+}
+
+// validateChains validates every named chain on its own
+func (c *LV2HostConfig) validateChains(chains map[string][]LV2PluginConfig) error {
+	for _, name := range sortedKeys(chains) {
+		if err := c.validate(chains[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateChains evaluates plugins of all named chains, in order of
+// chain names. Each chain is validated on its own.
+func (c *LV2HostConfig) evaluateChains(vars map[string]interface{}, run *evalRun) (map[string][]LV2PluginConfig, error) {
+	chains, err := c.mapChains(func(_ string, chain []LV2PluginConfig) ([]LV2PluginConfig, error) {
+		return c.evaluateChain(chain, vars, run)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.validateChains(chains); err != nil {
+		return nil, err
+	}
+	return chains, nil
+}
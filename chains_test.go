@@ -0,0 +1,91 @@
+package lv2hostconfig_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+const chainsConfig = `plugins:
+  - name: eq
+    pluginUri: urn:test:eq
+    parameters:
+      gain: 1
+chains:
+  vocals:
+    - name: comp
+      pluginUri: urn:test:comp
+      parameters:
+        threshold: -20
+    - pluginUri: urn:test:eq
+      parameters:
+        gain: 2
+`
+
+func TestChainsCompare(t *testing.T) {
+	old := lv2hostconfigtest.Parse(t, chainsConfig)
+	lv2hostconfigtest.MustEvaluate(t, old)
+	new := lv2hostconfigtest.Parse(t, strings.Replace(chainsConfig, "threshold: -20", "threshold: -24", 1))
+	lv2hostconfigtest.MustEvaluate(t, new)
+	diffs := old.Compare(new, 0)
+	if len(diffs) != 1 || diffs[0].Chain != "vocals" || diffs[0].Plugin != 0 || diffs[0].Symbol != "threshold" {
+		t.Fatalf("Differences are %+v, want threshold of vocals/comp", diffs)
+	}
+	if old.Equal(new, 0) {
+		t.Error("Configs with different chains are equal")
+	}
+	var buf bytes.Buffer
+	if err := lv2hostconfig.ChangeReport(old, new, &buf); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+	if got := buf.String(); got != "vocals/comp.threshold: -20 → -24\n" {
+		t.Errorf("Report is '%v'", got)
+	}
+}
+
+func TestChainsAssignUUIDs(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, chainsConfig)
+	if err := c.AssignUUIDs(); err != nil {
+		t.Fatalf("Failed to assign UUIDs: %v", err)
+	}
+	for _, pc := range append(c.Plugins, c.Chains["vocals"]...) {
+		if pc.UUID == "" {
+			t.Errorf("Plugin %v got no UUID", pc.PluginURI)
+		}
+	}
+	if _, err := c.FindPlugin(c.Chains["vocals"][1].UUID); err != nil {
+		t.Errorf("Plugin of named chain not found by UUID: %v", err)
+	}
+}
+
+func TestChainsApplyPlan(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, chainsConfig)
+	lv2hostconfigtest.MustEvaluate(t, c)
+	h, err := c.DryRun(48000)
+	if err != nil {
+		t.Fatalf("Failed to run plan: %v", err)
+	}
+	if len(h.Problems) != 0 {
+		t.Errorf("Plan has problems: %v", h.Problems)
+	}
+	// plugins of named chains are numbered after those of Plugins
+	for i, want := range []float32{1, -20, 2} {
+		symbol := "gain"
+		if i == 1 {
+			symbol = "threshold"
+		}
+		if got, ok := h.Param(i, symbol); !ok || got != want {
+			t.Errorf("Plugin %v has %v = %v, want %v", i, symbol, got, want)
+		}
+	}
+	cmds, err := c.ExportJalv()
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if len(cmds) != 3 {
+		t.Errorf("Got %v jalv commands, want 3", len(cmds))
+	}
+}
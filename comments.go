@@ -0,0 +1,84 @@
+package lv2hostconfig
+
+import (
+	yaml "gopkg.in/yaml.v3"
+)
+
+// copyNodeComments copies comments of a single node
+func copyNodeComments(dst, src *yaml.Node) {
+	dst.HeadComment = src.HeadComment
+	dst.LineComment = src.LineComment
+	dst.FootComment = src.FootComment
+}
+
+// keyNodes returns key and value nodes of a mapping by key. Merged
+// mappings are not included, as their comments belong to the
+// anchored mapping rather than to the one they are merged into.
+func keyNodes(n *yaml.Node) map[string][2]*yaml.Node {
+	res := make(map[string][2]*yaml.Node)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := n.Content[i]
+		if k.Tag != "!!merge" {
+			res[k.Value] = [2]*yaml.Node{k, n.Content[i+1]}
+		}
+	}
+	return res
+}
+
+// copyComments copies comments from src node tree to dst node tree.
+// Mapping entries are matched by key, and sequence items by index.
+// skip is a key of dst mapping whose value is left alone.
+func copyComments(dst, src *yaml.Node, skip string) {
+	src, _ = resolveAlias(src)
+	if dst == nil || src == nil {
+		return
+	}
+	copyNodeComments(dst, src)
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		keys := keyNodes(src)
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			k, v := dst.Content[i], dst.Content[i+1]
+			s, ok := keys[k.Value]
+			if !ok {
+				continue
+			}
+			copyNodeComments(k, s[0])
+			if k.Value == skip {
+				if sv, _ := resolveAlias(s[1]); sv != nil {
+					copyNodeComments(v, sv)
+				}
+				continue
+			}
+			copyComments(v, s[1], "")
+		}
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		for i := 0; i < len(dst.Content) && i < len(src.Content); i++ {
+			copyComments(dst.Content[i], src.Content[i], "")
+		}
+	}
+}
+
+// transplantComments copies comments from the document config was
+// parsed from to a newly encoded one. Plugins are matched by the
+// nodes they were parsed from, so comments follow plugins that
+// were moved around the chain, and new plugins have none.
+func transplantComments(doc *yaml.Node, src *configSource, plugins []*pluginSource, pluginsKey string) {
+	if src == nil || src.doc == nil || len(doc.Content) == 0 || len(src.doc.Content) == 0 {
+		return
+	}
+	copyNodeComments(doc, src.doc)
+	top := doc.Content[0]
+	copyComments(top, src.doc.Content[0], pluginsKey)
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		if top.Content[i].Value != pluginsKey || top.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		items := top.Content[i+1].Content
+		for j := 0; j < len(items) && j < len(plugins); j++ {
+			if plugins[j] != nil {
+				copyComments(items[j], plugins[j].node.node, "")
+			}
+		}
+	}
+}
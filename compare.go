@@ -0,0 +1,136 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DiffKind is a kind of difference between two configs
+type DiffKind int
+
+// Kinds of differences reported by Compare
+const (
+	DiffPluginAdded DiffKind = iota
+	DiffPluginRemoved
+	DiffPluginURI
+	DiffParamAdded
+	DiffParamRemoved
+	DiffParamValue
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffPluginAdded:
+		return "plugin added"
+	case DiffPluginRemoved:
+		return "plugin removed"
+	case DiffPluginURI:
+		return "plugin URI changed"
+	case DiffParamAdded:
+		return "parameter added"
+	case DiffParamRemoved:
+		return "parameter removed"
+	case DiffParamValue:
+		return "parameter changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// LV2ConfigDiff is a single difference between two configs.
+// Chain is name of the named chain plugin is in (empty for
+// Plugins), Plugin is index of plugin in the chain, and Symbol is
+// empty for plugin-level differences. Old and New are only
+// meaningful for parameter differences.
+type LV2ConfigDiff struct {
+	Kind   DiffKind
+	Chain  string
+	Plugin int
+	URI    string
+	Symbol string
+	Old    float32
+	New    float32
+}
+
+func floatsEqual(a, b, epsilon float32) bool {
+	aNaN := math.IsNaN(float64(a))
+	bNaN := math.IsNaN(float64(b))
+	if aNaN || bNaN {
+		return aNaN && bNaN
+	}
+	return math.Abs(float64(a)-float64(b)) <= float64(epsilon)
+}
+
+// sortedKeys returns keys of a map, sorted
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Compare compares evaluated values of this config with another
+// config. Plugins are compared positionally, and parameter values
+// within epsilon of each other are considered equal. Formatting
+// of values is not compared, so "2" and "1 + 1" are the same
+// thing as far as Compare is concerned. Named chains are compared
+// by name after Plugins, in order of chain names, and plugins of
+// a chain only one of the configs has are all added or removed.
+func (c *LV2HostConfig) Compare(other *LV2HostConfig, epsilon float32) []LV2ConfigDiff {
+	diffs := compareChain("", c.Plugins, other.Plugins, epsilon)
+	names := make(map[string][]LV2PluginConfig)
+	for name := range c.Chains {
+		names[name] = nil
+	}
+	for name := range other.Chains {
+		names[name] = nil
+	}
+	for _, name := range sortedKeys(names) {
+		diffs = append(diffs, compareChain(name, c.Chains[name], other.Chains[name], epsilon)...)
+	}
+	return diffs
+}
+
+// compareChain compares evaluated values of two versions of a chain
+func compareChain(chain string, old, new []LV2PluginConfig, epsilon float32) []LV2ConfigDiff {
+	diffs := make([]LV2ConfigDiff, 0)
+
+	for i := range old {
+		a := &old[i]
+		if i >= len(new) {
+			diffs = append(diffs, LV2ConfigDiff{DiffPluginRemoved, chain, i, a.PluginURI, "", 0, 0})
+			continue
+		}
+		b := &new[i]
+		if a.PluginURI != b.PluginURI {
+			diffs = append(diffs, LV2ConfigDiff{DiffPluginURI, chain, i, b.PluginURI, "", 0, 0})
+			continue
+		}
+		for _, sym := range sortedKeys(a.Data) {
+			av := a.Data[sym]
+			bv, ok := b.Data[sym]
+			if !ok {
+				diffs = append(diffs, LV2ConfigDiff{DiffParamRemoved, chain, i, a.PluginURI, sym, av, 0})
+			} else if !floatsEqual(av, bv, epsilon) {
+				diffs = append(diffs, LV2ConfigDiff{DiffParamValue, chain, i, a.PluginURI, sym, av, bv})
+			}
+		}
+		for _, sym := range sortedKeys(b.Data) {
+			if _, ok := a.Data[sym]; !ok {
+				diffs = append(diffs, LV2ConfigDiff{DiffParamAdded, chain, i, a.PluginURI, sym, 0, b.Data[sym]})
+			}
+		}
+	}
+	for i := len(old); i < len(new); i++ {
+		diffs = append(diffs, LV2ConfigDiff{DiffPluginAdded, chain, i, new[i].PluginURI, "", 0, 0})
+	}
+	return diffs
+}
+
+// Equal reports whether evaluated values of two configs are
+// the same, within epsilon. See Compare for details.
+func (c *LV2HostConfig) Equal(other *LV2HostConfig, epsilon float32) bool {
+	return len(c.Compare(other, epsilon)) == 0
+}
@@ -0,0 +1,114 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/Knetic/govaluate"
+)
+
+// ParamExpr is a compiled parameter value, meant for parameters
+// driven in real time. Expression is parsed only once, so that
+// evaluating it with changing variables is cheap.
+type ParamExpr struct {
+	source  string
+	literal bool
+	value   float32
+	expr    *govaluate.EvaluableExpression
+	base    map[string]interface{}
+}
+
+// overlayParams looks variables up in top map first, and falls
+// back to base map
+type overlayParams struct {
+	top  map[string]interface{}
+	base map[string]interface{}
+}
+
+func (p overlayParams) Get(name string) (interface{}, error) {
+	if v, ok := p.top[name]; ok {
+		return v, nil
+	}
+	if v, ok := p.base[name]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("No parameter '%v' found", name)
+}
+
+// CompileParam compiles formatted value of a plugin parameter.
+// Values from ValueMap and providers are captured at compile
+// time, and are used for any variables not passed to Eval.
+func (c *LV2HostConfig) CompileParam(plugin, symbol string) (ParamExpr, error) {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return ParamExpr{}, err
+	}
+	value, ok := pc.DataFmt[symbol]
+	if !ok {
+		return ParamExpr{}, fmt.Errorf("Plugin '%v' has no parameter '%v'", plugin, symbol)
+	}
+	return c.Compile(value)
+}
+
+// Compile compiles an arbitrary expression, same way CompileParam
+// compiles parameter values.
+func (c *LV2HostConfig) Compile(value string) (ParamExpr, error) {
+	if result64, err := strconv.ParseFloat(value, 32); err == nil {
+		return ParamExpr{value, true, float32(result64), nil, nil}, nil
+	}
+	expr, err := c.compileExpr(value)
+	if err != nil {
+		return ParamExpr{}, err
+	}
+	base, err := c.evalValues()
+	if err != nil {
+		return ParamExpr{}, err
+	}
+	return ParamExpr{value, false, 0, expr, base}, nil
+}
+
+// Eval evaluates compiled value with given variables. It is safe
+// to call Eval concurrently, as long as functions used by the
+// expression are safe for concurrent use.
+func (pe ParamExpr) Eval(vars map[string]interface{}) (float32, error) {
+	if pe.literal {
+		return pe.value, nil
+	}
+	if pe.expr == nil {
+		return float32(math.NaN()), fmt.Errorf("Expression was not compiled")
+	}
+	return runExpr(pe.expr, overlayParams{vars, pe.base})
+}
+
+// String returns source of compiled value
+func (pe ParamExpr) String() string {
+	return pe.source
+}
+
+// Vars returns names of variables compiled value depends on
+func (pe ParamExpr) Vars() []string {
+	if pe.literal || pe.expr == nil {
+		return nil
+	}
+	return pe.expr.Vars()
+}
+
+// EvalOver evaluates a single expression over many sets of
+// variables, e.g. to build a lookup table of values across all
+// controller positions. Expression is compiled only once.
+func (c *LV2HostConfig) EvalOver(expr string, varSets []map[string]interface{}) ([]float32, error) {
+	pe, err := c.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]float32, len(varSets))
+	for i, vars := range varSets {
+		v, err := pe.Eval(vars)
+		if err != nil {
+			return nil, fmt.Errorf("Variable set %v: %v", i, err)
+		}
+		results[i] = v
+	}
+	return results, nil
+}
@@ -0,0 +1,73 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ConfigError is an error in a specific place of a config, such as
+// a YAML syntax error or a malformed expression, so that editors
+// and CI can point users to the exact location of the problem.
+// Line and Column are zero if position is not known (e.g. the
+// config was not read from YAML), and Plugin, Param and Expr are
+// only set for errors in plugin values.
+type ConfigError struct {
+	File   string
+	Line   int
+	Column int
+	Plugin string
+	Param  string
+	Expr   string
+	Err    error
+}
+
+func (e *ConfigError) Error() string {
+	switch {
+	case e.Line == 0:
+		return e.Err.Error()
+	case e.File == "" && e.Column == 0:
+		return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+	case e.File == "":
+		return fmt.Sprintf("line %v, column %v: %v", e.Line, e.Column, e.Err)
+	case e.Column == 0:
+		return fmt.Sprintf("%v:%v: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%v:%v:%v: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// yamlErrorLine matches line numbers in YAML parser errors
+var yamlErrorLine = regexp.MustCompile(`line (\d+):`)
+
+// parseError wraps an error returned by YAML parser, extracting
+// the line it refers to
+func parseError(file string, err error) error {
+	ce := &ConfigError{File: file, Err: fmt.Errorf("Failed to parse config: %v", err)}
+	if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+		ce.Line, _ = strconv.Atoi(m[1])
+	}
+	return ce
+}
+
+// valueError wraps an error evaluating a value of a plugin (param
+// is parameter symbol, CV port or "startOffset"), adding position
+// of the value if it is known. Budget errors are returned as is.
+func (pc *LV2PluginConfig) valueError(param, expr string, err error) error {
+	if _, ok := err.(*BudgetError); ok {
+		return err
+	}
+	ce := &ConfigError{Plugin: pc.displayName(), Param: param, Expr: expr, Err: err}
+	pos, ok := pc.ParamPos(param)
+	if !ok || pc.DataFmt[param] != expr {
+		pos, ok = pc.Pos()
+	}
+	if ok {
+		ce.File, ce.Line, ce.Column = pos.File, pos.Line, pos.Column
+	}
+	return ce
+}
@@ -0,0 +1,50 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// builtinConstants are standard constants available to expressions
+var builtinConstants = map[string]float64{
+	"pi":           math.Pi,
+	"e":            math.E,
+	"sqrt2":        math.Sqrt2,
+	"ln2":          math.Ln2,
+	"ln10":         math.Ln10,
+	"MINUS_INF_DB": convert.MinDB,
+}
+
+// copyConstants copies a map of constants
+func copyConstants(constants map[string]float64) map[string]float64 {
+	res := make(map[string]float64, len(constants))
+	for k, v := range constants {
+		res[k] = v
+	}
+	return res
+}
+
+// RegisterConstant adds a named constant available to expressions.
+// It fails if the name is already used by a constant, a variable
+// or a function.
+func (c *LV2HostConfig) RegisterConstant(name string, v float64) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("Constant name '%v' is not a valid identifier", name)
+	}
+	if _, ok := c.Constants[name]; ok {
+		return fmt.Errorf("Constant '%v' is already registered", name)
+	}
+	if _, ok := c.ValueMap[name]; ok || reservedVariables[name] {
+		return fmt.Errorf("Name '%v' is already used by a variable", name)
+	}
+	if _, ok := c.FunctionMap[name]; ok || IsBuiltinFunction(name) {
+		return fmt.Errorf("Name '%v' is already used by a function", name)
+	}
+	if c.Constants == nil {
+		c.Constants = make(map[string]float64)
+	}
+	c.Constants[name] = v
+	return nil
+}
@@ -0,0 +1,139 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// Types of imported variables
+const (
+	TypeFloat  = "float"
+	TypeString = "string"
+	TypeBool   = "bool"
+)
+
+// ContractError is returned when imports of some configs could
+// not be satisfied by exports of others
+type ContractError struct {
+	Problems []string
+}
+
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("Failed to resolve config contracts: %v", strings.Join(e.Problems, "; "))
+}
+
+// evaluateAny evaluates a formatted value, without requiring
+// result to be a float
+func (c *LV2HostConfig) evaluateAny(value string, vars map[string]interface{}) (interface{}, error) {
+	if result64, err := strconv.ParseFloat(value, 64); err == nil {
+		return result64, nil
+	}
+	expr, err := c.compileExpr(value)
+	if err != nil {
+		return nil, err
+	}
+	result, err := expr.Eval(govaluate.MapParameters(vars))
+	if err != nil {
+		return nil, fmt.Errorf("Error evaluating expression '%v': %v", value, err)
+	}
+	return result, nil
+}
+
+// Exported evaluates exports of the config
+func (c *LV2HostConfig) Exported() (map[string]interface{}, error) {
+	vars, err := c.evalValues()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	for name, value := range c.Exports {
+		v, err := c.evaluateAny(value, vars)
+		if err != nil {
+			return nil, fmt.Errorf("Export '%v': %v", name, err)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return TypeString
+	case bool:
+		return TypeBool
+	}
+	if _, err := getFloat32(v); err == nil {
+		return TypeFloat
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ResolveContracts resolves imports of each config against exports
+// of all the others. Imported values are stored in ValueMap of the
+// importing config. Resolution is atomic: all problems (unknown
+// imports, type mismatches, variables exported by more than one
+// config) are reported together, and no config is modified.
+func ResolveContracts(configs ...*LV2HostConfig) error {
+	problems := make([]string, 0)
+	exports := make(map[string]interface{})
+	exporters := make(map[string]int)
+	for i, c := range configs {
+		values, err := c.Exported()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("config %v: %v", i, err))
+			continue
+		}
+		for name, v := range values {
+			if j, ok := exporters[name]; ok {
+				problems = append(problems, fmt.Sprintf("variable '%v' is exported by both config %v and config %v", name, j, i))
+				continue
+			}
+			exports[name] = v
+			exporters[name] = i
+		}
+	}
+
+	imports := make([]map[string]interface{}, len(configs))
+	for i, c := range configs {
+		imports[i] = make(map[string]interface{})
+		names := make([]string, 0, len(c.Imports))
+		for name := range c.Imports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			want := c.Imports[name]
+			switch want {
+			case TypeFloat, TypeString, TypeBool:
+			default:
+				problems = append(problems, fmt.Sprintf("config %v: import '%v' has unknown type '%v'", i, name, want))
+				continue
+			}
+			v, ok := exports[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("config %v: import '%v' is not exported by any config", i, name))
+				continue
+			}
+			if got := typeOf(v); got != want {
+				problems = append(problems, fmt.Sprintf("config %v: import '%v' is of type '%v', expected '%v'", i, name, got, want))
+				continue
+			}
+			imports[i][name] = v
+		}
+	}
+	if len(problems) != 0 {
+		return &ContractError{problems}
+	}
+
+	for i, c := range configs {
+		for name, v := range imports[i] {
+			c.ValueMap[name] = v
+		}
+	}
+	return nil
+}
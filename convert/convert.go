@@ -0,0 +1,187 @@
+// Package convert provides checked conversions between types of
+// values commonly found in LV2 host configs and expressions, as
+// well as conversions between decibels and linear gain.
+package convert
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// MinDB is the level (in dB) LinearToDB returns for silence
+const MinDB = -144
+
+// ConversionError is returned when a value can't be converted to
+// the requested type. Err is the underlying error, if any (such
+// as a string parsing error).
+type ConversionError struct {
+	Value interface{}
+	Type  string
+	Err   error
+}
+
+func (e *ConversionError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Value '%v' can't be converted to %v: %v", e.Value, e.Type, e.Err)
+	}
+	return fmt.Sprintf("Value '%v' can't be converted to %v", e.Value, e.Type)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// DBToLinear converts level in decibels to linear gain
+func DBToLinear(db float32) float32 {
+	return float32(math.Pow(10, float64(db)/20.0))
+}
+
+// LinearToDB converts linear gain to level in decibels. Zero
+// gain is converted to MinDB rather than negative infinity.
+func LinearToDB(linear float32) float32 {
+	if linear != 0 {
+		return 20 * float32(math.Log10(float64(linear)))
+	}
+	return MinDB
+}
+
+// LinearToDBFloor converts linear gain to level in decibels, never
+// returning anything below floor. Zero gain is converted to floor.
+func LinearToDBFloor(linear, floor float32) float32 {
+	if linear == 0 {
+		return floor
+	}
+	db := 20 * float32(math.Log10(float64(linear)))
+	if db < floor {
+		return floor
+	}
+	return db
+}
+
+// indirect dereferences pointers, returning false for nil values
+func indirect(val interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+// Float64 converts numbers and numeric strings to float64
+func Float64(val interface{}) (float64, error) {
+	v, ok := indirect(val)
+	if !ok {
+		return math.NaN(), &ConversionError{val, "float", nil}
+	}
+	// report dereferenced value in errors
+	val = v.Interface()
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.String:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return math.NaN(), &ConversionError{val, "float", err}
+		}
+		return f, nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		return Float64(string(v.Bytes()))
+	}
+	return math.NaN(), &ConversionError{val, "float", nil}
+}
+
+// Float32 converts numbers and numeric strings to float32
+func Float32(val interface{}) (float32, error) {
+	f, err := Float64(val)
+	if err != nil {
+		return float32(math.NaN()), err
+	}
+	return float32(f), nil
+}
+
+// Int converts integers, integral floats and strings to int
+func Int(val interface{}) (int, error) {
+	v, ok := indirect(val)
+	if ok && v.Kind() == reflect.String {
+		i, err := strconv.ParseInt(v.String(), 0, strconv.IntSize)
+		if err == nil {
+			return int(i), nil
+		}
+	}
+	f, err := Float64(val)
+	if err != nil {
+		if ce, ok := err.(*ConversionError); ok {
+			ce.Type = "integer"
+		}
+		return 0, err
+	}
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || float64(int(f)) != f {
+		return 0, &ConversionError{val, "integer", nil}
+	}
+	return int(f), nil
+}
+
+// Bool converts booleans, numbers (non-zero is true) and strings
+// understood by strconv.ParseBool to bool
+func Bool(val interface{}) (bool, error) {
+	v, ok := indirect(val)
+	if !ok {
+		return false, &ConversionError{val, "boolean", nil}
+	}
+	// report dereferenced value in errors
+	val = v.Interface()
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		b, err := strconv.ParseBool(v.String())
+		if err != nil {
+			return false, &ConversionError{val, "boolean", err}
+		}
+		return b, nil
+	}
+	f, err := Float64(val)
+	if err != nil || math.IsNaN(f) {
+		return false, &ConversionError{val, "boolean", nil}
+	}
+	return f != 0, nil
+}
+
+// String converts strings, booleans and numbers to string. Floats
+// are formatted using as few digits as needed to represent them.
+func String(val interface{}) (string, error) {
+	v, ok := indirect(val)
+	if !ok {
+		return "", &ConversionError{val, "string", nil}
+	}
+	// report dereferenced value in errors
+	val = v.Interface()
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		return string(v.Bytes()), nil
+	}
+	return "", &ConversionError{val, "string", nil}
+}
@@ -0,0 +1,239 @@
+package lv2hostconfig
+
+import (
+	"sort"
+	"sync"
+)
+
+// VectorClock counts edits made by each replica, by replica ID
+type VectorClock map[string]uint64
+
+func (vc VectorClock) copy() VectorClock {
+	res := make(VectorClock, len(vc))
+	for k, v := range vc {
+		res[k] = v
+	}
+	return res
+}
+
+// Descends checks if vc has seen every edit other has seen
+func (vc VectorClock) Descends(other VectorClock) bool {
+	for k, v := range other {
+		if vc[k] < v {
+			return false
+		}
+	}
+	return true
+}
+
+// merge makes vc the least clock descending both vc and other
+func (vc VectorClock) merge(other VectorClock) {
+	for k, v := range other {
+		if vc[k] < v {
+			vc[k] = v
+		}
+	}
+}
+
+func (vc VectorClock) sum() uint64 {
+	var s uint64
+	for _, v := range vc {
+		s += v
+	}
+	return s
+}
+
+// ReplicaEntry is the last written value of a parameter, along
+// with clock of the write and ID of replica that made it
+type ReplicaEntry struct {
+	Plugin  string      `json:"plugin"`
+	Symbol  string      `json:"symbol"`
+	Value   string      `json:"value"`
+	Clock   VectorClock `json:"clock"`
+	Replica string      `json:"replica"`
+}
+
+// wins checks if entry e should replace other. Later writes win,
+// and of concurrent ones, the one with more history behind it, or
+// the one made by replica with greater ID, so that all replicas
+// pick the same winner regardless of order of merges.
+func (e *ReplicaEntry) wins(other *ReplicaEntry) bool {
+	ed, od := e.Clock.Descends(other.Clock), other.Clock.Descends(e.Clock)
+	switch {
+	case ed && !od:
+		return true
+	case od && !ed:
+		return false
+	case ed && od:
+		// same write
+		return false
+	}
+	if es, ots := e.Clock.sum(), other.Clock.sum(); es != ots {
+		return es > ots
+	}
+	return e.Replica > other.Replica
+}
+
+// ReplicaState is state of a replica (or changes to it), as sent
+// between replicas to sync them
+type ReplicaState struct {
+	Clock   VectorClock    `json:"clock"`
+	Entries []ReplicaEntry `json:"entries"`
+}
+
+type replicaKey struct {
+	plugin, symbol string
+}
+
+// Replica is a copy of parameter edits made to a shared config,
+// for setups where several editors (e.g. a tablet UI and a laptop)
+// change the same live config. Every parameter is a last-writer-
+// wins register ordered by vector clocks, so replicas converge to
+// the same values once they have seen the same edits, whatever
+// order they were merged in. Values are formatted values, so both
+// literals and expressions can be edited. Replicas are safe for
+// concurrent use.
+type Replica struct {
+	id      string
+	mu      sync.Mutex
+	clock   VectorClock
+	entries map[replicaKey]*ReplicaEntry
+}
+
+// NewReplica creates a new replica. ID must be unique among all
+// replicas that will ever be merged with each other.
+func NewReplica(id string) *Replica {
+	return &Replica{
+		id:      id,
+		clock:   make(VectorClock),
+		entries: make(map[replicaKey]*ReplicaEntry),
+	}
+}
+
+// ID returns replica ID
+func (r *Replica) ID() string {
+	return r.id
+}
+
+// Clock returns current clock of the replica, which can be passed
+// to Delta of another replica to get edits this one hasn't seen
+func (r *Replica) Clock() VectorClock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clock.copy()
+}
+
+// Set records a local edit of plugin parameter
+func (r *Replica) Set(plugin, symbol, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock[r.id]++
+	r.entries[replicaKey{plugin, symbol}] = &ReplicaEntry{
+		Plugin:  plugin,
+		Symbol:  symbol,
+		Value:   value,
+		Clock:   r.clock.copy(),
+		Replica: r.id,
+	}
+}
+
+// Value returns current value of plugin parameter, if it was ever
+// edited
+func (r *Replica) Value(plugin, symbol string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[replicaKey{plugin, symbol}]
+	if !ok {
+		return "", false
+	}
+	return e.Value, true
+}
+
+// entryList returns entries accepted by filter, in stable order
+func (r *Replica) entryList(filter func(e *ReplicaEntry) bool) []ReplicaEntry {
+	res := make([]ReplicaEntry, 0)
+	for _, e := range r.entries {
+		if filter(e) {
+			ne := *e
+			ne.Clock = e.Clock.copy()
+			res = append(res, ne)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Plugin != res[j].Plugin {
+			return res[i].Plugin < res[j].Plugin
+		}
+		return res[i].Symbol < res[j].Symbol
+	})
+	return res
+}
+
+// State returns full state of the replica
+func (r *Replica) State() ReplicaState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ReplicaState{
+		Clock:   r.clock.copy(),
+		Entries: r.entryList(func(*ReplicaEntry) bool { return true }),
+	}
+}
+
+// Delta returns edits made since given clock, i.e. those a replica
+// with that clock hasn't seen yet
+func (r *Replica) Delta(since VectorClock) ReplicaState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ReplicaState{
+		Clock: r.clock.copy(),
+		Entries: r.entryList(func(e *ReplicaEntry) bool {
+			return !since.Descends(e.Clock)
+		}),
+	}
+}
+
+// Merge merges state (or delta) of another replica into this one.
+// Merging is commutative, associative and idempotent. Returns
+// entries that changed values of this replica.
+func (r *Replica) Merge(state ReplicaState) []ReplicaEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := make([]ReplicaEntry, 0)
+	for i := range state.Entries {
+		e := state.Entries[i]
+		e.Clock = e.Clock.copy()
+		key := replicaKey{e.Plugin, e.Symbol}
+		if cur, ok := r.entries[key]; ok && !e.wins(cur) {
+			continue
+		}
+		r.entries[key] = &e
+		ce := e
+		ce.Clock = e.Clock.copy()
+		changed = append(changed, ce)
+	}
+	r.clock.merge(state.Clock)
+	return changed
+}
+
+// Apply writes current values of the replica into formatted values
+// of a config. Plugins are identified by name, UUID or URI. The
+// config must be re-evaluated for values to take effect. Applying
+// is atomic.
+func (r *Replica) Apply(c *LV2HostConfig) error {
+	r.mu.Lock()
+	entries := r.entryList(func(*ReplicaEntry) bool { return true })
+	r.mu.Unlock()
+
+	// applying should be atomic, so check all plugins first
+	pcs := make([]*LV2PluginConfig, len(entries))
+	for i, e := range entries {
+		pc, err := c.findPlugin(e.Plugin)
+		if err != nil {
+			return err
+		}
+		pcs[i] = pc
+	}
+	for i, e := range entries {
+		pcs[i].DataFmt[e.Symbol] = e.Value
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package lv2hostconfig
+
+// LV2CVPort describes a CV port. Value is initial value of
+// the port, and is evaluated the same way parameter values
+// are. Generator is an optional expression describing how
+// the port should be driven over time - it is not evaluated
+// by the config parser, and is left for the host to interpret.
+type LV2CVPort struct {
+	Value     string `yaml:"value"`
+	Generator string `yaml:"generator,omitempty"`
+}
+
+func copyCVPorts(ports map[string]LV2CVPort) map[string]LV2CVPort {
+	if len(ports) == 0 {
+		return nil
+	}
+	p := make(map[string]LV2CVPort)
+	for k, v := range ports {
+		p[k] = v
+	}
+	return p
+}
@@ -0,0 +1,118 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Knetic/govaluate"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// powerToDb converts power ratio to decibels, never returning
+// anything below floor
+func powerToDb(power float64, floor float32) float64 {
+	if power <= 0 {
+		return float64(floor)
+	}
+	db := 10 * math.Log10(power)
+	if db < float64(floor) {
+		return float64(floor)
+	}
+	return db
+}
+
+func dbToPower(db float32) float64 {
+	return math.Pow(10, float64(db)/10.0)
+}
+
+// decibelFunc returns the decibel function using a silence floor
+func decibelFunc(floor float32) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return math.NaN(), fmt.Errorf("Function 'decibel' expects exactly 1 argument")
+		}
+		linear, err := convert.Float32(args[0])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
+		}
+		return float64(convert.LinearToDBFloor(linear, floor)), nil
+	}
+}
+
+// dbsumFunc returns the dbsum function using a silence floor
+func dbsumFunc(floor float32) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) < 1 {
+			return math.NaN(), fmt.Errorf("Function 'dbsum' expects at least 1 argument")
+		}
+		sum := 0.0
+		for _, arg := range args {
+			db, err := convert.Float32(arg)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
+			}
+			sum += dbToPower(db)
+		}
+		return powerToDb(sum, floor), nil
+	}
+}
+
+// dbdiffFunc returns the dbdiff function using a silence floor
+func dbdiffFunc(floor float32) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return math.NaN(), fmt.Errorf("Function 'dbdiff' expects exactly 2 arguments")
+		}
+		a, err := convert.Float32(args[0])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
+		}
+		b, err := convert.Float32(args[1])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[1])
+		}
+		if b > a {
+			return math.NaN(), fmt.Errorf("Level '%v' can't be subtracted from lower level '%v'", b, a)
+		}
+		return powerToDb(dbToPower(a)-dbToPower(b), floor), nil
+	}
+}
+
+// silenceFuncs are standard functions that depend on silence floor
+var silenceFuncs = map[string]func(floor float32) govaluate.ExpressionFunction{
+	"decibel": decibelFunc,
+	"dbsum":   dbsumFunc,
+	"dbdiff":  dbdiffFunc,
+}
+
+func setUpDecibelFuncs(lvc *LV2HostConfig) {
+	lvc.FunctionMap["dbsum"] = dbsumFunc(convert.MinDB)
+	lvc.FunctionMap["dbdiff"] = dbdiffFunc(convert.MinDB)
+}
+
+// silenceFloor returns level used for silence
+func (c *LV2HostConfig) silenceFloor() float32 {
+	if c.SilenceFloor == 0 {
+		return convert.MinDB
+	}
+	return c.SilenceFloor
+}
+
+// functions returns functions available to expressions. If silence
+// floor is set, standard functions depending on it use that floor.
+func (c *LV2HostConfig) functions() map[string]govaluate.ExpressionFunction {
+	if c.SilenceFloor == 0 {
+		return c.FunctionMap
+	}
+	funcs := make(map[string]govaluate.ExpressionFunction, len(c.FunctionMap))
+	for name, f := range c.FunctionMap {
+		funcs[name] = f
+	}
+	for name, f := range silenceFuncs {
+		if _, ok := funcs[name]; ok {
+			funcs[name] = f(c.SilenceFloor)
+		}
+	}
+	return funcs
+}
@@ -0,0 +1,136 @@
+package lv2hostconfig
+
+import (
+	// embed is needed for the factory defaults database
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FactoryPort is a control port entry in factory defaults database
+type FactoryPort struct {
+	Default float32 `json:"default"`
+	Min     float32 `json:"min"`
+	Max     float32 `json:"max"`
+}
+
+// entries for installed plugins are added by gendefaults
+//go:generate go run ./internal/gendefaults -o factory_defaults.json
+
+//go:embed factory_defaults.json
+var factoryDefaultsJSON []byte
+
+var factoryDefaults struct {
+	once  sync.Once
+	mu    sync.RWMutex
+	ports map[string]map[string]FactoryPort
+	err   error
+}
+
+func loadFactoryDefaults() {
+	factoryDefaults.once.Do(func() {
+		factoryDefaults.ports = make(map[string]map[string]FactoryPort)
+		factoryDefaults.err = json.Unmarshal(factoryDefaultsJSON, &factoryDefaults.ports)
+	})
+}
+
+// LoadDefaults merges a defaults database in JSON format into the
+// factory defaults database, replacing entries for any plugins it
+// contains. The format is the same as that of embedded database:
+//
+//	{"<plugin URI>": {"<symbol>": {"default": 0, "min": -90, "max": 24}}}
+func LoadDefaults(r io.Reader) error {
+	var ports map[string]map[string]FactoryPort
+	if err := json.NewDecoder(r).Decode(&ports); err != nil {
+		return fmt.Errorf("Failed to parse defaults: %v", err)
+	}
+	loadFactoryDefaults()
+	factoryDefaults.mu.Lock()
+	defer factoryDefaults.mu.Unlock()
+	for uri, p := range ports {
+		factoryDefaults.ports[uri] = p
+	}
+	return nil
+}
+
+// RegisterDefaults adds or replaces factory defaults entry for a plugin
+func RegisterDefaults(uri string, ports map[string]FactoryPort) {
+	loadFactoryDefaults()
+	factoryDefaults.mu.Lock()
+	defer factoryDefaults.mu.Unlock()
+	p := make(map[string]FactoryPort)
+	for k, v := range ports {
+		p[k] = v
+	}
+	factoryDefaults.ports[uri] = p
+}
+
+// FactoryPorts returns factory defaults database entry for a plugin
+func FactoryPorts(uri string) (map[string]FactoryPort, bool) {
+	loadFactoryDefaults()
+	factoryDefaults.mu.RLock()
+	defer factoryDefaults.mu.RUnlock()
+	ports, ok := factoryDefaults.ports[uri]
+	if !ok {
+		return nil, false
+	}
+	p := make(map[string]FactoryPort)
+	for k, v := range ports {
+		p[k] = v
+	}
+	return p, true
+}
+
+// DefaultsFor returns default control port values of a plugin, as
+// recorded in factory defaults database. This works even on systems
+// without the plugin installed.
+func DefaultsFor(uri string) (map[string]float32, bool) {
+	ports, ok := FactoryPorts(uri)
+	if !ok {
+		return nil, false
+	}
+	defaults := make(map[string]float32)
+	for k, v := range ports {
+		defaults[k] = v.Default
+	}
+	return defaults, true
+}
+
+// FactoryDefaultsError returns an error if embedded factory defaults
+// database failed to load, which would be a packaging bug
+func FactoryDefaultsError() error {
+	loadFactoryDefaults()
+	return factoryDefaults.err
+}
+
+// FillDefaults adds parameters missing from plugin configs (of
+// Plugins and named chains), with default values from factory
+// defaults database. Parameters that are already set, even to
+// expressions that failed to evaluate, are left alone. Returns
+// number of parameters added.
+func (c *LV2HostConfig) FillDefaults() int {
+	n := 0
+	c.eachPlugin(func(pc *LV2PluginConfig) {
+		defaults, ok := DefaultsFor(pc.PluginURI)
+		if !ok {
+			return
+		}
+		if pc.Data == nil {
+			pc.Data = make(map[string]float32)
+		}
+		if pc.DataFmt == nil {
+			pc.DataFmt = make(map[string]string)
+		}
+		for symbol, v := range defaults {
+			if _, ok := pc.DataFmt[symbol]; ok {
+				continue
+			}
+			pc.DataFmt[symbol] = formatFloat(v)
+			pc.Data[symbol] = v
+			n++
+		}
+	})
+	return n
+}
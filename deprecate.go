@@ -0,0 +1,343 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Deprecation is a legacy expression idiom found in a parameter
+// (or CV port) value. New is the expression Old can be rewritten to
+// with the same result, or empty if the idiom can only be flagged,
+// e.g. because rewriting would change results in corner cases.
+type Deprecation struct {
+	Plugin  string
+	Symbol  string
+	Old     string
+	New     string
+	Message string
+}
+
+func (d Deprecation) String() string {
+	if d.New == "" {
+		return fmt.Sprintf("%v.%v: %v: '%v'", d.Plugin, d.Symbol, d.Message, d.Old)
+	}
+	return fmt.Sprintf("%v.%v: %v: '%v' -> '%v'", d.Plugin, d.Symbol, d.Message, d.Old, d.New)
+}
+
+// handMIDI matches division by MIDI range, as in hand-written
+// "lo + cc / 127 * (hi - lo)"
+var handMIDI = regexp.MustCompile(`/\s*127(\.0*)?\b`)
+
+// literalValue returns value of a numeric literal
+func literalValue(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 32)
+	return v, err == nil
+}
+
+// skipString returns index of quote closing string literal that
+// starts at i, or len(expr) if it is not closed
+func skipString(expr string, i int) int {
+	j := i + 1
+	for j < len(expr) && expr[j] != expr[i] {
+		if expr[j] == '\\' {
+			j++
+		}
+		j++
+	}
+	return j
+}
+
+// closingIndex returns index of bracket closing the one at i, or -1,
+// skipping over string literals
+func closingIndex(expr string, i int) int {
+	depth := 0
+	for j := i; j < len(expr); j++ {
+		switch expr[j] {
+		case '\'', '"':
+			j = skipString(expr, j)
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// splitArgs splits arguments of a call at top-level commas
+func splitArgs(s string) []string {
+	args := make([]string, 0)
+	if strings.TrimSpace(s) == "" {
+		return args
+	}
+	last := 0
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\'', '"':
+			j = skipString(s, j)
+		case '(', '[':
+			j = closingIndex(s, j)
+			if j < 0 {
+				return append(args, strings.TrimSpace(s[last:]))
+			}
+		case ',':
+			args = append(args, strings.TrimSpace(s[last:j]))
+			last = j + 1
+		}
+	}
+	return append(args, strings.TrimSpace(s[last:]))
+}
+
+// parseCall parses expression that is a single function call
+func parseCall(expr string) (string, []string, bool) {
+	expr = strings.TrimSpace(expr)
+	i := 0
+	for i < len(expr) && isIdentChar(expr[i]) {
+		i++
+	}
+	name := expr[:i]
+	if !isIdentifier(name) {
+		return "", nil, false
+	}
+	for i < len(expr) && expr[i] == ' ' {
+		i++
+	}
+	if i >= len(expr) || expr[i] != '(' || closingIndex(expr, i) != len(expr)-1 {
+		return "", nil, false
+	}
+	return name, splitArgs(expr[i+1 : len(expr)-1]), true
+}
+
+// isOperand checks if expression binds tighter than any operator
+func isOperand(expr string) bool {
+	expr = strings.TrimSpace(expr)
+	if _, ok := literalValue(expr); ok || isIdentifier(expr) {
+		return true
+	}
+	if _, _, ok := parseCall(expr); ok {
+		return true
+	}
+	if expr != "" && (expr[0] == '(' || expr[0] == '[') {
+		return closingIndex(expr, 0) == len(expr)-1
+	}
+	return false
+}
+
+// deprecatedCall checks a call for legacy idioms, returning the
+// replacement (if any) and what is wrong with the call
+func deprecatedCall(name string, args []string) (string, string, bool) {
+	switch name {
+	case "min", "max":
+		if len(args) != 2 {
+			break
+		}
+		inner := "max"
+		if name == "max" {
+			inner = "min"
+		}
+		for k := range args {
+			iname, iargs, ok := parseCall(args[k])
+			if !ok || iname != inner || len(iargs) != 2 {
+				continue
+			}
+			outer := args[1-k]
+			// value is the non-literal argument of the inner call
+			val, bound := iargs[0], iargs[1]
+			if _, ok := literalValue(val); ok {
+				val, bound = bound, val
+			}
+			lo, hi := bound, outer
+			if name == "max" {
+				lo, hi = outer, bound
+			}
+			msg := "nested min/max used as clamp"
+			// clamp rejects inverted ranges instead of pinning the
+			// value to one of the bounds, so only rewrite known ones
+			l, lok := literalValue(lo)
+			h, hok := literalValue(hi)
+			if !lok || !hok || l > h {
+				return "", msg + ", consider clamp(value, min, max)", true
+			}
+			return fmt.Sprintf("clamp(%v, %v, %v)", val, lo, hi), msg, true
+		}
+	case "scale":
+		if len(args) != 5 {
+			break
+		}
+		from, fok := literalValue(args[1])
+		to, tok := literalValue(args[2])
+		if fok && tok && from == 0 && to == 127 {
+			return fmt.Sprintf("frommidi(%v, %v, %v)", args[0], args[3], args[4]), "MIDI scaling with scale", true
+		}
+		from, fok = literalValue(args[3])
+		to, tok = literalValue(args[4])
+		if fok && tok && from == 0 && to == 127 {
+			// midi rounds to whole steps, so results would differ
+			return "", "MIDI scaling with scale, consider midi(value, min, max)", true
+		}
+	case "pow":
+		if len(args) != 2 {
+			break
+		}
+		if b, ok := literalValue(args[0]); !ok || b != 10 {
+			break
+		}
+		i := strings.LastIndexByte(args[1], '/')
+		if i < 0 {
+			break
+		}
+		if d, ok := literalValue(strings.TrimSpace(args[1][i+1:])); !ok || d != 20 {
+			break
+		}
+		if db := strings.TrimSpace(args[1][:i]); isOperand(db) {
+			if db[0] == '(' {
+				db = strings.TrimSpace(db[1 : len(db)-1])
+			}
+			return fmt.Sprintf("linear(%v)", db), "decibel conversion with pow", true
+		}
+	}
+	return "", "", false
+}
+
+// migrateExpr rewrites legacy idioms in expression, reporting every
+// idiom found. Parts of expression that are not rewritten are kept
+// byte for byte.
+func migrateExpr(expr string, report func(old, new, msg string)) string {
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '\'' || c == '"' || c == '[':
+			var j int
+			if c == '[' {
+				j = strings.IndexByte(expr[i:], ']')
+				if j >= 0 {
+					j += i
+				}
+			} else {
+				j = skipString(expr, i)
+			}
+			if j < 0 || j >= len(expr) {
+				b.WriteString(expr[i:])
+				return b.String()
+			}
+			b.WriteString(expr[i : j+1])
+			i = j + 1
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(expr) && isIdentChar(expr[j]) {
+				j++
+			}
+			k := j
+			for k < len(expr) && expr[k] == ' ' {
+				k++
+			}
+			end := -1
+			if k < len(expr) && expr[k] == '(' {
+				end = closingIndex(expr, k)
+			}
+			if end < 0 {
+				b.WriteString(expr[i:j])
+				i = j
+				continue
+			}
+			name, args, _ := parseCall(expr[i : end+1])
+			changed := false
+			for n, arg := range args {
+				if na := migrateExpr(arg, report); na != arg {
+					args[n] = na
+					changed = true
+				}
+			}
+			call := expr[i : end+1]
+			if changed {
+				call = fmt.Sprintf("%v(%v)", name, strings.Join(args, ", "))
+			}
+			if repl, msg, ok := deprecatedCall(name, args); ok {
+				report(call, repl, msg)
+				if repl != "" {
+					call = repl
+				}
+			}
+			b.WriteString(call)
+			i = end + 1
+		case c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(expr) && (isIdentChar(expr[j]) || expr[j] == '.') {
+				j++
+			}
+			b.WriteString(expr[i:j])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// deprecations finds legacy idioms in a value, returning the value
+// with idioms rewritten
+func deprecations(plugin, symbol, value string) (string, []Deprecation) {
+	res := make([]Deprecation, 0)
+	if !isExpression(value) {
+		return value, res
+	}
+	nv := migrateExpr(value, func(old, new, msg string) {
+		res = append(res, Deprecation{plugin, symbol, old, new, msg})
+	})
+	if handMIDI.MatchString(nv) {
+		res = append(res, Deprecation{plugin, symbol, nv, "",
+			"hand-written MIDI scaling, consider frommidi(cc, min, max)"})
+	}
+	return nv, res
+}
+
+// migrate finds legacy idioms in formatted values of a config, and
+// rewrites them if fix is set
+func (c *LV2HostConfig) migrate(fix bool) []Deprecation {
+	res := make([]Deprecation, 0)
+	for i := range c.Plugins {
+		pc := &c.Plugins[i]
+		id := c.InstanceID(i)
+		for _, symbol := range sortedKeys(pc.DataFmt) {
+			nv, d := deprecations(id, symbol, pc.DataFmt[symbol])
+			res = append(res, d...)
+			if fix {
+				pc.DataFmt[symbol] = nv
+			}
+		}
+		for _, port := range sortedKeys(pc.CVFmt) {
+			cv := pc.CVFmt[port]
+			nv, d := deprecations(id, port, cv.Value)
+			res = append(res, d...)
+			if fix {
+				cv.Value = nv
+				pc.CVFmt[port] = cv
+			}
+		}
+	}
+	return res
+}
+
+// ScanDeprecated finds legacy expression idioms which have newer
+// built-in replacements, such as nested min/max used in place of
+// clamp, or hand-written MIDI and decibel conversions. The config
+// is not modified.
+func (c *LV2HostConfig) ScanDeprecated() []Deprecation {
+	return c.migrate(false)
+}
+
+// MigrateDeprecated rewrites legacy expression idioms to newer
+// built-ins wherever results stay the same, and returns a migration
+// report listing every idiom found, including those that could not
+// be rewritten (with empty New). The config must be re-evaluated
+// afterwards, as usual.
+func (c *LV2HostConfig) MigrateDeprecated() []Deprecation {
+	return c.migrate(true)
+}
@@ -0,0 +1,289 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParamRef identifies a parameter of a plugin in the chain, by
+// instance ID of the plugin (see InstanceID) and port symbol
+type ParamRef struct {
+	Plugin string
+	Symbol string
+}
+
+func (r ParamRef) String() string {
+	return r.Plugin + "." + r.Symbol
+}
+
+// ParamCycleError is returned when parameters refer to each other
+// in a loop, so that none of them can be evaluated. Path lists
+// parameters along the cycle, starting and ending with the same
+// parameter, and Expressions lists expression of each parameter
+// in Path (except for the last one, which repeats the first).
+type ParamCycleError struct {
+	Path        []ParamRef
+	Expressions []string
+}
+
+func (e *ParamCycleError) Error() string {
+	steps := make([]string, len(e.Path))
+	for i, r := range e.Path {
+		steps[i] = r.String()
+		if i < len(e.Expressions) {
+			steps[i] += fmt.Sprintf(" ('%v')", e.Expressions[i])
+		}
+	}
+	return fmt.Sprintf("Parameters depend on each other: %v", strings.Join(steps, " -> "))
+}
+
+// DependencyGraph is the graph of references between parameters
+// of a chain. Parameter refers to another one by using variable
+// named [<plugin>.<symbol>] in its expression, where plugin is
+// anything plugins can be looked up by (name, UUID, URI or
+// instance ID). Variables of ValueMap and providers take precedence.
+type DependencyGraph struct {
+	// Params are all parameters of the chain, in chain order
+	Params []ParamRef
+
+	deps  map[ParamRef][]ParamRef
+	exprs map[ParamRef]string
+	// variable names parameters are referred to with
+	names map[ParamRef][]string
+}
+
+// DependsOn returns parameters a parameter refers to
+func (g *DependencyGraph) DependsOn(r ParamRef) []ParamRef {
+	return append([]ParamRef(nil), g.deps[r]...)
+}
+
+// Expression returns expression of a parameter
+func (g *DependencyGraph) Expression(r ParamRef) string {
+	return g.exprs[r]
+}
+
+// Components returns strongly-connected components of the graph,
+// ordered so that every component comes after all components it
+// depends on. Parameters that are not part of a cycle are in
+// components of their own.
+func (g *DependencyGraph) Components() [][]ParamRef {
+	// Tarjan's algorithm, which finds components in just that order
+	index := make(map[ParamRef]int)
+	low := make(map[ParamRef]int)
+	onStack := make(map[ParamRef]bool)
+	stack := make([]ParamRef, 0)
+	comps := make([][]ParamRef, 0)
+
+	var visit func(r ParamRef)
+	visit = func(r ParamRef) {
+		index[r] = len(index)
+		low[r] = index[r]
+		stack = append(stack, r)
+		onStack[r] = true
+		for _, d := range g.deps[r] {
+			if _, ok := index[d]; !ok {
+				visit(d)
+				if low[d] < low[r] {
+					low[r] = low[d]
+				}
+			} else if onStack[d] && index[d] < low[r] {
+				low[r] = index[d]
+			}
+		}
+		if low[r] != index[r] {
+			return
+		}
+		comp := make([]ParamRef, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			comp = append(comp, top)
+			if top == r {
+				break
+			}
+		}
+		comps = append(comps, g.chainOrder(comp))
+	}
+	for _, r := range g.Params {
+		if _, ok := index[r]; !ok {
+			visit(r)
+		}
+	}
+	return comps
+}
+
+// chainOrder sorts parameters in chain order
+func (g *DependencyGraph) chainOrder(refs []ParamRef) []ParamRef {
+	pos := make(map[ParamRef]int)
+	for i, r := range g.Params {
+		pos[r] = i
+	}
+	sort.Slice(refs, func(a, b int) bool { return pos[refs[a]] < pos[refs[b]] })
+	return refs
+}
+
+// Cycles returns components of the graph that form cycles, i.e.
+// that contain more than one parameter, or a parameter referring
+// to itself
+func (g *DependencyGraph) Cycles() [][]ParamRef {
+	cycles := make([][]ParamRef, 0)
+	for _, comp := range g.Components() {
+		if len(comp) > 1 || g.refersTo(comp[0], comp[0]) {
+			cycles = append(cycles, comp)
+		}
+	}
+	return cycles
+}
+
+func (g *DependencyGraph) refersTo(from, to ParamRef) bool {
+	for _, d := range g.deps[from] {
+		if d == to {
+			return true
+		}
+	}
+	return false
+}
+
+// cycleError finds the shortest cycle through first parameter of a
+// component, and reports it along with expressions involved
+func (g *DependencyGraph) cycleError(comp []ParamRef) *ParamCycleError {
+	in := make(map[ParamRef]bool)
+	for _, r := range comp {
+		in[r] = true
+	}
+	start := comp[0]
+	prev := map[ParamRef]ParamRef{start: start}
+	queue := []ParamRef{start}
+	last := start
+	for len(queue) != 0 {
+		r := queue[0]
+		queue = queue[1:]
+		if g.refersTo(r, start) {
+			last = r
+			break
+		}
+		for _, d := range g.deps[r] {
+			if _, seen := prev[d]; !seen && in[d] {
+				prev[d] = r
+				queue = append(queue, d)
+			}
+		}
+	}
+	path := []ParamRef{start}
+	for r := last; r != start; r = prev[r] {
+		path = append(path, r)
+	}
+	// path is backwards, apart from the start
+	for i, j := 1, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	e := &ParamCycleError{Expressions: make([]string, 0, len(path))}
+	for _, r := range path {
+		e.Expressions = append(e.Expressions, g.exprs[r])
+	}
+	e.Path = append(path, start)
+	return e
+}
+
+// Dependencies returns the graph of references between parameters
+// of the chain. Expressions that fail to parse, and references to
+// unknown plugins or parameters, are left out; evaluation reports
+// them.
+func (c *LV2HostConfig) Dependencies() (*DependencyGraph, error) {
+	vars, err := c.baseValues()
+	if err != nil {
+		return nil, err
+	}
+	return c.dependencies(vars), nil
+}
+
+func (c *LV2HostConfig) dependencies(vars map[string]interface{}) *DependencyGraph {
+	g := &DependencyGraph{
+		Params: make([]ParamRef, 0),
+		deps:   make(map[ParamRef][]ParamRef),
+		exprs:  make(map[ParamRef]string),
+		names:  make(map[ParamRef][]string),
+	}
+	ids := make([]string, len(c.Plugins))
+	for i := range c.Plugins {
+		ids[i] = c.InstanceID(i)
+	}
+	for i := range c.Plugins {
+		pc := &c.Plugins[i]
+		for _, param := range sortedKeys(pc.DataFmt) {
+			r := ParamRef{ids[i], param}
+			value := pc.DataFmt[param]
+			g.Params = append(g.Params, r)
+			g.exprs[r] = value
+			if !strings.Contains(value, "[") {
+				continue
+			}
+			if _, err := strconv.ParseFloat(value, 32); err == nil {
+				continue
+			}
+			expr, err := c.compileExpr(value)
+			if err != nil {
+				continue
+			}
+			for _, name := range expr.Vars() {
+				if _, ok := vars[name]; ok {
+					continue
+				}
+				plugin, symbol, err := splitParamRef(name)
+				if err != nil {
+					continue
+				}
+				j, err := c.findPluginIndex(plugin)
+				if err != nil {
+					continue
+				}
+				if _, ok := c.Plugins[j].DataFmt[symbol]; !ok {
+					continue
+				}
+				d := ParamRef{ids[j], symbol}
+				if !g.refersTo(r, d) {
+					g.deps[r] = append(g.deps[r], d)
+				}
+				g.names[d] = append(g.names[d], name)
+			}
+		}
+	}
+	return g
+}
+
+// resolveReferences evaluates parameters that other parameters refer
+// to, in order of their dependencies, and adds their values to vars
+func (c *LV2HostConfig) resolveReferences(vars map[string]interface{}) error {
+	g := c.dependencies(vars)
+	if len(g.names) == 0 {
+		return nil
+	}
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		return g.cycleError(cycles[0])
+	}
+	index := make(map[string]int)
+	for i := range c.Plugins {
+		index[c.InstanceID(i)] = i
+	}
+	run := c.newEvalRun()
+	for _, comp := range g.Components() {
+		r := comp[0]
+		names, ok := g.names[r]
+		if !ok {
+			continue
+		}
+		run.plugin = index[r.Plugin]
+		pc := &c.Plugins[run.plugin]
+		v, err := c.paramValue(pc, pc, r.Symbol, vars, run, c.rangePorts(pc))
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			vars[name] = float64(v)
+		}
+	}
+	return nil
+}
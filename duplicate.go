@@ -0,0 +1,86 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DuplicatePlugin makes a copy of plugin identified by name, and
+// inserts it right after the original in the chain. The copy is
+// named newName, and if the original had a UUID, the copy gets a
+// new one. Expressions of the copy referring to variables named
+// "<name>_<variable>", which are the original's own variables by
+// convention, are rewritten to refer to "<newName>_<variable>",
+// and any such variables that don't exist yet are created, with
+// values copied from the original's. Rewriting is only done if both
+// names are valid identifiers. Duplicating is atomic.
+func (c *LV2HostConfig) DuplicatePlugin(name, newName string) error {
+	i, err := c.findPluginIndex(name)
+	if err != nil {
+		return err
+	}
+	if newName == "" {
+		return fmt.Errorf("Name of plugin copy must not be empty")
+	}
+	for _, pc := range c.Plugins {
+		if pc.Name == newName {
+			return fmt.Errorf("Plugin name '%v' is already in use", newName)
+		}
+	}
+	pc := c.Plugins[i].clone()
+	oldName := pc.Name
+	pc.Name = newName
+	if pc.UUID != "" {
+		if pc.UUID, err = newUUID(); err != nil {
+			return err
+		}
+	}
+
+	values := make(map[string]interface{})
+	if isIdentifier(oldName) && isIdentifier(newName) {
+		prefix := oldName + "_"
+		rename := func(v string) (string, bool) {
+			if !strings.HasPrefix(v, prefix) {
+				return v, false
+			}
+			if _, ok := c.ValueMap[v]; !ok {
+				return v, false
+			}
+			nv := newName + "_" + strings.TrimPrefix(v, prefix)
+			if _, ok := c.ValueMap[nv]; !ok {
+				values[nv] = c.ValueMap[v]
+			}
+			return nv, true
+		}
+		for k, v := range pc.DataFmt {
+			pc.DataFmt[k] = renameVariables(v, rename)
+		}
+		for k, v := range pc.CVFmt {
+			v.Value = renameVariables(v.Value, rename)
+			pc.CVFmt[k] = v
+		}
+		pc.StartOffsetFmt = renameVariables(pc.StartOffsetFmt, rename)
+	}
+
+	// duplicating should be atomic, so operate on a copy
+	nc := c.Clone()
+	for k, v := range values {
+		if err := nc.SetVariable(k, v); err != nil {
+			return err
+		}
+	}
+	// connections keep referring to the same plugins, even those
+	// identified by URI and instance number
+	nc.rearrange(func() {
+		nc.Plugins = append(nc.Plugins, LV2PluginConfig{})
+		copy(nc.Plugins[i+2:], nc.Plugins[i+1:])
+		nc.Plugins[i+1] = pc
+	}, func(k int) int {
+		if k > i {
+			return k + 1
+		}
+		return k
+	})
+	*c = *nc
+	return nil
+}
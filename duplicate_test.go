@@ -0,0 +1,45 @@
+package lv2hostconfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+func TestDuplicateKeepsConnections(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, `plugins:
+  - name: a
+    pluginUri: urn:test:eq
+  - pluginUri: urn:test:eq
+  - pluginUri: urn:test:eq
+connections:
+  - from: a:out
+    to: urn:test:eq#2:in
+  - from: urn:test:eq#3:out
+    to: system:playback_1
+`)
+	if err := c.DuplicatePlugin("a", "b"); err != nil {
+		t.Fatalf("Failed to duplicate plugin: %v", err)
+	}
+	if c.Plugins[1].Name != "b" {
+		t.Fatalf("Plugins are %+v, want copy right after the original", c.Plugins)
+	}
+	// the copy shares URI with plugins identified by instance number,
+	// which must keep referring to the same plugins
+	want := []lv2hostconfig.LV2Connection{
+		{From: lv2hostconfig.LV2Endpoint{Plugin: "a", Port: "out"}, To: lv2hostconfig.LV2Endpoint{Plugin: "urn:test:eq#3", Port: "in"}},
+		{From: lv2hostconfig.LV2Endpoint{Plugin: "urn:test:eq#4", Port: "out"}, To: lv2hostconfig.LV2Endpoint{Plugin: "system", Port: "playback_1"}},
+	}
+	if !reflect.DeepEqual(c.Connections, want) {
+		t.Errorf("Connections are %v after duplicating, want %v", c.Connections, want)
+	}
+	g, err := c.Graph()
+	if err != nil {
+		t.Fatalf("Failed to build graph: %v", err)
+	}
+	if len(g.Edges) != 2 || g.Edges[0].From != 0 || g.Edges[0].To != 2 || g.Edges[1].From != 3 {
+		t.Errorf("Graph edges are %+v after duplicating, want 0 -> 2 and 3 -> system", g.Edges)
+	}
+}
@@ -0,0 +1,72 @@
+package lv2hostconfig
+
+import (
+	"sort"
+	"time"
+)
+
+// EngineEvaluator is the name of expression evaluator in use
+const EngineEvaluator = "govaluate"
+
+// engineOperators are operators supported by the evaluator. Results
+// of expressions must be numbers, so comparisons and logic
+// operators are only useful inside ternary expressions.
+var engineOperators = []string{
+	"+", "-", "*", "/", "%", "**",
+	"&", "|", "^", "<<", ">>", "~",
+	"==", "!=", ">", ">=", "<", "<=", "=~", "!~",
+	"&&", "||", "!", "?:", "??", "in",
+}
+
+// EngineInfo describes what expressions a config can evaluate, so
+// that tools generating configs can check what the host supports
+type EngineInfo struct {
+	Evaluator string
+	Operators []string
+
+	// Functions are names of all functions in FunctionMap,
+	// Variables are names of variables set by the parser
+	// itself, and Constants are names of all constants
+	Functions []string
+	Variables []string
+	Constants []string
+
+	// Formats are config file formats that can be read and written
+	Formats []Format
+
+	ParamBudget time.Duration
+	TotalBudget time.Duration
+	Memoize     bool
+	AuditLimit  int
+}
+
+// EngineInfo reports capabilities and limits of the expression
+// engine, as configured for this config
+func (c *LV2HostConfig) EngineInfo() EngineInfo {
+	info := EngineInfo{
+		Evaluator:   EngineEvaluator,
+		Operators:   append([]string(nil), engineOperators...),
+		Functions:   make([]string, 0, len(c.FunctionMap)),
+		Variables:   make([]string, 0, len(reservedVariables)),
+		Formats:     []Format{FormatYAML, FormatJSON, FormatTOML},
+		ParamBudget: c.ParamBudget,
+		TotalBudget: c.TotalBudget,
+		Memoize:     c.Memoize,
+	}
+	for name := range c.FunctionMap {
+		info.Functions = append(info.Functions, name)
+	}
+	sort.Strings(info.Functions)
+	for name := range reservedVariables {
+		info.Variables = append(info.Variables, name)
+	}
+	sort.Strings(info.Variables)
+	for name := range c.Constants {
+		info.Constants = append(info.Constants, name)
+	}
+	sort.Strings(info.Constants)
+	if c.Audit != nil {
+		info.AuditLimit = c.Audit.Limit
+	}
+	return info
+}
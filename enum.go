@@ -0,0 +1,94 @@
+package lv2hostconfig
+
+import (
+	"sort"
+	"strconv"
+)
+
+// EnumTable maps labels of an enumerated parameter, such as LV2
+// scale points, to their values
+type EnumTable map[string]float32
+
+// label returns label of a value, if there is one. If several
+// labels have the same value, the first one in sorted order wins.
+func (t EnumTable) label(v float32) (string, bool) {
+	labels := make([]string, 0, len(t))
+	for l, lv := range t {
+		if lv == v {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) == 0 {
+		return "", false
+	}
+	sort.Strings(labels)
+	return labels[0], true
+}
+
+// RegisterEnum registers labels of an enumerated parameter of
+// plugin with given URI, replacing any labels already registered
+// for it. Parameters of such plugins can then be set by label, e.g.
+// mode: "Soft Knee", and the label is translated to its value on
+// evaluation. When the config is written, literal values that have
+// a label are written as labels, unless they were read from the
+// file as numbers.
+func (c *LV2HostConfig) RegisterEnum(uri, symbol string, labels EnumTable) {
+	if c.Enums == nil {
+		c.Enums = make(map[string]map[string]EnumTable)
+	}
+	if c.Enums[uri] == nil {
+		c.Enums[uri] = make(map[string]EnumTable)
+	}
+	t := make(EnumTable, len(labels))
+	for k, v := range labels {
+		t[k] = v
+	}
+	c.Enums[uri][symbol] = t
+}
+
+// copyEnums copies a map of enum tables
+func copyEnums(enums map[string]map[string]EnumTable) map[string]map[string]EnumTable {
+	if enums == nil {
+		return nil
+	}
+	res := make(map[string]map[string]EnumTable, len(enums))
+	for uri, tables := range enums {
+		res[uri] = make(map[string]EnumTable, len(tables))
+		for symbol, table := range tables {
+			t := make(EnumTable, len(table))
+			for k, v := range table {
+				t[k] = v
+			}
+			res[uri][symbol] = t
+		}
+	}
+	return res
+}
+
+// enumValue translates a parameter label to its value
+func (c *LV2HostConfig) enumValue(pc *LV2PluginConfig, symbol, value string) (float32, bool) {
+	v, ok := c.Enums[pc.PluginURI][symbol][value]
+	return v, ok
+}
+
+// enumLabel translates a literal parameter value to its label,
+// unless it was read from a file as is
+func (c *LV2HostConfig) enumLabel(pc *LV2PluginConfig, symbol, value string) string {
+	t, ok := c.Enums[pc.PluginURI][symbol]
+	if !ok {
+		return value
+	}
+	if pc.source != nil {
+		if ref, ok := pc.source.params[symbol]; ok && ref.node.Value == value {
+			return value
+		}
+	}
+	v, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return value
+	}
+	if l, ok := t.label(float32(v)); ok {
+		return l
+	}
+	return value
+}
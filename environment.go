@@ -0,0 +1,93 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+const modulePath = "github.com/burillo-se/lv2hostconfig"
+
+// LV2Environment describes how a saved config was produced: version
+// of this package that wrote it, when the config was last evaluated,
+// name of the host it was written on, and values of variables
+// expressions were evaluated with.
+type LV2Environment struct {
+	Version     string                 `yaml:"version,omitempty"`
+	EvaluatedAt time.Time              `yaml:"evaluatedAt,omitempty"`
+	Host        string                 `yaml:"host,omitempty"`
+	Values      map[string]interface{} `yaml:"values,omitempty"`
+}
+
+func (e *LV2Environment) clone() *LV2Environment {
+	if e == nil {
+		return nil
+	}
+	ne := *e
+	if e.Values != nil {
+		ne.Values = make(map[string]interface{})
+		for k, v := range e.Values {
+			ne.Values[k] = v
+		}
+	}
+	return &ne
+}
+
+// packageVersion returns version of this package, as recorded in
+// build info of the binary
+func packageVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if bi.Main.Path == modulePath {
+		return bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// captureEnvironment describes current environment of the config.
+// Only values of basic types are recorded, and built-in constants
+// are left out unless they were redefined.
+func (c *LV2HostConfig) captureEnvironment() (*LV2Environment, error) {
+	vars, err := c.baseValues()
+	if err != nil {
+		return nil, err
+	}
+	env := &LV2Environment{
+		Version:     packageVersion(),
+		EvaluatedAt: c.EvaluatedAt,
+		Values:      make(map[string]interface{}),
+	}
+	if host, err := os.Hostname(); err == nil {
+		env.Host = host
+	}
+	for k, v := range vars {
+		if b, ok := builtinConstants[k]; ok && v == b {
+			continue
+		}
+		switch v.(type) {
+		case bool, string, float32, float64, int, int32, int64, uint, uint32, uint64:
+			env.Values[k] = v
+		}
+	}
+	return env, nil
+}
+
+// CaptureEnvironment replaces environment of the config with the
+// current one, which is what WriteToFile and friends do when
+// RecordEnvironment is set
+func (c *LV2HostConfig) CaptureEnvironment() error {
+	env, err := c.captureEnvironment()
+	if err != nil {
+		return fmt.Errorf("Failed to capture environment: %v", err)
+	}
+	c.Environment = env
+	return nil
+}
@@ -0,0 +1,91 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EvaluationErrors holds all errors found by EvaluateAll
+type EvaluationErrors struct {
+	Errors []error
+}
+
+func (e *EvaluationErrors) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		lines = append(lines, err.Error())
+	}
+	return fmt.Sprintf("%v errors evaluating config:\n%v", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+// Unwrap returns all of the errors
+func (e *EvaluationErrors) Unwrap() []error {
+	return e.Errors
+}
+
+// totalBudgetExceeded checks if error means no more values can be
+// evaluated within total evaluation budget
+func totalBudgetExceeded(err error) bool {
+	be, ok := err.(*BudgetError)
+	return ok && be.Total
+}
+
+// evaluateChainAll evaluates every value of a chain, adding
+// failures to errs
+func (c *LV2HostConfig) evaluateChainAll(chain []LV2PluginConfig, vars map[string]interface{}, run *evalRun, errs *[]error) []LV2PluginConfig {
+	pcs := make([]LV2PluginConfig, 0, len(chain))
+	for i := range chain {
+		run.plugin = i
+		if len(*errs) != 0 && totalBudgetExceeded((*errs)[len(*errs)-1]) {
+			pcs = append(pcs, chain[i].clone())
+			continue
+		}
+		pc, perrs := c.evaluateValues(&chain[i], vars, run, false)
+		pcs = append(pcs, pc)
+		*errs = append(*errs, perrs...)
+	}
+	return pcs
+}
+
+// EvaluateAll works like Evaluate, but doesn't stop at the first
+// bad value. Every value is evaluated, values that did evaluate
+// are stored in the config, failed ones are left out, and all
+// failures are returned as *EvaluationErrors, in chain order
+// (Plugins first, then named chains in order of their names).
+// Validators are only run (and EvaluatedAt is only updated) if
+// there were no errors. Evaluation still stops if the total
+// budget is exceeded, and plugins that were not reached keep
+// their previous values.
+func (c *LV2HostConfig) EvaluateAll() error {
+	if c.Audit != nil {
+		c.Audit.Reset()
+	}
+	vars, err := c.evalValues()
+	if err != nil {
+		return err
+	}
+	run := c.newEvalRun()
+
+	errs := make([]error, 0)
+	pcs := c.evaluateChainAll(c.Plugins, vars, run, &errs)
+	chains, _ := c.mapChains(func(_ string, chain []LV2PluginConfig) ([]LV2PluginConfig, error) {
+		return c.evaluateChainAll(chain, vars, run, &errs), nil
+	})
+
+	if len(errs) == 0 {
+		if err := c.validate(pcs); err != nil {
+			return err
+		}
+		if err := c.validateChains(chains); err != nil {
+			return err
+		}
+	}
+	c.Plugins = pcs
+	c.Chains = chains
+	c.timings = run.timings
+	if len(errs) != 0 {
+		return &EvaluationErrors{errs}
+	}
+	c.EvaluatedAt = c.now()
+	return nil
+}
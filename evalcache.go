@@ -0,0 +1,149 @@
+package lv2hostconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// EvalCacheSuffix is appended to config file name to get name of
+// its evaluation cache file
+const EvalCacheSuffix = ".eval"
+
+// evalCache is contents of evaluation cache file. Evaluated values
+// are stored the same way sandbox process returns them.
+type evalCache struct {
+	Hash    string
+	Plugins []sandboxResult
+	Chains  map[string][]sandboxResult
+}
+
+// evalHash hashes everything evaluation results depend on, apart
+// from functions: config file contents, values of variables, and
+// field names used to parse the file
+func evalHash(data []byte, vars map[string]interface{}, fields FieldMap) string {
+	h := sha256.New()
+	h.Write(data)
+	names := make([]string, 0, len(vars))
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Fprintf(h, "\x00%v=%T:%v", k, vars[k], vars[k])
+	}
+	names = names[:0]
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Fprintf(h, "\x00%v:%v", k, fields[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fromCache sets evaluated values from cache, returning false if
+// cache doesn't match the config
+func (c *LV2HostConfig) fromCache(cache *evalCache) (bool, error) {
+	pcs, err := applyResults(c.Plugins, cache.Plugins)
+	if err != nil {
+		return false, nil
+	}
+	if len(cache.Chains) != len(c.Chains) {
+		return false, nil
+	}
+	chains, err := c.mapChains(func(name string, chain []LV2PluginConfig) ([]LV2PluginConfig, error) {
+		return applyResults(chain, cache.Chains[name])
+	})
+	if err != nil {
+		return false, nil
+	}
+	if err := c.validate(pcs); err != nil {
+		return false, err
+	}
+	if err := c.validateChains(chains); err != nil {
+		return false, err
+	}
+	c.Plugins = pcs
+	c.Chains = chains
+	c.EvaluatedAt = c.now()
+	return true, nil
+}
+
+// ReadFileCached reads and evaluates config file, using evaluation
+// results cached in a sidecar file (file name with EvalCacheSuffix
+// appended) if the hash of the config file and variable values
+// matches the one stored in cache. Otherwise, config is evaluated
+// and the cache is updated. Validators are run in either case.
+// Functions are not part of the hash, so the cache must be removed
+// if they change. Returns true if cached results were used.
+// Reading is atomic, config is only modified on success.
+func (c *LV2HostConfig) ReadFileCached(file string) (bool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, fmt.Errorf("Failed to read config: %v", err)
+	}
+	nc := c.Clone()
+	rc, err := ParseWith(data, ParseOptions{File: file, Fields: nc.Fields})
+	if err != nil {
+		return false, err
+	}
+	if err := nc.Load(rc); err != nil {
+		return false, err
+	}
+	vars, err := nc.evalValues()
+	if err != nil {
+		return false, err
+	}
+	hash := evalHash(data, vars, nc.Fields)
+
+	cacheFile := file + EvalCacheSuffix
+	if cached, err := ioutil.ReadFile(cacheFile); err == nil {
+		var cache evalCache
+		if json.Unmarshal(cached, &cache) == nil && cache.Hash == hash {
+			ok, err := nc.fromCache(&cache)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				*c = *nc
+				return true, nil
+			}
+		}
+	}
+
+	if err := nc.Evaluate(); err != nil {
+		return false, err
+	}
+	cache := evalCache{Hash: hash, Plugins: sandboxResults(nc.Plugins)}
+	if nc.Chains != nil {
+		cache.Chains = make(map[string][]sandboxResult, len(nc.Chains))
+		for name, chain := range nc.Chains {
+			cache.Chains[name] = sandboxResults(chain)
+		}
+	}
+	out, err := json.Marshal(&cache)
+	if err != nil {
+		return false, fmt.Errorf("Failed to write evaluation cache: %v", err)
+	}
+	if err := writeFileAtomic(cacheFile, out, 0644); err != nil {
+		return false, err
+	}
+	*c = *nc
+	return false, nil
+}
+
+// RemoveEvalCache removes evaluation cache of a config file, if
+// there is one
+func RemoveEvalCache(file string) error {
+	err := os.Remove(file + EvalCacheSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to remove evaluation cache: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package lv2hostconfig
+
+import (
+	"strconv"
+	"strings"
+)
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// isIdentifier checks whether name can be used as a variable
+// name in expressions without escaping
+func isIdentifier(name string) bool {
+	if name == "" || !isIdentStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isIdentChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isExpression checks whether formatted value is an expression,
+// as opposed to a plain float value
+func isExpression(value string) bool {
+	_, err := strconv.ParseFloat(value, 32)
+	return err != nil
+}
+
+// renameVariables rewrites variable names in expression according
+// to rename function, which returns the new name and whether the
+// variable should be renamed at all. Function names and string
+// literals are left intact, and escaped [variable] names are
+// supported.
+func renameVariables(expr string, rename func(string) (string, bool)) string {
+	if !isExpression(expr) {
+		return expr
+	}
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '\'' || c == '"':
+			// string literal, copy verbatim
+			j := i + 1
+			for j < len(expr) && expr[j] != c {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(expr) {
+				j++
+			}
+			b.WriteString(expr[i:j])
+			i = j
+		case c == '[':
+			// escaped variable name
+			j := strings.IndexByte(expr[i:], ']')
+			if j < 0 {
+				b.WriteString(expr[i:])
+				return b.String()
+			}
+			name := expr[i+1 : i+j]
+			if n, ok := rename(name); ok {
+				name = n
+			}
+			b.WriteString("[" + name + "]")
+			i += j + 1
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(expr) && isIdentChar(expr[j]) {
+				j++
+			}
+			name := expr[i:j]
+			// function names are not variables
+			k := j
+			for k < len(expr) && expr[k] == ' ' {
+				k++
+			}
+			if k < len(expr) && expr[k] == '(' {
+				b.WriteString(name)
+			} else if n, ok := rename(name); ok {
+				if isIdentifier(n) {
+					b.WriteString(n)
+				} else {
+					b.WriteString("[" + n + "]")
+				}
+			} else {
+				b.WriteString(name)
+			}
+			i = j
+		case c >= '0' && c <= '9':
+			// numbers may contain letters, e.g. 1e10
+			j := i + 1
+			for j < len(expr) && (isIdentChar(expr[j]) || expr[j] == '.') {
+				j++
+			}
+			b.WriteString(expr[i:j])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
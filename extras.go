@@ -0,0 +1,37 @@
+package lv2hostconfig
+
+// copyExtras makes a deep copy of unrecognized fields
+func copyExtras(extras map[string]interface{}) map[string]interface{} {
+	if len(extras) == 0 {
+		return nil
+	}
+	e := make(map[string]interface{})
+	for k, v := range extras {
+		e[k] = copyExtra(v)
+	}
+	return e
+}
+
+func copyExtra(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{})
+		for k, mv := range t {
+			m[k] = copyExtra(mv)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{})
+		for k, mv := range t {
+			m[k] = copyExtra(mv)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, lv := range t {
+			l[i] = copyExtra(lv)
+		}
+		return l
+	}
+	return v
+}
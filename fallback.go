@@ -0,0 +1,60 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+)
+
+// Degradation describes a fallback to another config file
+// because primary one could not be loaded
+type Degradation struct {
+	Primary  string
+	Fallback string
+	Reason   error
+}
+
+func (d *Degradation) String() string {
+	return fmt.Sprintf("Using fallback config '%v' instead of '%v': %v", d.Fallback, d.Primary, d.Reason)
+}
+
+// load reads and evaluates config file. Loading is atomic, so
+// config is only modified if both reading and evaluation succeed.
+func (c *LV2HostConfig) load(file string) error {
+	nc := c.Clone()
+	if err := nc.ReadFile(file); err != nil {
+		return err
+	}
+	if err := nc.Evaluate(); err != nil {
+		return err
+	}
+	*c = *nc
+	return nil
+}
+
+// ReadFileWithFallback reads and evaluates primary config file.
+// If that fails for any reason (including validators vetoing the
+// config), fallback file is loaded instead, and the degradation
+// is reported. Unlike ReadFile, config is evaluated on success.
+func (c *LV2HostConfig) ReadFileWithFallback(primary, fallback string) (*Degradation, error) {
+	err := c.load(primary)
+	if err == nil {
+		return nil, nil
+	}
+	if ferr := c.load(fallback); ferr != nil {
+		return nil, fmt.Errorf("Failed to load config '%v' (%v) or its fallback '%v': %v", primary, err, fallback, ferr)
+	}
+	return &Degradation{primary, fallback, err}, nil
+}
+
+// MarkLastKnownGood saves config to a file meant to be used as a
+// fallback. File is replaced atomically, so that a crash midway
+// never leaves a broken fallback behind.
+func (c *LV2HostConfig) MarkLastKnownGood(file string) error {
+	// temporary file has a different extension, so format has to
+	// be picked by name of the file being replaced
+	data, err := c.encode(FormatOf(file))
+	if err != nil {
+		return err
+	}
+	// configs aren't private, unlike temporary files
+	return writeFileAtomic(file, data, 0644)
+}
@@ -0,0 +1,162 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// FieldMap maps standard names of config fields (such as
+// "pluginUri", "parameters" or "referenceLevel") to names used
+// in config files, so that files following established schemas
+// with different key names can be read and written without any
+// pre-processing. Both top-level and plugin fields can be renamed.
+type FieldMap map[string]string
+
+// yamlFields returns names of fields of a raw struct type
+func yamlFields(t reflect.Type) map[string]bool {
+	res := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" {
+			res[name] = true
+		}
+	}
+	return res
+}
+
+var (
+	hostFields   = yamlFields(reflect.TypeOf(lv2HostRaw{}))
+	pluginFields = yamlFields(reflect.TypeOf(lv2PluginRaw{}))
+)
+
+// check makes sure field map only renames known fields, and that
+// no two fields end up with the same name
+func (m FieldMap) check() error {
+	used := make(map[string]string)
+	for from, to := range m {
+		if !hostFields[from] && !pluginFields[from] {
+			return fmt.Errorf("Unknown config field '%v'", from)
+		}
+		if to == "" {
+			return fmt.Errorf("Config field '%v' is mapped to empty name", from)
+		}
+		if other, ok := used[to]; ok {
+			return fmt.Errorf("Config fields '%v' and '%v' are both mapped to '%v'", from, other, to)
+		}
+		used[to] = from
+	}
+	for from, to := range m {
+		_, renamed := m[to]
+		if to != from && (hostFields[to] || pluginFields[to]) && !renamed {
+			return fmt.Errorf("Config field '%v' is mapped to name of another field", from)
+		}
+	}
+	return nil
+}
+
+// renamedKeys returns a copy of mapping node with keys renamed,
+// including keys of any mappings merged into it. Mappings can be
+// reached through aliases, and so be used elsewhere, which is why
+// they are copied rather than modified. Values are kept as is.
+func renamedKeys(n *yaml.Node, names map[string]string) *yaml.Node {
+	m, _ := resolveAlias(n)
+	if m == nil || m.Kind != yaml.MappingNode {
+		return n
+	}
+	res := *m
+	res.Anchor = ""
+	res.Content = make([]*yaml.Node, 0, len(m.Content))
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		if k.Tag == "!!merge" {
+			if seq, _ := resolveAlias(v); seq != nil && seq.Kind == yaml.SequenceNode {
+				merged := *seq
+				merged.Anchor = ""
+				merged.Content = make([]*yaml.Node, 0, len(seq.Content))
+				for _, item := range seq.Content {
+					merged.Content = append(merged.Content, renamedKeys(item, names))
+				}
+				v = &merged
+			} else {
+				v = renamedKeys(v, names)
+			}
+		} else if name, ok := names[k.Value]; ok {
+			nk := *k
+			nk.Value = name
+			k = &nk
+		}
+		res.Content = append(res.Content, k, v)
+	}
+	return &res
+}
+
+// renameFields renames host and plugin fields of a document.
+// names maps current names of fields to new ones, and pluginsKey
+// and chainsKey are current names of plugins and chains fields.
+func renameFields(doc *yaml.Node, names map[string]string, pluginsKey, chainsKey string) {
+	if len(names) == 0 || len(doc.Content) == 0 {
+		return
+	}
+	top := renamedKeys(doc.Content[0], names)
+	if name, ok := names[pluginsKey]; ok {
+		pluginsKey = name
+	}
+	if name, ok := names[chainsKey]; ok {
+		chainsKey = name
+	}
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		switch top.Content[i].Value {
+		case pluginsKey:
+			top.Content[i+1] = renamedPlugins(top.Content[i+1], names)
+		case chainsKey:
+			chains, _ := resolveAlias(top.Content[i+1])
+			if chains == nil || chains.Kind != yaml.MappingNode {
+				continue
+			}
+			renamed := *chains
+			renamed.Anchor = ""
+			renamed.Content = make([]*yaml.Node, 0, len(chains.Content))
+			for j := 0; j+1 < len(chains.Content); j += 2 {
+				renamed.Content = append(renamed.Content, chains.Content[j], renamedPlugins(chains.Content[j+1], names))
+			}
+			top.Content[i+1] = &renamed
+		}
+	}
+	doc.Content[0] = top
+}
+
+// renamedPlugins returns a copy of sequence of plugin entries with
+// their keys renamed
+func renamedPlugins(n *yaml.Node, names map[string]string) *yaml.Node {
+	plugins, _ := resolveAlias(n)
+	if plugins == nil || plugins.Kind != yaml.SequenceNode {
+		return n
+	}
+	renamed := *plugins
+	renamed.Anchor = ""
+	renamed.Content = make([]*yaml.Node, 0, len(plugins.Content))
+	for _, item := range plugins.Content {
+		renamed.Content = append(renamed.Content, renamedKeys(item, names))
+	}
+	return &renamed
+}
+
+// fileName returns name field has in config files
+func (m FieldMap) fileName(field string) string {
+	if name, ok := m[field]; ok {
+		return name
+	}
+	return field
+}
+
+// reverse maps names fields have in config files to standard ones
+func (m FieldMap) reverse() map[string]string {
+	res := make(map[string]string, len(m))
+	for from, to := range m {
+		res[to] = from
+	}
+	return res
+}
@@ -0,0 +1,82 @@
+package lv2hostconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Format is a config file format
+type Format int
+
+// supported config file formats
+const (
+	FormatYAML Format = iota
+	FormatJSON
+	FormatTOML
+)
+
+// FormatOf guesses config file format from its extension. Files
+// with .json extension are JSON, files with .toml extension are
+// TOML, and anything else is YAML.
+func FormatOf(file string) Format {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+func encodeYAML(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeJSON encodes a document as JSON. Document is decoded into
+// generic values first, so that YAML field names are used as is.
+func encodeJSON(doc *yaml.Node) ([]byte, error) {
+	var v interface{}
+	if err := doc.Decode(&v); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ReadFileJSON reads a JSON config. Unlike ReadFile, it fails if
+// the file is not valid JSON.
+func (c *LV2HostConfig) ReadFileJSON(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read config: %v", err)
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("Failed to parse config: '%v' is not valid JSON", file)
+	}
+	_, err = c.readFrom(bytes.NewReader(data), file, FormatJSON)
+	return err
+}
+
+// WriteToFileJSON writes config in JSON form, regardless of file
+// extension
+func (c *LV2HostConfig) WriteToFileJSON(file string) error {
+	return c.writeFile(file, FormatJSON)
+}
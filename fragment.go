@@ -0,0 +1,161 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Fragment is a partial config, such as plugins generated by
+// code, meant to be layered on top of another config. Plugins
+// are inserted after plugin identified by After, or appended to
+// the chain if After is empty. Values are added to ValueMap, and
+// Connections (which may refer to plugins of both the fragment and
+// the config) are added to connections of the config.
+type Fragment struct {
+	Plugins     []LV2PluginConfig
+	Values      map[string]interface{}
+	After       string
+	Connections []LV2Connection
+}
+
+// FragmentConflictError is returned when a fragment can't be
+// applied to a config because it conflicts with its contents
+type FragmentConflictError struct {
+	Conflicts []string
+}
+
+func (e *FragmentConflictError) Error() string {
+	return fmt.Sprintf("Fragment conflicts with config: %v", strings.Join(e.Conflicts, "; "))
+}
+
+// NewFragment creates a new empty fragment
+func NewFragment() *Fragment {
+	return &Fragment{
+		make([]LV2PluginConfig, 0),
+		make(map[string]interface{}),
+		"",
+		nil,
+	}
+}
+
+// conflicts lists all reasons fragment can't be applied to config
+func (f *Fragment) conflicts(c *LV2HostConfig) []string {
+	conflicts := make([]string, 0)
+	names := make(map[string]bool)
+	uuids := make(map[string]bool)
+	for _, pc := range c.Plugins {
+		names[pc.Name] = true
+		uuids[pc.UUID] = true
+	}
+	for _, pc := range f.Plugins {
+		if pc.Name != "" {
+			if names[pc.Name] {
+				conflicts = append(conflicts, fmt.Sprintf("plugin name '%v' is already in use", pc.Name))
+			}
+			names[pc.Name] = true
+		}
+		if pc.UUID != "" {
+			if uuids[pc.UUID] {
+				conflicts = append(conflicts, fmt.Sprintf("plugin UUID '%v' is already in use", pc.UUID))
+			}
+			uuids[pc.UUID] = true
+		}
+	}
+	for k, v := range f.Values {
+		if cv, ok := c.ValueMap[k]; ok && !reflect.DeepEqual(cv, v) {
+			conflicts = append(conflicts, fmt.Sprintf("variable '%v' is already set to '%v'", k, cv))
+		}
+	}
+	return conflicts
+}
+
+// connectionConflicts lists connections of the fragment that are
+// already in the config, or that feed an input which is already
+// connected, given a config with connections of the fragment last
+func (f *Fragment) connectionConflicts(routed *LV2HostConfig) []string {
+	conflicts := make([]string, 0)
+	edges, err := routed.resolveConnections()
+	if err != nil {
+		// reported by Graph
+		return conflicts
+	}
+	type input struct {
+		plugin int
+		port   string
+	}
+	fed := make(map[input]int)
+	first := len(edges) - len(f.Connections)
+	for i, e := range edges {
+		j, ok := fed[input{e.To, e.ToPort}]
+		if !ok {
+			fed[input{e.To, e.ToPort}] = i
+			continue
+		}
+		if i < first {
+			continue
+		}
+		conn := routed.Connections[i]
+		if edges[j].From == e.From && edges[j].FromPort == e.FromPort {
+			conflicts = append(conflicts, fmt.Sprintf("connection '%v' already exists", conn))
+		} else {
+			conflicts = append(conflicts, fmt.Sprintf("input '%v' is already connected to '%v'", conn.To, routed.Connections[j].From))
+		}
+	}
+	return conflicts
+}
+
+// Apply layers the fragment on top of a config. Applying is atomic,
+// so if there are any conflicts (plugin names or UUIDs already in
+// use, variables already set to a different value, connections to
+// unknown plugins, forming a cycle, repeating existing connections
+// or feeding inputs that are already connected), they are all
+// reported and config is left untouched.
+func (f *Fragment) Apply(c *LV2HostConfig) error {
+	pos := len(c.Plugins)
+	if f.After != "" {
+		i, err := c.findPluginIndex(f.After)
+		if err != nil {
+			return err
+		}
+		pos = i + 1
+	}
+	conflicts := f.conflicts(c)
+
+	pcs := make([]LV2PluginConfig, 0, len(c.Plugins)+len(f.Plugins))
+	pcs = append(pcs, c.Plugins[:pos]...)
+	for _, pc := range f.Plugins {
+		pcs = append(pcs, pc.clone())
+	}
+	pcs = append(pcs, c.Plugins[pos:]...)
+
+	// existing connections must keep referring to the same plugins
+	routed := &LV2HostConfig{Plugins: pcs}
+	if c.Connections != nil || f.Connections != nil {
+		edges, err := c.resolveConnections()
+		if err != nil {
+			return err
+		}
+		routed.remapConnections(edges, func(k int) int {
+			if k >= pos {
+				return k + len(f.Plugins)
+			}
+			return k
+		})
+		routed.Connections = append(routed.Connections, f.Connections...)
+		if _, err := routed.Graph(); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+		conflicts = append(conflicts, f.connectionConflicts(routed)...)
+	}
+	if len(conflicts) != 0 {
+		return &FragmentConflictError{conflicts}
+	}
+
+	c.Plugins = pcs
+	c.Connections = routed.Connections
+	for k, v := range f.Values {
+		c.ValueMap[k] = v
+	}
+	return nil
+}
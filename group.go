@@ -0,0 +1,150 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+)
+
+// Import appends all plugins of another config to this one as
+// a named group. To avoid collisions, variables from ValueMap of
+// the imported config are namespaced as "<group>_<variable>", and
+// any expressions and properties referring to them are rewritten
+// accordingly. Variables not found in ValueMap of the imported
+// config (such as those coming from providers) are left as is.
+// Names of imported plugins are prefixed with "<group>/", and so
+// are names of imported named chains. References to parameters
+// of imported plugins are rewritten to keep referring to the same
+// plugins, and importing fails if any of them can't be resolved.
+// Connections of the imported config are added to connections of
+// this one. Functions missing from this config are imported as
+// well, but existing ones are never replaced.
+func (c *LV2HostConfig) Import(other *LV2HostConfig, group string) error {
+	if !isIdentifier(group) {
+		return fmt.Errorf("Group name '%v' is not a valid identifier", group)
+	}
+	for _, pc := range c.Plugins {
+		if pc.Group == group {
+			return fmt.Errorf("Group '%v' already exists", group)
+		}
+	}
+
+	// importing should be atomic, so check for collisions first
+	values := make(map[string]interface{})
+	for k, v := range other.ValueMap {
+		nk := group + "_" + k
+		if _, ok := c.ValueMap[nk]; ok {
+			return fmt.Errorf("Variable '%v' already exists", nk)
+		}
+		values[nk] = v
+	}
+	chains := make(map[string][]LV2PluginConfig)
+	for _, name := range other.ChainNames() {
+		nn := group + "/" + name
+		if _, ok := c.Chains[nn]; ok {
+			return fmt.Errorf("Chain '%v' already exists", nn)
+		}
+		chains[nn] = importPlugins(other.Chains[name], group)
+	}
+	pcs := make([]LV2PluginConfig, 0, len(c.Plugins)+len(other.Plugins))
+	pcs = append(pcs, c.Plugins...)
+	pcs = append(pcs, importPlugins(other.Plugins, group)...)
+	routed := &LV2HostConfig{Plugins: pcs}
+	first := len(c.Plugins)
+
+	var refErr error
+	rename := func(name string) (string, bool) {
+		if _, ok := other.ValueMap[name]; ok {
+			return group + "_" + name, true
+		}
+		plugin, symbol, err := splitParamRef(name)
+		if err != nil {
+			return name, false
+		}
+		j, err := other.findPluginIndex(plugin)
+		if err == nil {
+			if _, ok := other.Plugins[j].DataFmt[symbol]; ok {
+				return routed.InstanceID(first+j) + "." + symbol, true
+			}
+		}
+		if refErr == nil {
+			refErr = fmt.Errorf("Parameter reference '%v' of imported config can't be resolved", name)
+		}
+		return name, false
+	}
+	for i := first; i < len(pcs); i++ {
+		pcs[i].renameVariables(rename)
+	}
+	for _, name := range sortedKeys(chains) {
+		for i := range chains[name] {
+			chains[name][i].renameVariables(rename)
+		}
+	}
+	if refErr != nil {
+		return refErr
+	}
+
+	// existing connections must keep referring to the same plugins
+	if c.Connections != nil || other.Connections != nil {
+		edges, err := c.resolveConnections()
+		if err != nil {
+			return err
+		}
+		imported, err := other.resolveConnections()
+		if err != nil {
+			return fmt.Errorf("Failed to import connections: %v", err)
+		}
+		routed.remapConnections(edges, func(k int) int { return k })
+		conns := routed.Connections
+		routed.remapConnections(imported, func(k int) int { return first + k })
+		routed.Connections = append(conns, routed.Connections...)
+	}
+
+	c.Plugins = pcs
+	c.Connections = routed.Connections
+	if len(chains) != 0 {
+		if c.Chains == nil {
+			c.Chains = make(map[string][]LV2PluginConfig)
+		}
+		for name, chain := range chains {
+			c.Chains[name] = chain
+		}
+	}
+	for k, v := range values {
+		c.ValueMap[k] = v
+	}
+	for k, f := range other.FunctionMap {
+		if _, ok := c.FunctionMap[k]; !ok {
+			c.FunctionMap[k] = f
+		}
+	}
+	return nil
+}
+
+// importPlugins makes copies of plugins of a group being imported
+func importPlugins(chain []LV2PluginConfig, group string) []LV2PluginConfig {
+	pcs := make([]LV2PluginConfig, 0, len(chain))
+	for _, opc := range chain {
+		pc := opc.clone()
+		pc.Group = group
+		if pc.Name != "" {
+			pc.Name = group + "/" + pc.Name
+		}
+		pcs = append(pcs, pc)
+	}
+	return pcs
+}
+
+// renameVariables renames variables referenced by expressions and
+// properties of a plugin
+func (pc *LV2PluginConfig) renameVariables(rename func(string) (string, bool)) {
+	for k, v := range pc.DataFmt {
+		pc.DataFmt[k] = renameVariables(v, rename)
+	}
+	for k, v := range pc.CVFmt {
+		v.Value = renameVariables(v.Value, rename)
+		pc.CVFmt[k] = v
+	}
+	pc.StartOffsetFmt = renameVariables(pc.StartOffsetFmt, rename)
+	for k, v := range pc.PropertiesFmt {
+		pc.PropertiesFmt[k] = renameProperty(v, rename)
+	}
+}
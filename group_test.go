@@ -0,0 +1,72 @@
+package lv2hostconfig_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+const importedConfig = `plugins:
+  - name: eq
+    pluginUri: urn:test:eq
+    parameters:
+      gain: level
+  - pluginUri: urn:test:comp
+    parameters:
+      threshold: "[eq.gain] - 6"
+    properties:
+      sample: ${dir}/kick.wav
+chains:
+  side:
+    - pluginUri: urn:test:comp
+      parameters:
+        threshold: "[eq.gain] - 12"
+connections:
+  - from: eq:out
+    to: urn:test:comp#1:in
+`
+
+func TestImportRewritesReferences(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - name: eq\n    pluginUri: urn:test:eq\n    parameters:\n      gain: '1'\n")
+	other := lv2hostconfigtest.Parse(t, importedConfig)
+	other.ValueMap["level"] = 3.0
+	other.ValueMap["dir"] = "/samples"
+	if err := c.Import(other, "grp"); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	if got := c.Plugins[2].DataFmt["threshold"]; got != "[grp/eq.gain] - 6" {
+		t.Errorf("Reference was rewritten as '%v', want '[grp/eq.gain] - 6'", got)
+	}
+	if got := c.Plugins[2].PropertiesFmt["sample"]; got != "${grp_dir}/kick.wav" {
+		t.Errorf("Property was rewritten as '%v', want '${grp_dir}/kick.wav'", got)
+	}
+	side, ok := c.Chains["grp/side"]
+	if !ok || side[0].DataFmt["threshold"] != "[grp/eq.gain] - 12" {
+		t.Errorf("Chains are %+v, want 'grp/side' referring to 'grp/eq'", c.Chains)
+	}
+	want := []lv2hostconfig.LV2Connection{
+		{From: lv2hostconfig.LV2Endpoint{Plugin: "grp/eq", Port: "out"}, To: lv2hostconfig.LV2Endpoint{Plugin: "urn:test:comp#1", Port: "in"}},
+	}
+	if !reflect.DeepEqual(c.Connections, want) {
+		t.Errorf("Connections are %v after import, want %v", c.Connections, want)
+	}
+	lv2hostconfigtest.MustEvaluate(t, c)
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", 1, 0)
+	lv2hostconfigtest.AssertValue(t, c, 2, "threshold", -3, 1e-6)
+	if got := c.Plugins[2].Properties["sample"]; got != "/samples/kick.wav" {
+		t.Errorf("Property is '%v', want '/samples/kick.wav'", got)
+	}
+}
+
+func TestImportUnresolvedReference(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - name: eq\n    pluginUri: urn:test:eq\n    parameters:\n      gain: '1'\n")
+	want := c.Clone()
+	other := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:comp\n    parameters:\n      threshold: '[eq.gain] - 6'\n")
+	// eq is a plugin of the config, not of the imported one
+	if err := c.Import(other, "grp"); err == nil {
+		t.Fatal("Importing unresolved reference succeeded")
+	}
+	assertUntouched(t, c, want)
+}
@@ -0,0 +1,112 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Fields of multi-host config files
+const (
+	hostsField     = "hosts"
+	variablesField = "variables"
+)
+
+// readHosts parses a multi-host config file, returning mapping of
+// hosts and shared variables, if there are any
+func readHosts(file string) (map[string]nodeRef, *yaml.Node, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read config: %v", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, parseError(file, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, fmt.Errorf("Failed to parse config: '%v' is empty", file)
+	}
+	top := mappingNodes(doc.Content[0], false)
+	hosts, ok := top[hostsField]
+	if !ok || hosts.node.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("Failed to parse config: '%v' has no hosts", file)
+	}
+	var vars *yaml.Node
+	if v, ok := top[variablesField]; ok {
+		vars = v.node
+	}
+	return mappingNodes(hosts.node, hosts.shared), vars, nil
+}
+
+// HostNames returns names of all hosts described by a multi-host
+// config file, sorted
+func HostNames(file string) ([]string, error) {
+	hosts, _, err := readHosts(file)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ReadHost reads configuration of a single host from a multi-host
+// config file, which describes several hosts (e.g. FOH and monitor
+// rigs) sharing a common set of variables:
+//
+//	variables:
+//	  roomGain: 3
+//	hosts:
+//	  foh:
+//	    referenceLevel: 0
+//	    plugins: ...
+//	  monitor:
+//	    plugins: ...
+//
+// Each host is described the same way as a regular config, and
+// shared variables are added to (or replace existing values in)
+// ValueMap. Just like ReadFile, this replaces plugins, and values
+// are not evaluated until Evaluate is called. Writing the config
+// back produces a regular, single-host config.
+func (c *LV2HostConfig) ReadHost(file, hostName string) error {
+	hosts, varsNode, err := readHosts(file)
+	if err != nil {
+		return err
+	}
+	host, ok := hosts[hostName]
+	if !ok {
+		return fmt.Errorf("Failed to parse config: host '%v' not found in '%v'", hostName, file)
+	}
+
+	vars := make(map[string]interface{})
+	if varsNode != nil {
+		if err := varsNode.Decode(&vars); err != nil {
+			return parseError(file, err)
+		}
+	}
+	for name := range vars {
+		if !isIdentifier(name) || reservedVariables[name] {
+			return fmt.Errorf("Failed to parse config: invalid variable name '%v'", name)
+		}
+	}
+
+	doc := yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{host.node}}
+	raw, err := decodeConfig(file, &doc, c.Fields)
+	if err != nil {
+		return err
+	}
+	sources := &configSource{file: file, doc: &doc, plugins: pluginSources(file, &doc, c.Fields),
+		chains: chainSources(file, &doc, c.Fields)}
+	if err := c.fromRaw(raw, sources); err != nil {
+		return err
+	}
+	for name, v := range vars {
+		c.ValueMap[name] = v
+	}
+	return nil
+}
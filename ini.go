@@ -0,0 +1,82 @@
+package lv2hostconfig
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadINI reads a flat INI host config, where each key is a
+// parameter reference ("plugin.symbol=value"). Keys may also be
+// grouped in sections, in which case section name is used as
+// plugin name ("[plugin]" followed by "symbol=value"). Lines
+// starting with ';' or '#' are comments. Each plugin is given a
+// name, and its URI is looked up in uris by that name. Plugins are
+// kept in the order they first appear in. Just like ReadFile, this
+// replaces any plugins already in the config, and the values are
+// not evaluated until Evaluate is called.
+func (c *LV2HostConfig) ReadINI(file string, uris map[string]string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read config: %v", err)
+	}
+	defer f.Close()
+
+	// parsing should be atomic, so operate on a copy
+	pcs := make([]LV2PluginConfig, 0)
+	index := make(map[string]int)
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == ';' || text[0] == '#' {
+			continue
+		}
+		if text[0] == '[' {
+			if text[len(text)-1] != ']' {
+				return fmt.Errorf("Failed to parse config: line %v: unterminated section name", line)
+			}
+			section = strings.TrimSpace(text[1 : len(text)-1])
+			continue
+		}
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return fmt.Errorf("Failed to parse config: line %v: expected 'key=value'", line)
+		}
+		key := strings.TrimSpace(text[:eq])
+		value := strings.Trim(strings.TrimSpace(text[eq+1:]), "\"")
+		if section != "" {
+			key = section + "." + key
+		}
+		name, symbol, err := splitParamRef(key)
+		if err != nil {
+			return fmt.Errorf("Failed to parse config: line %v: %v", line, err)
+		}
+
+		i, ok := index[name]
+		if !ok {
+			uri, ok := uris[name]
+			if !ok {
+				return fmt.Errorf("Failed to parse config: line %v: no URI known for plugin '%v'", line, name)
+			}
+			pc := NewLV2PluginConfig()
+			pc.Name = name
+			pc.PluginURI = uri
+			i = len(pcs)
+			index[name] = i
+			pcs = append(pcs, pc)
+		}
+		if _, ok := pcs[i].DataFmt[symbol]; ok {
+			return fmt.Errorf("Failed to parse config: line %v: parameter '%v' is specified more than once", line, key)
+		}
+		pcs[i].DataFmt[symbol] = value
+		pcs[i].ParamOrder = append(pcs[i].ParamOrder, symbol)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Failed to read config: %v", err)
+	}
+
+	c.Plugins = pcs
+	return nil
+}
@@ -0,0 +1,75 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InstanceID returns identifier of plugin at given position in the
+// chain, which can be used to look the plugin up even if there are
+// several instances of the same plugin: plugin name if it has one,
+// its UUID otherwise, or "<URI>#<n>" for the n-th (counting from 1)
+// instance of plugin with that URI, if it has neither.
+func (c *LV2HostConfig) InstanceID(i int) string {
+	pc := &c.Plugins[i]
+	if pc.Name != "" {
+		return pc.Name
+	}
+	if pc.UUID != "" {
+		return pc.UUID
+	}
+	n := 0
+	for j := 0; j <= i; j++ {
+		if c.Plugins[j].PluginURI == pc.PluginURI {
+			n++
+		}
+	}
+	return fmt.Sprintf("%v#%v", pc.PluginURI, n)
+}
+
+// instanceIndex looks up plugin by "<URI>#<n>" instance ID
+func (c *LV2HostConfig) instanceIndex(id string) (int, bool) {
+	sep := strings.LastIndexByte(id, '#')
+	if sep < 0 {
+		return -1, false
+	}
+	n, err := strconv.Atoi(id[sep+1:])
+	if err != nil || n < 1 {
+		return -1, false
+	}
+	uri := id[:sep]
+	for i := range c.Plugins {
+		if c.Plugins[i].PluginURI != uri {
+			continue
+		}
+		if n--; n == 0 {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// FindPlugin looks up plugin by its name, UUID, URI or instance ID,
+// in Plugins first and then in named chains
+func (c *LV2HostConfig) FindPlugin(id string) (*LV2PluginConfig, error) {
+	return c.findPlugin(id)
+}
+
+// PluginIndex returns position in the chain of plugin with given
+// name, UUID, URI or instance ID
+func (c *LV2HostConfig) PluginIndex(id string) (int, error) {
+	return c.findPluginIndex(id)
+}
+
+// PluginsByLabel returns positions in the chain of all plugins
+// with given label
+func (c *LV2HostConfig) PluginsByLabel(label string) []int {
+	res := make([]int, 0)
+	for i := range c.Plugins {
+		if c.Plugins[i].Label == label {
+			res = append(res, i)
+		}
+	}
+	return res
+}
@@ -0,0 +1,27 @@
+package lv2hostconfig
+
+// Instantiate creates an evaluated instance of a template config,
+// such as a config for a specific venue. The config is cloned,
+// vars are added to (or replace existing values in) ValueMap of
+// the clone, and the clone is evaluated. All parameters of the
+// result (including those of named chains) are frozen, so that the instance keeps its values even if
+// scenes are applied or it is re-evaluated with different values.
+// The template itself is never modified.
+func (c *LV2HostConfig) Instantiate(vars map[string]interface{}) (*LV2HostConfig, error) {
+	nc := c.Clone()
+	for k, v := range vars {
+		nc.ValueMap[k] = v
+	}
+	if err := nc.Evaluate(); err != nil {
+		return nil, err
+	}
+	nc.eachPlugin(func(pc *LV2PluginConfig) {
+		if pc.Frozen == nil {
+			pc.Frozen = make(map[string]bool)
+		}
+		for symbol := range pc.Data {
+			pc.Frozen[symbol] = true
+		}
+	})
+	return nc, nil
+}
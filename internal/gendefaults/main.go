@@ -0,0 +1,87 @@
+// Command gendefaults regenerates the factory defaults database of
+// lv2hostconfig from LV2 bundles installed on the system. Entries
+// of plugins whose URIs match any of the prefixes are taken from
+// the bundles and merged into the database, replacing existing
+// entries of the same plugins, while entries of plugins that are
+// not installed are kept, so the database can be grown on
+// machines with different sets of plugins installed:
+//
+//	go run ./internal/gendefaults [-o factory_defaults.json] [-prefix uri,...] [lv2 path...]
+//
+// LV2 paths default to those of lv2world.DefaultPaths.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2world"
+)
+
+// defaultPrefixes are URI prefixes of popular open-source plugin
+// collections: LV2 example plugins, x42, Calf, LSP and ZAM
+var defaultPrefixes = []string{
+	"http://lv2plug.in/plugins/",
+	"http://gareus.org/oss/lv2/",
+	"http://calf.sourceforge.net/plugins/",
+	"http://lsp-plug.in/plugins/lv2/",
+	"urn:zamaudio:",
+}
+
+func main() {
+	out := flag.String("o", "factory_defaults.json", "database file to update")
+	prefixes := flag.String("prefix", strings.Join(defaultPrefixes, ","), "comma-separated URI prefixes of plugins to add, empty for all")
+	flag.Parse()
+	if err := generate(*out, splitList(*prefixes), flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func generate(file string, prefixes, paths []string) error {
+	db := make(map[string]map[string]lv2hostconfig.FactoryPort)
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to read defaults: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &db); err != nil {
+			return fmt.Errorf("Failed to parse defaults: %v", err)
+		}
+	}
+
+	w, err := lv2world.Load(paths...)
+	if err != nil {
+		return err
+	}
+	for _, err := range w.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	installed := w.Defaults(prefixes...)
+	for uri, ports := range installed {
+		db[uri] = ports
+	}
+
+	var buf bytes.Buffer
+	if err := lv2world.WriteDefaults(&buf, db); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Failed to write defaults: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "%v: %v plugins (%v from installed bundles)\n", file, len(db), len(installed))
+	return nil
+}
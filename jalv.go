@@ -0,0 +1,52 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JalvCommand is argument list of a jalv invocation, starting with
+// the command itself
+type JalvCommand []string
+
+// String formats the command as a shell command line, quoting
+// arguments where needed
+func (jc JalvCommand) String() string {
+	args := make([]string, len(jc))
+	for i, arg := range jc {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+			args[i] = arg
+			continue
+		}
+		args[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(args, " ")
+}
+
+// ExportJalv returns jalv invocations running plugins of an
+// evaluated config, one per plugin, with evaluated parameter values
+// passed as controls. Plugins with names get JACK clients of the same
+// name. Plugins of named chains follow those of Plugins. Plugins
+// skipped or bypassed by ResolveMissing are left out.
+// Since every jalv instance is a separate JACK client, connecting
+// them is left to the rig.
+func (c *LV2HostConfig) ExportJalv() ([]JalvCommand, error) {
+	if c.EvaluatedAt.IsZero() {
+		return nil, fmt.Errorf("Config has not been evaluated")
+	}
+	cmds := make([]JalvCommand, 0, len(c.Plugins))
+	c.eachPlugin(func(pc *LV2PluginConfig) {
+		if !pc.instantiated() {
+			return
+		}
+		cmd := JalvCommand{"jalv"}
+		if pc.Name != "" {
+			cmd = append(cmd, "-n", pc.Name)
+		}
+		for _, symbol := range sortedKeys(pc.Data) {
+			cmd = append(cmd, "-c", fmt.Sprintf("%v=%v", symbol, formatFloat(pc.Data[symbol])))
+		}
+		cmds = append(cmds, append(cmd, pc.PluginURI))
+	})
+	return cmds, nil
+}
@@ -0,0 +1,133 @@
+package lv2hostconfig
+
+import (
+	"sync"
+	"time"
+)
+
+// BurstPolicy decides what happens to changes arriving while
+// reloads are being rate limited
+type BurstPolicy int
+
+// Burst policies
+const (
+	// BurstCoalesce applies latest change as soon as allowed
+	BurstCoalesce BurstPolicy = iota
+	// BurstDrop drops changes arriving too soon after last apply
+	BurstDrop
+)
+
+// ReloadPolicy configures rate limiting of config reloads.
+// Debounce is the quiet period to wait for after a change before
+// applying it, so that bursts of writes (editors tend to produce
+// several for a single save) result in a single reload.
+// MinInterval is the minimum time between two applies, for hosts
+// that can only apply changes a few times per second safely.
+type ReloadPolicy struct {
+	Debounce    time.Duration
+	MinInterval time.Duration
+	Burst       BurstPolicy
+}
+
+// ReloadLimiter rate limits calls to apply function according to
+// reload policy. It is safe for concurrent use, and apply is
+// never called concurrently with itself. Intervals between applies
+// are measured with Clock, or system clock if it is nil.
+type ReloadLimiter struct {
+	Clock Clock
+
+	policy ReloadPolicy
+	apply  func()
+
+	mu        sync.Mutex
+	applyMu   sync.Mutex
+	debounce  *time.Timer
+	pending   *time.Timer
+	lastApply time.Time
+	stopped   bool
+}
+
+// NewReloadLimiter creates a new reload limiter
+func NewReloadLimiter(policy ReloadPolicy, apply func()) *ReloadLimiter {
+	return &ReloadLimiter{
+		policy: policy,
+		apply:  apply,
+	}
+}
+
+func (l *ReloadLimiter) now() time.Time {
+	if l.Clock == nil {
+		return time.Now()
+	}
+	return l.Clock.Now()
+}
+
+// Trigger signals that a change has happened. Without Debounce,
+// the change is applied by Trigger itself, if it is not too soon.
+func (l *ReloadLimiter) Trigger() {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return
+	}
+	if l.policy.Debounce <= 0 {
+		l.mu.Unlock()
+		l.fire()
+		return
+	}
+	if l.debounce != nil {
+		l.debounce.Stop()
+	}
+	l.debounce = time.AfterFunc(l.policy.Debounce, l.fire)
+	l.mu.Unlock()
+}
+
+// Stop cancels any pending applies, and waits for apply that is
+// running, if any. Triggers after Stop are ignored.
+func (l *ReloadLimiter) Stop() {
+	l.mu.Lock()
+	l.stopped = true
+	if l.debounce != nil {
+		l.debounce.Stop()
+	}
+	if l.pending != nil {
+		l.pending.Stop()
+	}
+	l.mu.Unlock()
+
+	l.applyMu.Lock()
+	defer l.applyMu.Unlock()
+}
+
+func (l *ReloadLimiter) fire() {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return
+	}
+	since := l.now().Sub(l.lastApply)
+	if l.policy.MinInterval > 0 && since < l.policy.MinInterval {
+		if l.policy.Burst == BurstCoalesce && l.pending == nil {
+			l.pending = time.AfterFunc(l.policy.MinInterval-since, func() {
+				l.mu.Lock()
+				l.pending = nil
+				l.mu.Unlock()
+				l.fire()
+			})
+		}
+		l.mu.Unlock()
+		return
+	}
+	l.lastApply = l.now()
+	l.mu.Unlock()
+
+	l.applyMu.Lock()
+	defer l.applyMu.Unlock()
+	// limiter may have been stopped while waiting for previous apply
+	l.mu.Lock()
+	stopped := l.stopped
+	l.mu.Unlock()
+	if !stopped {
+		l.apply()
+	}
+}
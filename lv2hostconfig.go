@@ -1,15 +1,21 @@
 package lv2hostconfig
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
+	"os"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/Knetic/govaluate"
 
-	yaml "gopkg.in/yaml.v1"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
 )
 
 // LV2 config parsing is done in two
@@ -20,108 +26,377 @@ import (
 // value of 'v' was set to 3).
 // This is the first stage: the raw text form.
 type lv2HostRaw struct {
-	Reference float32        `yaml:"referenceLevel"`
-	Plugins   []lv2PluginRaw `yaml:"plugins"`
+	Environment *LV2Environment `yaml:"environment,omitempty"`
+
+	Reference float32 `yaml:"referenceLevel"`
+
+	Tempo         float64 `yaml:"tempo,omitempty"`
+	TimeSignature string  `yaml:"timeSignature,omitempty"`
+
+	SilenceFloor float32 `yaml:"silenceFloor,omitempty"`
+
+	Plugins []lv2PluginRaw            `yaml:"plugins"`
+	Chains  map[string][]lv2PluginRaw `yaml:"chains,omitempty"`
+
+	Connections []lv2ConnectionRaw `yaml:"connections,omitempty"`
+
+	Scenes     map[string]LV2Scene `yaml:"scenes,omitempty"`
+	Variations []LV2Variation      `yaml:"variations,omitempty"`
+
+	Exports map[string]string `yaml:"exports,omitempty"`
+	Imports map[string]string `yaml:"imports,omitempty"`
+
+	OSC map[string]string `yaml:"osc,omitempty"`
+
+	Extras map[string]interface{} `yaml:",inline"`
 }
 
 // LV2PluginRaw is the raw parsed data from a
 // YAML config file.
 type lv2PluginRaw struct {
-	URI  string            `yaml:"pluginUri"`
-	Data map[string]string `yaml:"parameters"`
+	Name  string            `yaml:"name,omitempty"`
+	UUID  string            `yaml:"uuid,omitempty"`
+	Label string            `yaml:"label,omitempty"`
+	Group string            `yaml:"group,omitempty"`
+	URI   string            `yaml:"pluginUri"`
+	Data  map[string]string `yaml:"parameters"`
+	UI    *LV2PluginUI      `yaml:"ui,omitempty"`
+
+	Notes      string            `yaml:"notes,omitempty"`
+	ParamNotes map[string]string `yaml:"parameterNotes,omitempty"`
+
+	Ranges  map[string]LV2ParamRange `yaml:"ranges,omitempty"`
+	Types   map[string]string        `yaml:"types,omitempty"`
+	CV      map[string]LV2CVPort     `yaml:"cv,omitempty"`
+	Outputs map[string]string        `yaml:"outputs,omitempty"`
+	Frozen  []string                 `yaml:"frozen,omitempty"`
+
+	Properties map[string]string `yaml:"properties,omitempty"`
+
+	Resources []string `yaml:"resources,omitempty"`
+
+	Preset string `yaml:"preset,omitempty"`
+
+	FallbackURI   string `yaml:"fallbackUri,omitempty"`
+	MissingPolicy string `yaml:"missing,omitempty"`
+
+	StartOffset     string `yaml:"startOffset,omitempty"`
+	StartOffsetUnit string `yaml:"startOffsetUnit,omitempty"`
+
+	Extras map[string]interface{} `yaml:",inline"`
+
+	// order is the order parameters are written in
+	order []string
 }
 
-func readConfig(file string) (*lv2HostRaw, error) {
-	var host lv2HostRaw
-	yamlFile, err := ioutil.ReadFile(file)
+func readConfig(r io.Reader, file string, fields FieldMap, format Format) (*lv2HostRaw, *configSource, int64, error) {
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read config: %v", err)
+		return nil, nil, int64(len(data)), fmt.Errorf("Failed to read config: %v", err)
 	}
-	err = yaml.Unmarshal(yamlFile, &host)
+	var raw *lv2HostRaw
+	var sources *configSource
+	if format == FormatTOML {
+		raw, sources, err = parseTOML(data, fields)
+	} else {
+		raw, sources, err = parseConfig(file, data, fields)
+	}
+	return raw, sources, int64(len(data)), err
+}
+
+func parseConfig(file string, yamlFile []byte, fields FieldMap) (*lv2HostRaw, *configSource, error) {
+	var doc yaml.Node
+	// parse into a node tree first, so that we can keep track of
+	// where each value came from
+	err := yaml.Unmarshal(yamlFile, &doc)
+	if err != nil {
+		return nil, nil, parseError(file, err)
+	}
+	host, err := decodeConfig(file, &doc, fields)
 	if err != nil {
+		return nil, nil, err
+	}
+	return host, &configSource{file: file, doc: &doc, plugins: pluginSources(file, &doc, fields),
+		chains: chainSources(file, &doc, fields)}, nil
+}
+
+// decodeConfig decodes parsed document into raw config. Document
+// itself is not modified, so it keeps field names used in the file.
+func decodeConfig(file string, doc *yaml.Node, fields FieldMap) (*lv2HostRaw, error) {
+	var host lv2HostRaw
+	if err := fields.check(); err != nil {
 		return nil, fmt.Errorf("Failed to parse config: %v", err)
 	}
+	renamed := *doc
+	renamed.Content = append([]*yaml.Node(nil), doc.Content...)
+	renameFields(&renamed, fields.reverse(), fields.fileName("plugins"), fields.fileName("chains"))
+	// anchors, aliases and merge keys are resolved by the YAML
+	// decoder, with explicitly specified keys taking precedence
+	// over merged ones
+	if len(renamed.Content) > 0 {
+		if err := renamed.Decode(&host); err != nil {
+			return nil, parseError(file, err)
+		}
+	}
 
 	return &host, nil
 }
 
-func writeConfig(hostRaw *lv2HostRaw, file string) error {
-	d, err := yaml.Marshal(hostRaw)
-	if err != nil {
-		return fmt.Errorf("Failed to serialize config: %v", err)
+// encodeConfig serializes raw config. If it was parsed from a YAML
+// document, comments are copied over from that document, with
+// plugins matched by their sources.
+func encodeConfig(hostRaw *lv2HostRaw, fields FieldMap, format Format, src *configSource, plugins []*pluginSource, chains map[string][]*pluginSource) ([]byte, error) {
+	if err := fields.check(); err != nil {
+		return nil, fmt.Errorf("Failed to serialize config: %v", err)
+	}
+	var top yaml.Node
+	if err := top.Encode(hostRaw); err != nil {
+		return nil, fmt.Errorf("Failed to serialize config: %v", err)
+	}
+	reorderParams(&top, hostRaw)
+	if format == FormatYAML {
+		restoreLiterals(&top, plugins, chains)
+	}
+	doc := yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&top}}
+	renameFields(&doc, fields, "plugins", "chains")
+	transplantComments(&doc, src, plugins, fields.fileName("plugins"))
+
+	var data []byte
+	var err error
+	switch format {
+	case FormatJSON:
+		data, err = encodeJSON(&doc)
+	case FormatTOML:
+		data, err = encodeTOML(&doc)
+	default:
+		data, err = encodeYAML(&doc)
 	}
-	err = ioutil.WriteFile(file, d, 0644)
 	if err != nil {
-		return fmt.Errorf("Failed to write config: %v", err)
+		return nil, fmt.Errorf("Failed to serialize config: %v", err)
 	}
-	return nil
+	return data, nil
 }
 
 // LV2HostConfig is main config structure containing
 // plugin configuration. In addition, it also contains
 // a parameter map (untyped), as well as govaluate
 // expression function map, to enable evaluating arbitrary
-// functions as part of config parsing.
+// functions as part of config parsing. Providers are
+// consulted for additional values on every evaluation,
+// and Clock is the time source used by the config.
 type LV2HostConfig struct {
 	Plugins     []LV2PluginConfig
 	ValueMap    map[string]interface{}
 	FunctionMap map[string]govaluate.ExpressionFunction
+	Providers   []ValueProvider
+	Clock       Clock
+
+	// Chains are named plugin chains, for hosts that run several
+	// chains (e.g. "vocals", "drums" and "master") off a single
+	// config. Plugins is the unnamed chain of flat configs.
+	Chains map[string][]LV2PluginConfig
+
+	// Connections route ports of Plugins to each other and to
+	// host's own ports. If nil, plugins are connected in chain
+	// order.
+	Connections []LV2Connection
+
+	// Constants are named values available to expressions,
+	// such as pi. Variables in ValueMap take precedence.
+	Constants map[string]float64
+
+	// EvaluatedAt is the time of last successful evaluation
+	EvaluatedAt time.Time
+
+	// AutoUUID makes WriteToFile assign UUIDs to plugins
+	// that don't have one yet
+	AutoUUID bool
+
+	// Validators can veto evaluation results, and so
+	// can Schemas of plugins (by URI)
+	Validators []Validator
+	Schemas    map[string]PluginSchema
+
+	// Enums are labels of enumerated parameters, by plugin
+	// URI and parameter symbol
+	Enums map[string]map[string]EnumTable
+
+	// Scenes are named sets of parameter values, and
+	// Scene is the name of last applied scene
+	Scenes map[string]LV2Scene
+	Scene  string
+
+	// Variations are alternative sets of parameter values,
+	// one of which is picked at random by PickVariation
+	Variations []LV2Variation
+
+	// Exports are expressions whose values other configs
+	// may import, and Imports map names of variables this
+	// config expects other configs to export to their types
+	Exports map[string]string
+	Imports map[string]string
+
+	// OSC maps OSC addresses to parameters, as
+	// "<plugin>.<symbol>"
+	OSC map[string]string
+
+	// Extras holds unrecognized top-level fields, which
+	// are preserved when the config is saved
+	Extras map[string]interface{}
+
+	// Environment describes how the config was produced,
+	// and is written along with it. If RecordEnvironment
+	// is set, it is captured anew whenever config is written.
+	Environment       *LV2Environment
+	RecordEnvironment bool
+
+	// Memoize enables caching of expression results, keyed
+	// by values of variables each expression references.
+	// Functions used in expressions must be pure for this
+	// to give correct results.
+	Memoize bool
+
+	// Audit, if set, records function calls made during
+	// evaluation. Memoized results don't call functions,
+	// so they are not recorded.
+	Audit *AuditLog
+
+	// ParamBudget and TotalBudget, if set, limit time it
+	// may take to evaluate a single value and the whole
+	// config respectively
+	ParamBudget time.Duration
+	TotalBudget time.Duration
+
+	// Tempo (in beats per minute) and TimeSignature, if
+	// set, are available to expressions as bpm, beat_ms
+	// and bar_ms variables
+	Tempo         float64
+	TimeSignature string
+
+	// Fields renames config fields when reading and
+	// writing files
+	Fields FieldMap
+
+	// SilenceFloor is the lowest level (in dB) standard
+	// functions return, which is also the level they use
+	// for silence. Zero means the default of -144 dB.
+	SilenceFloor float32
+
+	// Strict makes reading fail on unrecognized fields at
+	// top level and in plugin entries, instead of keeping
+	// them in Extras
+	Strict bool
+
+	// RangePolicy says what to do with evaluated values
+	// outside of known parameter ranges
+	RangePolicy string
+
+	memo    *exprMemo
+	timings []EvalTiming
+	source  *configSource
 }
 
-// LV2PluginConfig is plugin config structure. Use
+// LV2PluginConfig is plugin config structure. Name
+// is an optional name of plugin instance, UUID is an
+// optional persistent identifier of plugin instance,
+// Label is an optional display label (which need not
+// be unique), and Group is set on plugins imported
+// from another config. Use
 // LV2 symbols to map parameters to values. Also
 // contains original formatting for data, in case
 // the config would need to be saved back into
-// file form.
+// file form. Notes and ParamNotes are free-form
+// plugin and parameter notes, not interpreted in
+// any way but preserved when the config is saved.
+// Ranges are optional per-parameter value ranges,
+// and Types are optional parameter types (ParamInt or
+// ParamToggle, with ParamFloat being the default).
+// CV ports are kept separately from control ports,
+// with CV containing evaluated initial values.
+// StartOffset is evaluated from StartOffsetFmt, and
+// is expressed in StartOffsetUnit units. Outputs are
+// values of output ports, which are never evaluated,
+// but are written by the host (e.g. measured values).
 type LV2PluginConfig struct {
-	PluginURI string
-	Data      map[string]float32
-	DataFmt   map[string]string
+	Name       string
+	UUID       string
+	Label      string
+	Group      string
+	PluginURI  string
+	Data       map[string]float32
+	DataFmt    map[string]string
+	UI         *LV2PluginUI
+	Notes      string
+	ParamNotes map[string]string
+	Ranges     map[string]LV2ParamRange
+	Types      map[string]string
+	CV         map[string]float32
+	CVFmt      map[string]LV2CVPort
+	Outputs    map[string]float32
+
+	// Properties are string-valued plugin properties, such
+	// as sample file paths, kept separately from control
+	// ports. Properties are expanded from PropertiesFmt on
+	// evaluation, with ${variable} replaced by its value.
+	Properties    map[string]string
+	PropertiesFmt map[string]string
+
+	StartOffset     float32
+	StartOffsetFmt  string
+	StartOffsetUnit string
+
+	// Frozen parameters are not affected by scenes or
+	// re-evaluation, until they are thawed
+	Frozen map[string]bool
+
+	// Resources are exclusive resources plugin requires,
+	// such as a hardware DSP slot or a MIDI port
+	Resources []string
+
+	// Preset is URI or label of a preset the parameters
+	// are applied on top of, and PresetValues are values
+	// ResolvePresets took from the preset
+	Preset       string
+	PresetValues map[string]string
+
+	// FallbackURI and MissingPolicy say what to do if the
+	// plugin is not installed, and Substitution is set by
+	// ResolveMissing if that was the case
+	FallbackURI   string
+	MissingPolicy string
+	Substitution  *PluginSubstitution
+
+	// Extras holds unrecognized plugin fields, which
+	// are preserved when the config is saved
+	Extras map[string]interface{}
+
+	// ParamOrder is the order parameters are written in.
+	// It is set to the order they were in when config is
+	// read from YAML, and parameters not listed in it are
+	// written after the listed ones, sorted by symbol.
+	ParamOrder []string
+
+	// source links plugin back to the YAML nodes it
+	// was parsed from, if it was read from a file
+	source *pluginSource
+
+	// overrides shadow evaluated values, which are kept
+	// in shadowed until overrides are cleared
+	overrides map[string]float32
+	shadowed  map[string]float32
 }
 
 func newLV2HostRaw() *lv2HostRaw {
 	return &lv2HostRaw{
-		0,
-		make([]lv2PluginRaw, 0),
+		Reference: 0,
+		Plugins:   make([]lv2PluginRaw, 0),
 	}
 }
 
 func newLV2PluginRaw() lv2PluginRaw {
 	return lv2PluginRaw{
-		"",
-		make(map[string]string),
-	}
-}
-
-func dbToLinear(db float32) float32 {
-	return float32(math.Pow(10, float64(db)/20.0))
-}
-
-func linearToDb(linear float32) float32 {
-	if linear != 0 {
-		return 20 * float32(math.Log10(float64(linear)))
-	}
-	return -144
-}
-
-func getFloat(val interface{}) (float32, error) {
-	floatType := reflect.TypeOf(float32(0))
-	stringType := reflect.TypeOf("")
-	v := reflect.ValueOf(val)
-	v = reflect.Indirect(v)
-	if v.Type().ConvertibleTo(floatType) {
-		fv := v.Convert(floatType)
-		return float32(fv.Float()), nil
-	} else if v.Type().ConvertibleTo(stringType) {
-		sv := v.Convert(stringType)
-		s := sv.String()
-		f64, err := strconv.ParseFloat(s, 32)
-		if err != nil {
-			return float32(math.NaN()), err
-		}
-		return float32(f64), nil
-	} else {
-		return float32(math.NaN()), fmt.Errorf("Can't convert %v to float", v.Type())
+		URI:  "",
+		Data: make(map[string]string),
 	}
 }
 
@@ -130,31 +405,22 @@ func setUpLV2HostConfigFuncs(lvc *LV2HostConfig) {
 		if len(args) != 1 {
 			return math.NaN(), fmt.Errorf("Function 'linear' expects exactly 1 argument")
 		}
-		db, err := getFloat(args[0])
+		db, err := convert.Float32(args[0])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
-		return dbToLinear(db), nil
-	}
-	lvc.FunctionMap["decibel"] = func(args ...interface{}) (interface{}, error) {
-		if len(args) != 1 {
-			return math.NaN(), fmt.Errorf("Function 'decibel' expects exactly 1 argument")
-		}
-		linear, err := getFloat(args[0])
-		if err != nil {
-			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
-		}
-		return linearToDb(linear), nil
+		return float64(convert.DBToLinear(db)), nil
 	}
+	lvc.FunctionMap["decibel"] = decibelFunc(convert.MinDB)
 	lvc.FunctionMap["min"] = func(args ...interface{}) (interface{}, error) {
 		if len(args) != 2 {
 			return math.NaN(), fmt.Errorf("Function 'min' expects exactly 2 arguments")
 		}
-		a, err := getFloat(args[0])
+		a, err := convert.Float32(args[0])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
-		b, err := getFloat(args[1])
+		b, err := convert.Float32(args[1])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
@@ -164,21 +430,39 @@ func setUpLV2HostConfigFuncs(lvc *LV2HostConfig) {
 		if len(args) != 2 {
 			return math.NaN(), fmt.Errorf("Function 'max' expects exactly 2 arguments")
 		}
-		a, err := getFloat(args[0])
+		a, err := convert.Float32(args[0])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
-		b, err := getFloat(args[1])
+		b, err := convert.Float32(args[1])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
 		return math.Max(float64(a), float64(b)), nil
 	}
+	lvc.FunctionMap["clamp"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return math.NaN(), fmt.Errorf("Function 'clamp' expects exactly 3 arguments")
+		}
+		var val, min, max float32
+		floatPtrs := []*float32{&val, &min, &max}
+		for i, arg := range args {
+			v, err := convert.Float32(arg)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
+			}
+			*floatPtrs[i] = v
+		}
+		if min > max {
+			return math.NaN(), fmt.Errorf("Range '%v-%v' is invalid", min, max)
+		}
+		return math.Min(math.Max(float64(val), float64(min)), float64(max)), nil
+	}
 	lvc.FunctionMap["abs"] = func(args ...interface{}) (interface{}, error) {
 		if len(args) != 1 {
 			return math.NaN(), fmt.Errorf("Function 'abs' expects exactly 1 argument")
 		}
-		v, err := getFloat(args[0])
+		v, err := convert.Float32(args[0])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
@@ -188,7 +472,7 @@ func setUpLV2HostConfigFuncs(lvc *LV2HostConfig) {
 		if len(args) != 1 {
 			return math.NaN(), fmt.Errorf("Function 'sqrt' expects exactly 1 argument")
 		}
-		v, err := getFloat(args[0])
+		v, err := convert.Float32(args[0])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
@@ -198,16 +482,30 @@ func setUpLV2HostConfigFuncs(lvc *LV2HostConfig) {
 		if len(args) != 2 {
 			return math.NaN(), fmt.Errorf("Function 'pow' expects exactly 2 arguments")
 		}
-		a, err := getFloat(args[0])
+		a, err := convert.Float32(args[0])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
-		b, err := getFloat(args[1])
+		b, err := convert.Float32(args[1])
 		if err != nil {
 			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
 		}
 		return math.Pow(float64(a), float64(b)), nil
 	}
+	lvc.FunctionMap["gain_to_target"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return math.NaN(), fmt.Errorf("Function 'gain_to_target' expects exactly 2 arguments")
+		}
+		level, err := convert.Float32(args[0])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
+		}
+		target, err := convert.Float32(args[1])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[1])
+		}
+		return float64(target) - float64(level), nil
+	}
 	lvc.FunctionMap["scale"] = func(args ...interface{}) (interface{}, error) {
 		if len(args) != 5 {
 			return math.NaN(), fmt.Errorf("Function 'scale' expects exactly 5 arguments")
@@ -217,7 +515,7 @@ func setUpLV2HostConfigFuncs(lvc *LV2HostConfig) {
 		floatPtrs := []*float32{&val, &oldMin, &oldMax, &newMin, &newMax}
 
 		for i, arg := range args {
-			val, err := getFloat(arg)
+			val, err := convert.Float32(arg)
 			if err != nil {
 				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
 			}
@@ -240,15 +538,26 @@ func setUpLV2HostConfigFuncs(lvc *LV2HostConfig) {
 
 		return newVal, nil
 	}
+
+	setUpMIDIFuncs(lvc)
+	setUpDecibelFuncs(lvc)
+	setUpTrigFuncs(lvc)
+	setUpBiquadFuncs(lvc)
+	setUpLoudnessFuncs(lvc)
+	setUpRampFuncs(lvc)
+	setUpCalibrationFuncs(lvc)
 }
 
 // NewLV2HostConfig allocate new host config (usually
 // for purposes of setting up its value map parameters)
 func NewLV2HostConfig() *LV2HostConfig {
 	lvc := LV2HostConfig{
-		make([]LV2PluginConfig, 0),
-		make(map[string]interface{}),
-		make(map[string]govaluate.ExpressionFunction),
+		Plugins:     make([]LV2PluginConfig, 0),
+		ValueMap:    make(map[string]interface{}),
+		FunctionMap: make(map[string]govaluate.ExpressionFunction),
+		Constants:   copyConstants(builtinConstants),
+		Clock:       systemClock{},
+		memo:        newExprMemo(),
 	}
 
 	// set up standard functions
@@ -260,12 +569,73 @@ func NewLV2HostConfig() *LV2HostConfig {
 // NewLV2PluginConfig allocate new plugin config
 func NewLV2PluginConfig() LV2PluginConfig {
 	return LV2PluginConfig{
-		"",
-		make(map[string]float32),
-		make(map[string]string),
+		PluginURI: "",
+		Data:      make(map[string]float32),
+		DataFmt:   make(map[string]string),
+		CV:        make(map[string]float32),
+		Outputs:   make(map[string]float32),
 	}
 }
 
+// copyStringMap copies a map of strings
+func copyStringMap(notes map[string]string) map[string]string {
+	if len(notes) == 0 {
+		return nil
+	}
+	n := make(map[string]string)
+	for k, v := range notes {
+		n[k] = v
+	}
+	return n
+}
+
+// clone makes a deep copy of plugin config, so that
+// the copy can be modified without affecting the original
+func (pc *LV2PluginConfig) clone() LV2PluginConfig {
+	npc := NewLV2PluginConfig()
+	npc.Name = pc.Name
+	npc.UUID = pc.UUID
+	npc.Label = pc.Label
+	npc.Group = pc.Group
+	npc.PluginURI = pc.PluginURI
+	for k, v := range pc.Data {
+		npc.Data[k] = v
+	}
+	for k, v := range pc.DataFmt {
+		npc.DataFmt[k] = v
+	}
+	npc.UI = pc.UI.clone()
+	npc.Notes = pc.Notes
+	npc.ParamNotes = copyStringMap(pc.ParamNotes)
+	npc.Ranges = copyRanges(pc.Ranges)
+	npc.Types = copyStringMap(pc.Types)
+	npc.Properties = copyStringMap(pc.Properties)
+	npc.PropertiesFmt = copyStringMap(pc.PropertiesFmt)
+	for k, v := range pc.CV {
+		npc.CV[k] = v
+	}
+	npc.CVFmt = copyCVPorts(pc.CVFmt)
+	for k, v := range pc.Outputs {
+		npc.Outputs[k] = v
+	}
+	npc.StartOffset = pc.StartOffset
+	npc.StartOffsetFmt = pc.StartOffsetFmt
+	npc.StartOffsetUnit = pc.StartOffsetUnit
+	npc.Frozen = copyFrozen(pc.Frozen)
+	npc.Resources = append([]string(nil), pc.Resources...)
+	npc.Preset = pc.Preset
+	npc.PresetValues = copyStringMap(pc.PresetValues)
+	npc.Extras = copyExtras(pc.Extras)
+	npc.FallbackURI = pc.FallbackURI
+	npc.MissingPolicy = pc.MissingPolicy
+	npc.Substitution = pc.Substitution.clone()
+	npc.ParamOrder = append([]string(nil), pc.ParamOrder...)
+	npc.source = pc.source
+	npc.overrides = copyValues(pc.overrides)
+	npc.shadowed = copyValues(pc.shadowed)
+	return npc
+}
+
 func getFloat32(val interface{}) (float32, error) {
 	t := reflect.TypeOf(float32(0))
 	v := reflect.ValueOf(val)
@@ -279,106 +649,507 @@ func getFloat32(val interface{}) (float32, error) {
 
 // ReadFile will read a YAML config into an LV2HostConfig
 // data structure. Note that any Data fields will not be
-// initialized until Evaluate is called.
+// initialized until Evaluate is called. Since JSON is a
+// subset of YAML, JSON configs can be read as well, and
+// files with .toml extension are read as TOML. Reading is
+// atomic: if it fails, neither plugins nor ValueMap are
+// touched, and errors in the config are returned as
+// *ConfigError with position of the problem, if known.
 func (c *LV2HostConfig) ReadFile(file string) error {
-	raw, err := readConfig(file)
+	f, err := os.Open(file)
 	if err != nil {
-		return err
+		return fmt.Errorf("Failed to read config: %v", err)
 	}
+	defer f.Close()
+	_, err = c.readFrom(f, file, FormatOf(file))
+	return err
+}
 
-	// parsing should be atomic, so operate on a copy
-	pcs := make([]LV2PluginConfig, 0)
+// ReadFrom works like ReadFile, but reads YAML (or JSON)
+// config from r. It returns the number of bytes read.
+func (c *LV2HostConfig) ReadFrom(r io.Reader) (int64, error) {
+	return c.readFrom(r, "", FormatYAML)
+}
 
-	// read raw string values into DataFmt
-	for _, rpd := range raw.Plugins {
-		pc := NewLV2PluginConfig()
+// ReadFromFormat reads config in a given format from r
+func (c *LV2HostConfig) ReadFromFormat(r io.Reader, format Format) error {
+	_, err := c.readFrom(r, "", format)
+	return err
+}
 
-		uri := rpd.URI
+// ReadBytes works like ReadFile, but reads YAML (or JSON)
+// config from a byte slice, such as a database blob
+func (c *LV2HostConfig) ReadBytes(data []byte) error {
+	_, err := c.readFrom(bytes.NewReader(data), "", FormatYAML)
+	return err
+}
 
-		pc.PluginURI = uri
+func (c *LV2HostConfig) readFrom(r io.Reader, file string, format Format) (int64, error) {
+	// strictness is checked by Load
+	rc, n, err := parse(r, ParseOptions{File: file, Format: format, Fields: c.Fields})
+	if err != nil {
+		return n, err
+	}
+	return n, c.Load(rc)
+}
 
-		for param, value := range rpd.Data {
-			pc.DataFmt[param] = value
+// fromRaw replaces contents of the config with parsed data
+func (c *LV2HostConfig) fromRaw(raw *lv2HostRaw, sources *configSource) error {
+	if c.Strict {
+		if err := checkStrict(raw, sources); err != nil {
+			return err
+		}
+	}
+
+	// parsing should be atomic, so operate on a copy
+	pcs := make([]LV2PluginConfig, 0)
+
+	// read raw string values into DataFmt
+	for i, rpd := range raw.Plugins {
+		var src *pluginSource
+		if sources != nil && i < len(sources.plugins) {
+			src = sources.plugins[i]
+		}
+		pc, err := pluginFromRaw(&rpd, src)
+		if err != nil {
+			return err
 		}
 		pcs = append(pcs, pc)
 	}
+	chains, err := chainsFromRaw(raw.Chains, sources)
+	if err != nil {
+		return err
+	}
+	conns, err := connectionsFromRaw(raw.Connections)
+	if err != nil {
+		return err
+	}
+	if conns != nil {
+		routed := &LV2HostConfig{Plugins: pcs, Connections: conns}
+		if _, err := routed.Graph(); err != nil {
+			return fmt.Errorf("Failed to parse config: %v", err)
+		}
+	}
+
+	if err := checkTempo(raw.Tempo, raw.TimeSignature); err != nil {
+		return fmt.Errorf("Failed to parse config: %v", err)
+	}
+	if raw.SilenceFloor > 0 {
+		return fmt.Errorf("Failed to parse config: silence floor %v dB is positive", raw.SilenceFloor)
+	}
 
 	// we're successfully parsed plugin data, so clear current contents
 	// and overwrite them with parsed data
 	c.Plugins = pcs
-	c.ValueMap["reference"] = raw.Reference
+	c.Chains = chains
+	c.Connections = conns
+	c.source = sources
+	c.Tempo = raw.Tempo
+	c.TimeSignature = raw.TimeSignature
+	c.SilenceFloor = raw.SilenceFloor
+	c.Scenes = copyScenes(raw.Scenes)
+	c.Variations = copyVariations(raw.Variations)
+	c.Exports = copyStringMap(raw.Exports)
+	c.Imports = copyStringMap(raw.Imports)
+	c.OSC = copyStringMap(raw.OSC)
+	c.Extras = copyExtras(raw.Extras)
+	c.Environment = raw.Environment.clone()
+	// govaluate only does arithmetic on float64
+	c.ValueMap["reference"] = float64(raw.Reference)
 
 	return nil
 }
 
-// Evaluate uses govaluate to (re-)parse contents of
-// config structure into actual values.
-func (c *LV2HostConfig) Evaluate() error {
-	// parsing should be atomic, so operate on a copy
-	pcs := make([]LV2PluginConfig, 0)
+// pluginFromRaw converts raw plugin entry to plugin config
+func pluginFromRaw(rpd *lv2PluginRaw, src *pluginSource) (LV2PluginConfig, error) {
+	pc := NewLV2PluginConfig()
+	if src != nil {
+		pc.source = src
+		pc.ParamOrder = append([]string(nil), src.order...)
+	}
 
-	// use govaluate to parse our values
-	for _, pd := range c.Plugins {
-		pc := NewLV2PluginConfig()
+	uri := rpd.URI
 
-		uri := pd.PluginURI
+	pc.PluginURI = uri
+	pc.Name = rpd.Name
+	pc.UUID = rpd.UUID
+	pc.Label = rpd.Label
+	pc.Group = rpd.Group
 
-		pc.PluginURI = uri
+	for param, value := range rpd.Data {
+		pc.DataFmt[param] = value
+	}
+	pc.UI = rpd.UI.clone()
+	pc.Notes = rpd.Notes
+	pc.ParamNotes = copyStringMap(rpd.ParamNotes)
+	pc.Ranges = copyRanges(rpd.Ranges)
+	if err := checkTypes(rpd.Types); err != nil {
+		return pc, err
+	}
+	pc.Types = copyStringMap(rpd.Types)
+	pc.PropertiesFmt = copyStringMap(rpd.Properties)
+	pc.CVFmt = copyCVPorts(rpd.CV)
+	for port, value := range rpd.Outputs {
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return pc, pc.valueError(port, value, fmt.Errorf("Failed to parse config: output '%v' value '%v' is not a float", port, value))
+		}
+		pc.Outputs[port] = float32(v)
+	}
+	pc.StartOffsetFmt = rpd.StartOffset
+	pc.StartOffsetUnit = rpd.StartOffsetUnit
+	pc.Frozen = frozenFromList(rpd.Frozen)
+	pc.Resources = append([]string(nil), rpd.Resources...)
+	pc.Preset = rpd.Preset
+	pc.FallbackURI = rpd.FallbackURI
+	pc.MissingPolicy = rpd.MissingPolicy
+	pc.Extras = copyExtras(rpd.Extras)
+	return pc, nil
+}
 
-		for param, value := range pd.DataFmt {
-			// keep current DataFmt to enable future re-parsing
-			pc.DataFmt[param] = value
+// compileExpr parses an expression, using config's functions
+func (c *LV2HostConfig) compileExpr(value string) (*govaluate.EvaluableExpression, error) {
+	expr, err := govaluate.NewEvaluableExpressionWithFunctions(value, c.auditedFunctions(value))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing expression '%v': %v", value, err)
+	}
+	return expr, nil
+}
 
-			// if we can parse value as float, there is no expression
-			result64, err := strconv.ParseFloat(value, 32)
-			if err == nil {
-				pc.Data[param] = float32(result64)
-				continue
-			}
-			// expression failed to parse, so evaluate it
-			expr, err := govaluate.NewEvaluableExpressionWithFunctions(value, c.FunctionMap)
-			if err != nil {
-				return fmt.Errorf("Error parsing expression '%v': %v", value, err)
-			}
-			evalResult, err := expr.Evaluate(c.ValueMap)
-			if err != nil {
-				return fmt.Errorf("Error evaluating expression '%v': %v", value, err)
-			}
+// runExpr evaluates a compiled expression
+func runExpr(expr *govaluate.EvaluableExpression, params govaluate.Parameters) (float32, error) {
+	value := expr.String()
+	evalResult, err := expr.Eval(params)
+	if err != nil {
+		return float32(math.NaN()), fmt.Errorf("Error evaluating expression '%v': %v", value, err)
+	}
 
-			// we've evaluated the expression, however it may not be a float
-			result32, err := getFloat32(evalResult)
-			if err != nil {
-				return fmt.Errorf("Error parsing expression '%v' result: %v", value, err)
+	// we've evaluated the expression, however it may not be a float
+	result32, err := getFloat32(evalResult)
+	if err != nil {
+		return float32(math.NaN()), fmt.Errorf("Error parsing expression '%v' result: %v", value, err)
+	}
+	return result32, nil
+}
+
+// evaluateExpr evaluates a single formatted value
+func (c *LV2HostConfig) evaluateExpr(value string, vars map[string]interface{}) (float32, error) {
+	// if we can parse value as float, there is no expression
+	result64, err := strconv.ParseFloat(value, 32)
+	if err == nil {
+		return float32(result64), nil
+	}
+	if c.Memoize {
+		return c.memoEval(value, vars)
+	}
+	// expression failed to parse, so evaluate it
+	expr, err := c.compileExpr(value)
+	if err != nil {
+		return float32(math.NaN()), err
+	}
+	return runExpr(expr, govaluate.MapParameters(vars))
+}
+
+// evaluatePlugin evaluates a single plugin config, returning
+// a copy of it with evaluated values
+func (c *LV2HostConfig) evaluatePlugin(pd *LV2PluginConfig, vars map[string]interface{}, run *evalRun) (LV2PluginConfig, error) {
+	pc, errs := c.evaluateValues(pd, vars, run, true)
+	if len(errs) != 0 {
+		return pc, errs[0]
+	}
+	return pc, nil
+}
+
+// evaluateValues evaluates values of a plugin config in a stable
+// order. Unless stop is set, evaluation carries on past errors
+// (leaving failed values out), and all errors are returned. It
+// always stops when the total evaluation budget is exceeded.
+func (c *LV2HostConfig) evaluateValues(pd *LV2PluginConfig, vars map[string]interface{}, run *evalRun, stop bool) (LV2PluginConfig, []error) {
+	// keep everything but Data, to enable future re-parsing
+	pc := pd.clone()
+	pc.Data = make(map[string]float32)
+	pc.CV = make(map[string]float32)
+	errs := make([]error, 0)
+	failed := func(err error) bool {
+		errs = append(errs, err)
+		return stop || totalBudgetExceeded(err)
+	}
+
+	c.evaluateInto(&pc, pd, vars, run, failed)
+	pc.applyOverrides()
+	return pc, errs
+}
+
+// evaluateInto evaluates values of pd into pc, calling failed on
+// every error, and stopping if it returns true
+func (c *LV2HostConfig) evaluateInto(pc, pd *LV2PluginConfig, vars map[string]interface{}, run *evalRun, failed func(error) bool) {
+	ports := c.rangePorts(pc)
+	for _, param := range sortedKeys(pc.DataFmt) {
+		result, err := c.paramValue(pc, pd, param, vars, run, ports)
+		if err != nil {
+			if failed(err) {
+				return
+			}
+			continue
+		}
+		pc.Data[param] = result
+	}
+	for _, port := range sortedKeys(pc.CVFmt) {
+		cv := pc.CVFmt[port]
+		result, err := c.evaluateTimed(run, port, cv.Value, vars)
+		if err != nil {
+			if failed(pc.valueError(port, cv.Value, err)) {
+				return
 			}
-			pc.Data[param] = result32
+			continue
 		}
+		pc.CV[port] = result
+	}
+	if err := c.evaluateStartOffset(pc, vars, run); err != nil {
+		if failed(err) {
+			return
+		}
+	}
+	if err := pc.evaluateProperties(vars); err != nil {
+		failed(err)
+	}
+}
 
-		pcs = append(pcs, pc)
+// paramValue evaluates a single parameter of pc, resolving it
+// against range of its port
+func (c *LV2HostConfig) paramValue(pc, pd *LV2PluginConfig, param string, vars map[string]interface{}, run *evalRun, ports map[string]FactoryPort) (float32, error) {
+	value := pc.DataFmt[param]
+	// frozen parameters keep their values
+	if v, ok := pd.evaluated(param); ok && pc.Frozen[param] {
+		return v, nil
+	}
+	if v, ok := c.enumValue(pc, param, value); ok {
+		return v, nil
+	}
+	result, err := c.evaluateTimed(run, param, value, vars)
+	if err != nil {
+		return result, pc.valueError(param, value, err)
+	}
+	if result, err = c.resolveValue(pc, param, result, ports); err != nil {
+		return result, pc.valueError(param, value, err)
+	}
+	return result, nil
+}
+
+// Evaluate uses govaluate to (re-)parse contents of
+// config structure into actual values. Evaluation
+// results are also checked by any registered validators
+// before they are stored in the config. Evaluation is
+// atomic: if it fails, plugins and chains keep their
+// previous values (only Audit, if set, reflects the
+// failed evaluation), and ValueMap is never modified.
+// Errors in plugin values are returned as *ConfigError,
+// with the plugin and the parameter set.
+func (c *LV2HostConfig) Evaluate() error {
+	if c.Audit != nil {
+		c.Audit.Reset()
+	}
+	vars, err := c.evalValues()
+	if err != nil {
+		return err
+	}
+	run := c.newEvalRun()
+
+	// parsing should be atomic, so operate on a copy
+	pcs, err := c.evaluateChain(c.Plugins, vars, run)
+	if err != nil {
+		return err
+	}
+
+	if err := c.validate(pcs); err != nil {
+		return err
+	}
+	chains, err := c.evaluateChains(vars, run)
+	if err != nil {
+		return err
 	}
 
 	// we're successfully parsed plugin data, so clear current contents
 	// and overwrite them with parsed data
 	c.Plugins = pcs
+	c.Chains = chains
+	c.EvaluatedAt = c.now()
+	c.timings = run.timings
 
 	return nil
 }
 
+// EvaluateEach evaluates plugins one at a time, handing each
+// evaluated plugin config off to fn as soon as it's ready, so
+// that the host can start instantiating early plugins while
+// later ones are still being evaluated. Config itself is not
+// modified, and validators are not run, as they need to see
+// the whole config. If fn returns an error, evaluation stops
+// and the error is returned.
+func (c *LV2HostConfig) EvaluateEach(fn func(plugin LV2PluginConfig) error) error {
+	if c.Audit != nil {
+		c.Audit.Reset()
+	}
+	vars, err := c.evalValues()
+	if err != nil {
+		return err
+	}
+	run := c.newEvalRun()
+	for i := range c.Plugins {
+		run.plugin = i
+		pc, err := c.evaluatePlugin(&c.Plugins[i], vars, run)
+		if err != nil {
+			return err
+		}
+		if err := fn(pc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WriteToFile will write LV2HostConfig data back into
-// YAML form. Note that Data contents is not dumped into
+// YAML form (or JSON or TOML, if file has .json or .toml
+// extension respectively). Note that Data contents is not dumped into
 // YAML - DataFmt is dumped instead. Therefore, any changes
 // to Data values will not be reflected in the YAML file
-// unless DataFmt was changed accordingly.
+// unless DataFmt was changed accordingly. If AutoUUID
+// is set, plugins without UUID are assigned one.
 func (c *LV2HostConfig) WriteToFile(file string) error {
-	raw := newLV2HostRaw()
+	return c.writeFile(file, FormatOf(file))
+}
 
-	for _, pcfg := range c.Plugins {
-		rawp := newLV2PluginRaw()
-		rawp.URI = pcfg.PluginURI
-		for k, v := range pcfg.DataFmt {
-			rawp.Data[k] = v
+// WriteTo works like WriteToFile, but writes YAML config
+// to w. It returns the number of bytes written.
+func (c *LV2HostConfig) WriteTo(w io.Writer) (int64, error) {
+	data, err := c.encode(FormatYAML)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	if err != nil {
+		return int64(n), fmt.Errorf("Failed to write config: %v", err)
+	}
+	return int64(n), nil
+}
+
+// WriteToFormat writes config in a given format to w
+func (c *LV2HostConfig) WriteToFormat(w io.Writer, format Format) error {
+	data, err := c.encode(format)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("Failed to write config: %v", err)
+	}
+	return nil
+}
+
+// WriteBytes works like WriteToFile, but returns YAML
+// config as a byte slice
+func (c *LV2HostConfig) WriteBytes() ([]byte, error) {
+	return c.encode(FormatYAML)
+}
+
+func (c *LV2HostConfig) writeFile(file string, format Format) error {
+	data, err := c.encode(format)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("Failed to write config: %v", err)
+	}
+	return nil
+}
+
+// encode serializes config in a given format
+func (c *LV2HostConfig) encode(format Format) ([]byte, error) {
+	if c.AutoUUID {
+		if err := c.AssignUUIDs(); err != nil {
+			return nil, err
+		}
+	}
+	if c.RecordEnvironment {
+		if err := c.CaptureEnvironment(); err != nil {
+			return nil, err
 		}
-		raw.Plugins = append(raw.Plugins, rawp)
 	}
+	plugins := make([]*pluginSource, 0, len(c.Plugins))
+	for i := range c.Plugins {
+		plugins = append(plugins, c.Plugins[i].source)
+	}
+	return encodeConfig(c.toRaw(), c.Fields, format, c.source, plugins, c.chainSourceList())
+}
+
+// toRaw converts config to its raw form
+func (c *LV2HostConfig) toRaw() *lv2HostRaw {
+	raw := newLV2HostRaw()
+	if ref, ok := c.ValueMap["reference"]; ok {
+		if v, err := getFloat32(ref); err == nil {
+			raw.Reference = v
+		}
+	}
+
+	for i := range c.Plugins {
+		raw.Plugins = append(raw.Plugins, c.pluginToRaw(&c.Plugins[i]))
+	}
+	raw.Chains = c.chainsToRaw()
+	raw.Connections = connectionsToRaw(c.Connections)
 
-	return writeConfig(raw, file)
+	raw.Tempo = c.Tempo
+	raw.TimeSignature = c.TimeSignature
+	raw.SilenceFloor = c.SilenceFloor
+	raw.Scenes = copyScenes(c.Scenes)
+	raw.Variations = copyVariations(c.Variations)
+	raw.Exports = copyStringMap(c.Exports)
+	raw.Imports = copyStringMap(c.Imports)
+	raw.OSC = copyStringMap(c.OSC)
+	raw.Extras = copyExtras(c.Extras)
+	raw.Environment = c.Environment.clone()
+
+	return raw
+}
+
+// pluginToRaw converts plugin config to its raw form
+func (c *LV2HostConfig) pluginToRaw(pcfg *LV2PluginConfig) lv2PluginRaw {
+	rawp := newLV2PluginRaw()
+	rawp.URI = pcfg.PluginURI
+	// substitutions are not persisted
+	if pcfg.Substitution != nil {
+		rawp.URI = pcfg.Substitution.OriginalURI
+	}
+	rawp.Name = pcfg.Name
+	rawp.UUID = pcfg.UUID
+	rawp.Label = pcfg.Label
+	rawp.Group = pcfg.Group
+	for k, v := range pcfg.DataFmt {
+		// values of presets are looked up again on reading
+		if pcfg.FromPreset(k) {
+			continue
+		}
+		rawp.Data[k] = c.enumLabel(pcfg, k, v)
+	}
+	rawp.UI = pcfg.UI.clone()
+	rawp.Notes = pcfg.Notes
+	rawp.ParamNotes = copyStringMap(pcfg.ParamNotes)
+	rawp.Ranges = copyRanges(pcfg.Ranges)
+	rawp.Types = copyStringMap(pcfg.Types)
+	rawp.Properties = copyStringMap(pcfg.PropertiesFmt)
+	rawp.CV = copyCVPorts(pcfg.CVFmt)
+	if len(pcfg.Outputs) != 0 {
+		rawp.Outputs = make(map[string]string)
+		for port, v := range pcfg.Outputs {
+			rawp.Outputs[port] = formatFloat(v)
+		}
+	}
+	rawp.StartOffset = pcfg.StartOffsetFmt
+	rawp.StartOffsetUnit = pcfg.StartOffsetUnit
+	rawp.Frozen = frozenToList(pcfg.Frozen)
+	rawp.Resources = append([]string(nil), pcfg.Resources...)
+	rawp.Preset = pcfg.Preset
+	rawp.FallbackURI = pcfg.FallbackURI
+	rawp.MissingPolicy = pcfg.MissingPolicy
+	rawp.Extras = copyExtras(pcfg.Extras)
+	rawp.order = pcfg.ParamOrder
+	return rawp
 }
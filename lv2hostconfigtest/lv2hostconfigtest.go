@@ -0,0 +1,123 @@
+// Package lv2hostconfigtest provides helpers for testing code
+// that uses lv2hostconfig: building configs, asserting evaluation
+// results and comparing serialized configs against golden files.
+package lv2hostconfigtest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+)
+
+// UpdateEnv is the environment variable which, when set to a
+// non-empty value, makes AssertGolden (re-)write golden files
+// instead of comparing against them.
+const UpdateEnv = "LV2HOSTCONFIG_UPDATE_GOLDEN"
+
+// Plugin builds a plugin config with given URI and formatted
+// parameter values.
+func Plugin(uri string, params map[string]string) lv2hostconfig.LV2PluginConfig {
+	pc := lv2hostconfig.NewLV2PluginConfig()
+	pc.PluginURI = uri
+	for k, v := range params {
+		pc.DataFmt[k] = v
+	}
+	return pc
+}
+
+// Config builds a host config out of plugin configs.
+func Config(plugins ...lv2hostconfig.LV2PluginConfig) *lv2hostconfig.LV2HostConfig {
+	c := lv2hostconfig.NewLV2HostConfig()
+	c.Plugins = append(c.Plugins, plugins...)
+	return c
+}
+
+// Parse reads a config from YAML source text, failing the test
+// on error.
+func Parse(t testing.TB, src string) *lv2hostconfig.LV2HostConfig {
+	t.Helper()
+	c := lv2hostconfig.NewLV2HostConfig()
+	if _, err := c.ReadFrom(strings.NewReader(src)); err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	return c
+}
+
+// MustEvaluate evaluates the config, failing the test on error.
+func MustEvaluate(t testing.TB, c *lv2hostconfig.LV2HostConfig) {
+	t.Helper()
+	if err := c.Evaluate(); err != nil {
+		t.Fatalf("Failed to evaluate config: %v", err)
+	}
+}
+
+// AssertValue checks that a parameter of plugin at given index
+// in the chain was evaluated to the expected value, within epsilon.
+func AssertValue(t testing.TB, c *lv2hostconfig.LV2HostConfig, plugin int, symbol string, want, epsilon float32) {
+	t.Helper()
+	if plugin < 0 || plugin >= len(c.Plugins) {
+		t.Errorf("No plugin at index %v", plugin)
+		return
+	}
+	got, ok := c.Plugins[plugin].Data[symbol]
+	if !ok {
+		t.Errorf("Plugin %v has no value for '%v'", plugin, symbol)
+		return
+	}
+	if math.Abs(float64(got)-float64(want)) > float64(epsilon) {
+		t.Errorf("Plugin %v parameter '%v': got %v, want %v", plugin, symbol, got, want)
+	}
+}
+
+// AssertValues is like AssertValue, but for several parameters
+// of the same plugin
+func AssertValues(t testing.TB, c *lv2hostconfig.LV2HostConfig, plugin int, want map[string]float32, epsilon float32) {
+	t.Helper()
+	for symbol, v := range want {
+		AssertValue(t, c, plugin, symbol, v, epsilon)
+	}
+}
+
+// normalize makes serialized output comparable across platforms
+// and editors: line endings are unified, trailing whitespace is
+// removed and output always ends with exactly one newline.
+func normalize(data []byte) []byte {
+	s := strings.Replace(string(data), "\r\n", "\n", -1)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	s = strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+	return []byte(s)
+}
+
+// AssertGolden serializes the config and compares the result
+// against contents of a golden file. If UpdateEnv is set, golden
+// file is written instead.
+func AssertGolden(t testing.TB, c *lv2hostconfig.LV2HostConfig, golden string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	got := normalize(buf.Bytes())
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if want = normalize(want); !bytes.Equal(got, want) {
+		t.Errorf("Config does not match golden file '%v':\n--- got:\n%s\n--- want:\n%s", golden, got, want)
+	}
+}
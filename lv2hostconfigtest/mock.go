@@ -0,0 +1,62 @@
+package lv2hostconfigtest
+
+import (
+	"sync"
+	"time"
+)
+
+// FixedProvider is a value provider always returning the same
+// values. It can be used in place of live providers.
+type FixedProvider map[string]interface{}
+
+// Values implements lv2hostconfig.ValueProvider
+func (p FixedProvider) Values() (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for k, v := range p {
+		values[k] = v
+	}
+	return values, nil
+}
+
+// FailingProvider is a value provider always returning an error
+type FailingProvider struct {
+	Err error
+}
+
+// Values implements lv2hostconfig.ValueProvider
+func (p FailingProvider) Values() (map[string]interface{}, error) {
+	return nil, p.Err
+}
+
+// FakeClock is a clock which only moves when told to. It is
+// safe for concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a fake clock set to given time
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now implements lv2hostconfig.Clock
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+// Set sets current time of the clock
+func (fc *FakeClock) Set(t time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = t
+}
+
+// Advance moves the clock forward by d
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+}
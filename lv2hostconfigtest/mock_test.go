@@ -0,0 +1,89 @@
+package lv2hostconfigtest_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+func TestProviders(t *testing.T) {
+	errOffline := errors.New("offline")
+	tests := []struct {
+		name     string
+		provider lv2hostconfig.ValueProvider
+		gain     float32
+		err      error
+	}{
+		{"fixed", lv2hostconfigtest.FixedProvider{"level": 3.0}, 4, nil},
+		{"fixed negative", lv2hostconfigtest.FixedProvider{"level": -1.0, "unused": "x"}, 0, nil},
+		{"failing", lv2hostconfigtest.FailingProvider{Err: errOffline}, 0, errOffline},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      gain: level + 1\n")
+			c.AddProvider(tt.provider)
+			err := c.Evaluate()
+			if tt.err != nil {
+				// provider errors are reported along with what failed
+				if err == nil || !strings.Contains(err.Error(), tt.err.Error()) {
+					t.Fatalf("Evaluating returned '%v', want '%v'", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to evaluate config: %v", err)
+			}
+			lv2hostconfigtest.AssertValue(t, c, 0, "gain", tt.gain, 1e-6)
+		})
+	}
+}
+
+func TestFixedProviderCopies(t *testing.T) {
+	p := lv2hostconfigtest.FixedProvider{"level": 1.0}
+	values, err := p.Values()
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+	values["level"] = 2.0
+	values["extra"] = 3.0
+	if len(p) != 1 || p["level"] != 1.0 {
+		t.Errorf("Provider was modified through its values: %v", p)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		move func(fc *lv2hostconfigtest.FakeClock)
+		want time.Time
+	}{
+		{"still", func(fc *lv2hostconfigtest.FakeClock) {}, start},
+		{"advance", func(fc *lv2hostconfigtest.FakeClock) { fc.Advance(time.Minute) }, start.Add(time.Minute)},
+		{"advance twice", func(fc *lv2hostconfigtest.FakeClock) {
+			fc.Advance(time.Second)
+			fc.Advance(time.Second)
+		}, start.Add(2 * time.Second)},
+		{"set", func(fc *lv2hostconfigtest.FakeClock) { fc.Set(start.Add(-time.Hour)) }, start.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := lv2hostconfigtest.NewFakeClock(start)
+			tt.move(fc)
+			if got := fc.Now(); !got.Equal(tt.want) {
+				t.Errorf("Clock is at %v, want %v", got, tt.want)
+			}
+			// configs take evaluation time from their clock
+			c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n")
+			c.Clock = fc
+			lv2hostconfigtest.MustEvaluate(t, c)
+			if !c.EvaluatedAt.Equal(tt.want) {
+				t.Errorf("Config was evaluated at %v, want %v", c.EvaluatedAt, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,537 @@
+// Package lv2world loads metadata of locally installed LV2 plugins
+// from their bundles, and checks configs against it: that plugins
+// are installed, that parameters name real control ports, and that
+// values are within ranges the ports declare. Bundles are read
+// directly, so neither lilv nor cgo is required.
+package lv2world
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/turtle"
+)
+
+// LV2 vocabulary
+const (
+	lv2NS  = "http://lv2plug.in/ns/lv2core#"
+	rdfsNS = "http://www.w3.org/2000/01/rdf-schema#"
+	rdfNS  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	doapNS = "http://usefulinc.com/ns/doap#"
+)
+
+// Port is a port of an LV2 plugin. Default, Minimum and Maximum
+// are only meaningful if the corresponding Has field is set.
+// ScalePoints map labels of notable values to the values.
+type Port struct {
+	Index  int
+	Symbol string
+	Name   string
+
+	Input   bool
+	Output  bool
+	Control bool
+	Audio   bool
+	CV      bool
+
+	Default    float32
+	Minimum    float32
+	Maximum    float32
+	HasDefault bool
+	HasMinimum bool
+	HasMaximum bool
+
+	ScalePoints map[string]float32
+}
+
+// Plugin is an installed LV2 plugin. Ports are sorted by index.
+type Plugin struct {
+	URI    string
+	Name   string
+	Bundle string
+	Ports  []Port
+}
+
+// Port returns port with given symbol, if there is one
+func (p *Plugin) Port(symbol string) (*Port, bool) {
+	for i := range p.Ports {
+		if p.Ports[i].Symbol == symbol {
+			return &p.Ports[i], true
+		}
+	}
+	return nil, false
+}
+
+// World is a set of installed LV2 plugins. Presets are installed
+// presets, by URI of the plugin they apply to. Errors holds errors
+// from bundles that could not be loaded, which are skipped.
+type World struct {
+	Plugins map[string]*Plugin
+	Presets map[string][]Preset
+	Errors  []error
+}
+
+// DefaultPaths returns directories LV2 bundles are searched in:
+// those listed in LV2_PATH if it is set, or the usual system and
+// user locations otherwise
+func DefaultPaths() []string {
+	if p := os.Getenv("LV2_PATH"); p != "" {
+		return filepath.SplitList(p)
+	}
+	paths := make([]string, 0)
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".lv2"))
+	}
+	return append(paths, "/usr/local/lib/lv2", "/usr/lib/lv2")
+}
+
+// Load loads all bundles found in given directories, or in
+// DefaultPaths if none are given. Directories that don't exist are
+// skipped. If a plugin is found in more than one directory, the
+// first one wins.
+func Load(paths ...string) (*World, error) {
+	if len(paths) == 0 {
+		paths = DefaultPaths()
+	}
+	g := newGraph()
+	w := &World{Plugins: make(map[string]*Plugin), Presets: make(map[string][]Preset)}
+	bundles := make(map[string]string)
+	for _, dir := range paths {
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read LV2 path: %v", err)
+		}
+		for _, e := range entries {
+			bundle := filepath.Join(dir, e.Name())
+			if !e.IsDir() {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(bundle, "manifest.ttl")); err != nil {
+				continue
+			}
+			plugins, err := g.loadBundle(bundle)
+			if err != nil {
+				w.Errors = append(w.Errors, err)
+				continue
+			}
+			for _, uri := range plugins {
+				if _, ok := bundles[uri]; !ok {
+					bundles[uri] = bundle
+				}
+			}
+		}
+	}
+	for uri, bundle := range bundles {
+		w.Plugins[uri] = g.plugin(uri, bundle)
+	}
+	w.loadPresets(g)
+	return w, nil
+}
+
+// Plugin returns plugin with given URI, if it is installed
+func (w *World) Plugin(uri string) (*Plugin, bool) {
+	p, ok := w.Plugins[uri]
+	return p, ok
+}
+
+// HasPlugin implements lv2hostconfig.PluginCatalog
+func (w *World) HasPlugin(uri string) bool {
+	_, ok := w.Plugins[uri]
+	return ok
+}
+
+// Schema returns schema of an installed plugin describing ranges
+// and defaults of its control input ports, to be used with
+// RegisterSchema
+func (w *World) Schema(uri string) (lv2hostconfig.PluginSchema, bool) {
+	p, ok := w.Plugins[uri]
+	if !ok {
+		return nil, false
+	}
+	schema := make(lv2hostconfig.PluginSchema)
+	for _, port := range p.Ports {
+		if !port.Control || !port.Input {
+			continue
+		}
+		ps := lv2hostconfig.ParamSchema{Default: port.Default}
+		if port.HasMinimum && port.HasMaximum {
+			ps.Min, ps.Max = port.Minimum, port.Maximum
+		}
+		schema[port.Symbol] = ps
+	}
+	return schema, true
+}
+
+// Defaults returns factory defaults database entries for control
+// input ports of installed plugins whose URIs start with any of
+// prefixes, or of all installed plugins if no prefixes are given.
+// Ports that don't declare a default are skipped, and so are
+// plugins that have no such ports.
+func (w *World) Defaults(prefixes ...string) map[string]map[string]lv2hostconfig.FactoryPort {
+	db := make(map[string]map[string]lv2hostconfig.FactoryPort)
+	for uri, p := range w.Plugins {
+		if !hasPrefix(uri, prefixes) {
+			continue
+		}
+		ports := make(map[string]lv2hostconfig.FactoryPort)
+		for _, port := range p.Ports {
+			if !port.Control || !port.Input || !port.HasDefault {
+				continue
+			}
+			ports[port.Symbol] = lv2hostconfig.FactoryPort{Default: port.Default, Min: port.Minimum, Max: port.Maximum}
+		}
+		if len(ports) != 0 {
+			db[uri] = ports
+		}
+	}
+	return db
+}
+
+// hasPrefix returns whether s starts with any of prefixes, which
+// is always the case if there are none
+func hasPrefix(s string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterDefaults adds control input ports of all installed
+// plugins to the factory defaults database, so that they can be
+// used by FillDefaults and DefaultsFor. Ports that don't declare a
+// default are skipped.
+func (w *World) RegisterDefaults() {
+	for uri, ports := range w.Defaults() {
+		lv2hostconfig.RegisterDefaults(uri, ports)
+	}
+}
+
+// WriteDefaults writes a factory defaults database in the format
+// LoadDefaults reads and the embedded database is kept in, with
+// plugins and ports sorted, so that the database can be generated
+// from installed bundles and diffs of it stay readable
+func WriteDefaults(out io.Writer, db map[string]map[string]lv2hostconfig.FactoryPort) error {
+	format := func(v float32) string {
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	}
+	uris := make([]string, 0, len(db))
+	for uri := range db {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	var b strings.Builder
+	b.WriteString("{\n")
+	for i, uri := range uris {
+		symbols := make([]string, 0, len(db[uri]))
+		for symbol := range db[uri] {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+		fmt.Fprintf(&b, "  %v: {\n", quote(uri))
+		for j, symbol := range symbols {
+			port := db[uri][symbol]
+			fmt.Fprintf(&b, "    %v: {\"default\": %v, \"min\": %v, \"max\": %v}", quote(symbol),
+				format(port.Default), format(port.Min), format(port.Max))
+			b.WriteString(separator(j, len(symbols)))
+		}
+		b.WriteString("  }" + separator(i, len(uris)))
+	}
+	b.WriteString("}\n")
+	if _, err := io.WriteString(out, b.String()); err != nil {
+		return fmt.Errorf("Failed to write defaults: %v", err)
+	}
+	return nil
+}
+
+// quote returns s as a JSON string
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// separator returns what follows i-th of n entries of a JSON object
+func separator(i, n int) string {
+	if i < n-1 {
+		return ",\n"
+	}
+	return "\n"
+}
+
+// RegisterEnums registers scale points of control input ports
+// of all installed plugins as enum labels with the config, so that
+// parameters can be set by label
+func (w *World) RegisterEnums(c *lv2hostconfig.LV2HostConfig) {
+	for uri, p := range w.Plugins {
+		for _, port := range p.Ports {
+			if port.Control && port.Input && len(port.ScalePoints) != 0 {
+				c.RegisterEnum(uri, port.Symbol, port.ScalePoints)
+			}
+		}
+	}
+}
+
+// pluginID returns identifier plugin is reported by
+func pluginID(pc *lv2hostconfig.LV2PluginConfig) string {
+	if pc.Name != "" {
+		return pc.Name
+	}
+	if pc.UUID != "" {
+		return pc.UUID
+	}
+	return pc.PluginURI
+}
+
+// Check checks plugins of a config against installed plugins. All
+// parameters are checked to be control input ports of their
+// plugin, and evaluated values are checked against ranges of the
+// ports, so for range checks to be meaningful the config must be
+// evaluated first.
+func (w *World) Check(c *lv2hostconfig.LV2HostConfig) []lv2hostconfig.ValidationMessage {
+	msgs := make([]lv2hostconfig.ValidationMessage, 0)
+	for i := range c.Plugins {
+		pc := &c.Plugins[i]
+		id := pluginID(pc)
+		p, ok := w.Plugins[pc.PluginURI]
+		if !ok {
+			msgs = append(msgs, lv2hostconfig.ValidationMessage{
+				Plugin:  id,
+				Message: fmt.Sprintf("plugin '%v' is not installed", pc.PluginURI),
+			})
+			continue
+		}
+		for _, symbol := range paramSymbols(pc) {
+			port, ok := p.Port(symbol)
+			if !ok {
+				msgs = append(msgs, lv2hostconfig.ValidationMessage{Plugin: id, Symbol: symbol, Message: "plugin has no such port"})
+				continue
+			}
+			if !port.Control || !port.Input {
+				msgs = append(msgs, lv2hostconfig.ValidationMessage{Plugin: id, Symbol: symbol, Message: "port is not a control input port"})
+				continue
+			}
+			v, ok := pc.Data[symbol]
+			if !ok {
+				continue
+			}
+			if (port.HasMinimum && v < port.Minimum) || (port.HasMaximum && v > port.Maximum) {
+				msgs = append(msgs, lv2hostconfig.ValidationMessage{
+					Plugin:  id,
+					Symbol:  symbol,
+					Message: fmt.Sprintf("value %v is out of port range %v", v, portRange(port)),
+				})
+			}
+		}
+	}
+	return msgs
+}
+
+// Validator returns a validator vetoing configs that don't match
+// installed plugins, to be used with AddValidator
+func (w *World) Validator() lv2hostconfig.Validator {
+	return lv2hostconfig.ValidatorFunc(func(c *lv2hostconfig.LV2HostConfig) ([]lv2hostconfig.ValidationMessage, error) {
+		return w.Check(c), nil
+	})
+}
+
+// paramSymbols returns symbols of all parameters of a plugin, sorted
+func paramSymbols(pc *lv2hostconfig.LV2PluginConfig) []string {
+	set := make(map[string]bool)
+	for k := range pc.DataFmt {
+		set[k] = true
+	}
+	for k := range pc.Data {
+		set[k] = true
+	}
+	symbols := make([]string, 0, len(set))
+	for k := range set {
+		symbols = append(symbols, k)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func portRange(port *Port) string {
+	min, max := "-inf", "inf"
+	if port.HasMinimum {
+		min = fmt.Sprint(port.Minimum)
+	}
+	if port.HasMaximum {
+		max = fmt.Sprint(port.Maximum)
+	}
+	return fmt.Sprintf("[%v, %v]", min, max)
+}
+
+// graph is a set of triples, indexed by subject
+type graph struct {
+	subjects map[turtle.Term]map[string][]turtle.Term
+	loaded   map[string]bool
+}
+
+func newGraph() *graph {
+	return &graph{
+		subjects: make(map[turtle.Term]map[string][]turtle.Term),
+		loaded:   make(map[string]bool),
+	}
+}
+
+func (g *graph) objects(s turtle.Term, predicate string) []turtle.Term {
+	return g.subjects[s][predicate]
+}
+
+func (g *graph) object(s turtle.Term, predicate string) (turtle.Term, bool) {
+	objs := g.objects(s, predicate)
+	if len(objs) == 0 {
+		return turtle.Term{}, false
+	}
+	return objs[0], true
+}
+
+func (g *graph) has(s turtle.Term, predicate, object string) bool {
+	for _, o := range g.objects(s, predicate) {
+		if o.Kind == turtle.IRI && o.Value == object {
+			return true
+		}
+	}
+	return false
+}
+
+// loadFile parses a Turtle file into the graph. Blank nodes are
+// prefixed with file URL, so that they don't clash between files.
+func (g *graph) loadFile(path string) ([]turtle.Triple, error) {
+	base := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	if g.loaded[base] {
+		return nil, nil
+	}
+	g.loaded[base] = true
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read LV2 bundle: %v", err)
+	}
+	defer f.Close()
+	triples, err := turtle.Parse(f, base)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse '%v': %v", path, err)
+	}
+	for i := range triples {
+		t := &triples[i]
+		if t.Subject.Kind == turtle.Blank {
+			t.Subject.Value = base + "#" + t.Subject.Value
+		}
+		if t.Object.Kind == turtle.Blank {
+			t.Object.Value = base + "#" + t.Object.Value
+		}
+		preds, ok := g.subjects[t.Subject]
+		if !ok {
+			preds = make(map[string][]turtle.Term)
+			g.subjects[t.Subject] = preds
+		}
+		preds[t.Predicate.Value] = append(preds[t.Predicate.Value], t.Object)
+	}
+	return triples, nil
+}
+
+// loadBundle loads manifest of a bundle and all files it refers
+// to, returning URIs of plugins the manifest lists
+func (g *graph) loadBundle(bundle string) ([]string, error) {
+	triples, err := g.loadFile(filepath.Join(bundle, "manifest.ttl"))
+	if err != nil {
+		return nil, err
+	}
+	plugins := make([]string, 0)
+	for _, t := range triples {
+		switch {
+		case t.Predicate.Value == turtle.RDFType && t.Object.Value == lv2NS+"Plugin":
+			plugins = append(plugins, t.Subject.Value)
+		case t.Predicate.Value == rdfsNS+"seeAlso" && t.Object.Kind == turtle.IRI:
+			u, err := url.Parse(t.Object.Value)
+			if err != nil || u.Scheme != "file" {
+				continue
+			}
+			if _, err := g.loadFile(filepath.FromSlash(u.Path)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return plugins, nil
+}
+
+// plugin builds description of a plugin from the graph
+func (g *graph) plugin(uri, bundle string) *Plugin {
+	s := turtle.Term{Kind: turtle.IRI, Value: uri}
+	p := &Plugin{URI: uri, Bundle: bundle, Ports: make([]Port, 0)}
+	if name, ok := g.object(s, doapNS+"name"); ok {
+		p.Name = name.Value
+	}
+	for _, ps := range g.objects(s, lv2NS+"port") {
+		port := Port{
+			Input:   g.has(ps, turtle.RDFType, lv2NS+"InputPort"),
+			Output:  g.has(ps, turtle.RDFType, lv2NS+"OutputPort"),
+			Control: g.has(ps, turtle.RDFType, lv2NS+"ControlPort"),
+			Audio:   g.has(ps, turtle.RDFType, lv2NS+"AudioPort"),
+			CV:      g.has(ps, turtle.RDFType, lv2NS+"CVPort"),
+		}
+		if t, ok := g.object(ps, lv2NS+"index"); ok {
+			v, _ := t.Float()
+			port.Index = int(v)
+		}
+		if t, ok := g.object(ps, lv2NS+"symbol"); ok {
+			port.Symbol = t.Value
+		}
+		if t, ok := g.object(ps, lv2NS+"name"); ok {
+			port.Name = t.Value
+		}
+		port.Default, port.HasDefault = g.float(ps, lv2NS+"default")
+		port.Minimum, port.HasMinimum = g.float(ps, lv2NS+"minimum")
+		port.Maximum, port.HasMaximum = g.float(ps, lv2NS+"maximum")
+		for _, sp := range g.objects(ps, lv2NS+"scalePoint") {
+			label, ok := g.object(sp, rdfsNS+"label")
+			if !ok {
+				continue
+			}
+			if v, ok := g.float(sp, rdfNS+"value"); ok {
+				if port.ScalePoints == nil {
+					port.ScalePoints = make(map[string]float32)
+				}
+				port.ScalePoints[label.Value] = v
+			}
+		}
+		p.Ports = append(p.Ports, port)
+	}
+	sort.Slice(p.Ports, func(i, j int) bool {
+		return p.Ports[i].Index < p.Ports[j].Index
+	})
+	return p
+}
+
+func (g *graph) float(s turtle.Term, predicate string) (float32, bool) {
+	t, ok := g.object(s, predicate)
+	if !ok {
+		return 0, false
+	}
+	v, ok := t.Float()
+	if !ok && t.Kind == turtle.Literal && strings.TrimSpace(t.Value) != "" {
+		// some bundles write numbers as plain strings
+		var f float64
+		if _, err := fmt.Sscan(t.Value, &f); err == nil {
+			return float32(f), true
+		}
+	}
+	return float32(v), ok
+}
@@ -0,0 +1,295 @@
+package lv2world
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/turtle"
+)
+
+// LV2 presets vocabulary
+const psetNS = "http://lv2plug.in/ns/ext/presets#"
+
+// LoadPreset loads an LV2 preset, either from a preset file or from
+// a preset bundle (a directory with manifest.ttl), which must have
+// exactly one preset. Preset is returned as a plugin config, with
+// port values of the preset as parameters and label of the preset
+// as plugin label. Plugin state other than port values is not
+// supported.
+func LoadPreset(path string) (lv2hostconfig.LV2PluginConfig, error) {
+	g := newGraph()
+	fi, err := os.Stat(path)
+	if err != nil {
+		return lv2hostconfig.LV2PluginConfig{}, fmt.Errorf("Failed to read LV2 preset: %v", err)
+	}
+	if fi.IsDir() {
+		_, err = g.loadBundle(path)
+	} else {
+		_, err = g.loadFile(path)
+	}
+	if err != nil {
+		return lv2hostconfig.LV2PluginConfig{}, err
+	}
+	presets := make([]turtle.Term, 0)
+	for s := range g.subjects {
+		if g.has(s, turtle.RDFType, psetNS+"Preset") {
+			presets = append(presets, s)
+		}
+	}
+	if len(presets) != 1 {
+		return lv2hostconfig.LV2PluginConfig{}, fmt.Errorf("Expected one LV2 preset in '%v', found %v", path, len(presets))
+	}
+	return g.preset(presets[0])
+}
+
+// preset builds plugin config out of a preset in the graph
+func (g *graph) preset(s turtle.Term) (lv2hostconfig.LV2PluginConfig, error) {
+	pc := lv2hostconfig.NewLV2PluginConfig()
+	uri, ok := g.object(s, lv2NS+"appliesTo")
+	if !ok || uri.Kind != turtle.IRI {
+		return pc, fmt.Errorf("LV2 preset %v doesn't say which plugin it applies to", s)
+	}
+	pc.PluginURI = uri.Value
+	if label, ok := g.object(s, rdfsNS+"label"); ok {
+		pc.Label = label.Value
+	}
+	for _, ps := range g.objects(s, lv2NS+"port") {
+		symbol, ok := g.object(ps, lv2NS+"symbol")
+		if !ok {
+			return pc, fmt.Errorf("LV2 preset %v has a port without symbol", s)
+		}
+		v, ok := g.float(ps, psetNS+"value")
+		if !ok {
+			return pc, fmt.Errorf("LV2 preset %v has no value for port '%v'", s, symbol.Value)
+		}
+		pc.DataFmt[symbol.Value] = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return pc, nil
+}
+
+// Preset is an installed LV2 preset of a plugin, with port values
+// of the preset by symbol
+type Preset struct {
+	URI    string
+	Label  string
+	Plugin string
+	Values map[string]string
+}
+
+// loadPresets collects presets from loaded bundles
+func (w *World) loadPresets(g *graph) {
+	subjects := make([]turtle.Term, 0)
+	for s := range g.subjects {
+		if s.Kind == turtle.IRI && g.has(s, turtle.RDFType, psetNS+"Preset") {
+			subjects = append(subjects, s)
+		}
+	}
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].Value < subjects[j].Value })
+	for _, s := range subjects {
+		pc, err := g.preset(s)
+		if err != nil {
+			w.Errors = append(w.Errors, err)
+			continue
+		}
+		w.Presets[pc.PluginURI] = append(w.Presets[pc.PluginURI], Preset{
+			URI:    s.Value,
+			Label:  pc.Label,
+			Plugin: pc.PluginURI,
+			Values: pc.DataFmt,
+		})
+	}
+}
+
+// ResolvePreset implements lv2hostconfig.PresetResolver, looking up
+// an installed preset of the plugin by URI, or by label if no preset
+// has that URI
+func (w *World) ResolvePreset(pluginURI, preset string) (map[string]string, error) {
+	var found *Preset
+	for i, p := range w.Presets[pluginURI] {
+		if p.URI == preset {
+			return copyValues(p.Values), nil
+		}
+		if p.Label != preset {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("More than one LV2 preset of plugin '%v' is labeled '%v'", pluginURI, preset)
+		}
+		found = &w.Presets[pluginURI][i]
+	}
+	if found == nil {
+		return nil, fmt.Errorf("LV2 preset '%v' of plugin '%v' is not installed", preset, pluginURI)
+	}
+	return copyValues(found.Values), nil
+}
+
+// ResolvePresetFile is a preset resolver (see
+// lv2hostconfig.PresetResolverFunc) that takes presets to be paths
+// of preset files or bundles, loading them with LoadPreset
+func ResolvePresetFile(pluginURI, path string) (map[string]string, error) {
+	pc, err := LoadPreset(path)
+	if err != nil {
+		return nil, err
+	}
+	if pc.PluginURI != pluginURI {
+		return nil, fmt.Errorf("LV2 preset '%v' applies to plugin '%v'", path, pc.PluginURI)
+	}
+	return pc.DataFmt, nil
+}
+
+func copyValues(values map[string]string) map[string]string {
+	res := make(map[string]string, len(values))
+	for k, v := range values {
+		res[k] = v
+	}
+	return res
+}
+
+// presetFileName derives preset file name from its label
+func presetFileName(label string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, label)
+	if name == "" {
+		name = "preset"
+	}
+	return name + ".ttl"
+}
+
+// presetManifest returns contents of manifest.ttl of a bundle with
+// preset file listed in it, or nil if the manifest already lists it.
+// Existing manifest is kept as is, with an entry for the preset
+// appended, so that other presets of the bundle stay registered.
+func presetManifest(bundle, file string, plugin turtle.Term, prefixes string) ([]byte, error) {
+	path := filepath.Join(bundle, "manifest.ttl")
+	entry := fmt.Sprintf("<%v>\n\ta pset:Preset ;\n\tlv2:appliesTo %v ;\n\trdfs:seeAlso <%v> .\n", file, plugin, file)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []byte(prefixes + entry), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read LV2 preset bundle: %v", err)
+	}
+
+	base := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(bundle, file))}).String()
+	// presets listed in the manifest, by the plugin they apply to
+	listed := func(data []byte) (map[string]bool, error) {
+		triples, err := turtle.Parse(bytes.NewReader(data), base)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse '%v': %v", path, err)
+		}
+		plugins := make(map[string]bool)
+		for _, t := range triples {
+			if t.Subject.Kind == turtle.IRI && t.Subject.Value == uri && t.Predicate.Value == lv2NS+"appliesTo" {
+				plugins[t.Object.Value] = true
+			}
+		}
+		return plugins, nil
+	}
+	plugins, err := listed(data)
+	if err != nil {
+		return nil, err
+	}
+	if plugins[plugin.Value] {
+		return nil, nil
+	}
+	if len(plugins) != 0 {
+		return nil, fmt.Errorf("LV2 preset bundle '%v' already has preset '%v' of another plugin", bundle, file)
+	}
+
+	merged := append([]byte(nil), data...)
+	if len(merged) != 0 && merged[len(merged)-1] != '\n' {
+		merged = append(merged, '\n')
+	}
+	merged = append(merged, '\n')
+	for _, line := range strings.SplitAfter(prefixes, "\n") {
+		if strings.HasPrefix(line, "@prefix") && !bytes.Contains(data, []byte(line)) {
+			merged = append(merged, line...)
+		}
+	}
+	merged = append(merged, entry...)
+	// existing manifest may use other prefixes or change base, so
+	// make sure the entry names the preset file
+	if plugins, err := listed(merged); err != nil || !plugins[plugin.Value] {
+		return nil, fmt.Errorf("Failed to add preset '%v' to manifest of LV2 preset bundle '%v'", file, bundle)
+	}
+	return merged, nil
+}
+
+// SavePreset saves evaluated values of a plugin config as an LV2
+// preset bundle, which any LV2 host can load: directory bundle is
+// created (if needed) with manifest.ttl and the preset file in it.
+// If the bundle already exists, the preset is added to its manifest,
+// so a bundle can hold any number of presets, and saving a preset
+// with the same label again replaces it. Preset is labeled with
+// label, or label (or name) of the plugin if label is empty.
+func SavePreset(bundle string, pc *lv2hostconfig.LV2PluginConfig, label string) error {
+	if label == "" {
+		label = pc.Label
+	}
+	if label == "" {
+		label = pc.Name
+	}
+	file := presetFileName(label)
+	plugin := turtle.Term{Kind: turtle.IRI, Value: pc.PluginURI}
+	prefixes := "@prefix lv2: <" + lv2NS + "> .\n" +
+		"@prefix pset: <" + psetNS + "> .\n" +
+		"@prefix rdfs: <" + rdfsNS + "> .\n\n"
+
+	var preset bytes.Buffer
+	preset.WriteString(prefixes)
+	fmt.Fprintf(&preset, "<>\n\ta pset:Preset ;\n\tlv2:appliesTo %v", plugin)
+	if label != "" {
+		fmt.Fprintf(&preset, " ;\n\trdfs:label %v", turtle.Term{Kind: turtle.Literal, Value: label})
+	}
+	symbols := make([]string, 0, len(pc.Data))
+	for symbol := range pc.Data {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for i, symbol := range symbols {
+		v := float64(pc.Data[symbol])
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("Value of parameter '%v' can't be saved in LV2 preset", symbol)
+		}
+		sep := " ,"
+		if i == 0 {
+			sep = " ;\n\tlv2:port"
+		}
+		fmt.Fprintf(&preset, "%v [\n\t\tlv2:symbol %v ;\n\t\tpset:value %v\n\t]", sep,
+			turtle.Term{Kind: turtle.Literal, Value: symbol}, strconv.FormatFloat(v, 'f', -1, 32))
+	}
+	preset.WriteString(" .\n")
+
+	if err := os.MkdirAll(bundle, 0755); err != nil {
+		return fmt.Errorf("Failed to create LV2 preset bundle: %v", err)
+	}
+	manifest, err := presetManifest(bundle, file, plugin, prefixes)
+	if err != nil {
+		return err
+	}
+	// write the preset first, so that the manifest never lists a
+	// missing file
+	if err := ioutil.WriteFile(filepath.Join(bundle, file), preset.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Failed to write LV2 preset bundle: %v", err)
+	}
+	if manifest != nil {
+		if err := ioutil.WriteFile(filepath.Join(bundle, "manifest.ttl"), manifest, 0644); err != nil {
+			return fmt.Errorf("Failed to write LV2 preset bundle: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Knetic/govaluate"
+)
+
+// maximum number of cached results per expression
+const memoMaxResults = 64
+
+type memoEntry struct {
+	expr    *govaluate.EvaluableExpression
+	vars    []string
+	results map[string]float32
+}
+
+// memoScope is what compiled expressions depend on apart from their
+// text: functions (which compiled expressions capture, along with
+// silence floor and audit log they report to)
+type memoScope struct {
+	funcs  uintptr
+	nfuncs int
+	floor  float32
+	audit  *AuditLog
+}
+
+func (c *LV2HostConfig) memoScope() memoScope {
+	return memoScope{reflect.ValueOf(c.FunctionMap).Pointer(), len(c.FunctionMap), c.SilenceFloor, c.Audit}
+}
+
+// exprMemo is shared by evaluations of the config, some of which
+// may still be running in the background after their budget ran
+// out, so it is set up when the config is created rather than on
+// first use, and only ever accessed under mu. Entries are only
+// valid for the scope they were compiled in.
+type exprMemo struct {
+	mu      sync.Mutex
+	scope   memoScope
+	entries map[string]*memoEntry
+}
+
+func newExprMemo() *exprMemo {
+	return &exprMemo{entries: make(map[string]*memoEntry)}
+}
+
+// memoKey builds cache key out of values of variables expression
+// references. If any variable is missing, there is no key, as the
+// expression would fail to evaluate anyway.
+func memoKey(vars []string, values map[string]interface{}) (string, bool) {
+	parts := make([]string, 0, len(vars))
+	for _, name := range vars {
+		v, ok := values[name]
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprintf("%T:%v", v, v))
+	}
+	return strings.Join(parts, "\x00"), true
+}
+
+// memoEval evaluates an expression, unless it was already evaluated
+// with the same values of the variables it references. Configs that
+// were not created by NewLV2HostConfig and friends have no memo, and
+// so evaluate expressions every time.
+func (c *LV2HostConfig) memoEval(value string, vars map[string]interface{}) (float32, error) {
+	m := c.memo
+	if m == nil {
+		expr, err := c.compileExpr(value)
+		if err != nil {
+			return float32(math.NaN()), err
+		}
+		return runExpr(expr, govaluate.MapParameters(vars))
+	}
+	e, key, ok, err := m.lookup(c, value, vars)
+	if err != nil {
+		return float32(math.NaN()), err
+	}
+	if ok {
+		m.mu.Lock()
+		result, found := e.results[key]
+		m.mu.Unlock()
+		if found {
+			return result, nil
+		}
+	}
+	// expression may take long (or never finish, if it ran over
+	// its budget), so it is run without holding the memo
+	result, err := runExpr(e.expr, govaluate.MapParameters(vars))
+	if err != nil {
+		return result, err
+	}
+	if ok {
+		m.mu.Lock()
+		if len(e.results) >= memoMaxResults {
+			e.results = make(map[string]float32)
+		}
+		e.results[key] = result
+		m.mu.Unlock()
+	}
+	return result, nil
+}
+
+// lookup returns memo entry of an expression, compiling it if it is
+// not memoized yet, and key of its result for given values, if there
+// is one. Entries compiled in another scope are dropped first.
+func (m *exprMemo) lookup(c *LV2HostConfig, value string, vars map[string]interface{}) (*memoEntry, string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if scope := c.memoScope(); scope != m.scope {
+		m.scope = scope
+		m.entries = make(map[string]*memoEntry)
+	}
+	e, ok := m.entries[value]
+	if !ok {
+		expr, err := c.compileExpr(value)
+		if err != nil {
+			return nil, "", false, err
+		}
+		names := expr.Vars()
+		sort.Strings(names)
+		e = &memoEntry{expr, names, make(map[string]float32)}
+		m.entries[value] = e
+	}
+	key, ok := memoKey(e.vars, vars)
+	return e, key, ok, nil
+}
+
+// ResetMemo drops all memoized expression results. Memo follows
+// silence floor, audit log and functions being added or removed,
+// but this must be done whenever a function of FunctionMap is
+// replaced by another one.
+func (c *LV2HostConfig) ResetMemo() {
+	if m := c.memo; m != nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.entries = make(map[string]*memoEntry)
+	}
+}
@@ -0,0 +1,61 @@
+package lv2hostconfig_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+func TestMemoHungExpression(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      gain: hang(1)\n")
+	c.Memoize = true
+	c.ParamBudget = 50 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	err := c.RegisterFunction("hang", func(args ...interface{}) (interface{}, error) {
+		<-release
+		return args[0], nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register function: %v", err)
+	}
+	var be *lv2hostconfig.BudgetError
+	if err := c.Evaluate(); !errors.As(err, &be) {
+		t.Fatalf("Evaluating hung expression returned '%v', want a BudgetError", err)
+	}
+	// the hung expression is still running, but must not keep other
+	// expressions from being evaluated
+	c.Plugins[0].DataFmt["gain"] = "1 + 2"
+	lv2hostconfigtest.MustEvaluate(t, c)
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", 3, 0)
+}
+
+func TestMemoFollowsFunctions(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      gain: decibel(0)\n")
+	c.Memoize = true
+	lv2hostconfigtest.MustEvaluate(t, c)
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", -144, 1e-3)
+
+	file := writeTemp(t, "floor.yaml", "silenceFloor: -100\nplugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      gain: decibel(0)\n")
+	if err := c.ReadFile(file); err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	lv2hostconfigtest.MustEvaluate(t, c)
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", -100, 1e-3)
+
+	c.Plugins[0].DataFmt["gain"] = "twice(2)"
+	if err := c.Evaluate(); err == nil {
+		t.Fatal("Evaluating unknown function succeeded")
+	}
+	err := c.RegisterFunction("twice", func(args ...interface{}) (interface{}, error) {
+		return args[0].(float64) * 2, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register function: %v", err)
+	}
+	lv2hostconfigtest.MustEvaluate(t, c)
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", 4, 0)
+}
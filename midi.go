@@ -0,0 +1,60 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+func setUpMIDIFuncs(lvc *LV2HostConfig) {
+	lvc.FunctionMap["midi"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return math.NaN(), fmt.Errorf("Function 'midi' expects exactly 3 arguments")
+		}
+		var val, min, max float32
+		floatPtrs := []*float32{&val, &min, &max}
+		for i, arg := range args {
+			v, err := convert.Float32(arg)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
+			}
+			*floatPtrs[i] = v
+		}
+		t, err := LV2ParamRange{min, max, MappingLinear}.normalize(val)
+		if err != nil {
+			return math.NaN(), err
+		}
+		return math.Round(float64(t) * 127), nil
+	}
+	lvc.FunctionMap["frommidi"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 && len(args) != 4 {
+			return math.NaN(), fmt.Errorf("Function 'frommidi' expects 3 or 4 arguments")
+		}
+		var cc, min, max float32
+		floatPtrs := []*float32{&cc, &min, &max}
+		for i, arg := range args[:3] {
+			v, err := convert.Float32(arg)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
+			}
+			*floatPtrs[i] = v
+		}
+		mapping := MappingLinear
+		if len(args) == 4 {
+			curve, ok := args[3].(string)
+			if !ok {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a string", args[3])
+			}
+			mapping = curve
+		}
+		if cc < 0 || cc > 127 {
+			return math.NaN(), fmt.Errorf("Value '%v' is not within range '0-127'", cc)
+		}
+		v, err := LV2ParamRange{min, max, mapping}.denormalize(cc / 127)
+		if err != nil {
+			return math.NaN(), err
+		}
+		return float64(v), nil
+	}
+}
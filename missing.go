@@ -0,0 +1,132 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// policies for plugins that are not installed
+const (
+	MissingFail     = "fail"
+	MissingFallback = "fallback"
+	MissingSkip     = "skip"
+	MissingBypass   = "bypass"
+)
+
+// PluginCatalog knows which plugins are installed
+type PluginCatalog interface {
+	HasPlugin(uri string) bool
+}
+
+// PluginCatalogFunc allows using a function as plugin catalog
+type PluginCatalogFunc func(uri string) bool
+
+// HasPlugin calls f(uri)
+func (f PluginCatalogFunc) HasPlugin(uri string) bool {
+	return f(uri)
+}
+
+// PluginSubstitution describes what was done about a plugin that
+// is not installed. Action is MissingFallback (plugin URI was
+// replaced with its fallback URI), MissingSkip (host should not
+// instantiate the plugin) or MissingBypass (host should keep the
+// plugin's place in the chain, but pass audio through).
+type PluginSubstitution struct {
+	OriginalURI string
+	Action      string
+}
+
+func (s *PluginSubstitution) clone() *PluginSubstitution {
+	if s == nil {
+		return nil
+	}
+	ns := *s
+	return &ns
+}
+
+// MissingPluginsError is returned when plugins are not installed,
+// and there is nothing that can be done about it
+type MissingPluginsError struct {
+	URIs []string
+}
+
+func (e *MissingPluginsError) Error() string {
+	return fmt.Sprintf("Plugins not installed: %v", strings.Join(e.URIs, ", "))
+}
+
+// missingAction decides what to do about a plugin that is not
+// installed. Unless policy says otherwise, fallback URI is used if
+// there is one and it is installed.
+func (pc *LV2PluginConfig) missingAction(catalog PluginCatalog) (string, error) {
+	switch pc.MissingPolicy {
+	case MissingSkip, MissingBypass, MissingFail:
+		return pc.MissingPolicy, nil
+	case "", MissingFallback:
+		if pc.FallbackURI != "" && catalog.HasPlugin(pc.FallbackURI) {
+			return MissingFallback, nil
+		}
+		return MissingFail, nil
+	}
+	return "", fmt.Errorf("Unknown missing plugin policy '%v'", pc.MissingPolicy)
+}
+
+// resolveMissing applies fallbacks and policies to plugins of a
+// chain, adding substitutions to subs and missing URIs to missing
+func resolveMissing(chain []LV2PluginConfig, catalog PluginCatalog, subs *[]PluginSubstitution, missing *[]string) ([]LV2PluginConfig, error) {
+	pcs := make([]LV2PluginConfig, 0, len(chain))
+	for i := range chain {
+		pc := chain[i].clone()
+		// plugins that were already substituted are checked again
+		if pc.Substitution != nil {
+			pc.PluginURI = pc.Substitution.OriginalURI
+			pc.Substitution = nil
+		}
+		if !catalog.HasPlugin(pc.PluginURI) {
+			action, err := pc.missingAction(catalog)
+			if err != nil {
+				return nil, err
+			}
+			if action == MissingFail {
+				*missing = append(*missing, pc.PluginURI)
+			} else {
+				sub := PluginSubstitution{pc.PluginURI, action}
+				if action == MissingFallback {
+					pc.PluginURI = pc.FallbackURI
+				}
+				pc.Substitution = &sub
+				*subs = append(*subs, sub)
+			}
+		}
+		pcs = append(pcs, pc)
+	}
+	return pcs, nil
+}
+
+// ResolveMissing checks plugins (of Plugins and named chains)
+// against a catalog of installed plugins, and applies fallbacks and
+// policies of plugins that are not installed, recording what was
+// done in their Substitution. Substitutions are not saved when the
+// config is written, so that the config keeps referring to the
+// original plugins. Resolving is atomic, so if any plugins can't be
+// substituted, the config is not modified and all of them are
+// reported.
+func (c *LV2HostConfig) ResolveMissing(catalog PluginCatalog) ([]PluginSubstitution, error) {
+	subs := make([]PluginSubstitution, 0)
+	missing := make([]string, 0)
+	pcs, err := resolveMissing(c.Plugins, catalog, &subs, &missing)
+	if err != nil {
+		return nil, err
+	}
+	chains, err := c.mapChains(func(_ string, chain []LV2PluginConfig) ([]LV2PluginConfig, error) {
+		return resolveMissing(chain, catalog, &subs, &missing)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) != 0 {
+		return nil, &MissingPluginsError{missing}
+	}
+	c.Plugins = pcs
+	c.Chains = chains
+	return subs, nil
+}
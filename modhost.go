@@ -0,0 +1,95 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"io"
+)
+
+// AudioPorts are symbols of audio ports of a plugin, in order
+type AudioPorts struct {
+	Inputs  []string
+	Outputs []string
+}
+
+// ModHost is a PlanConsumer that writes commands understood by
+// mod-host, so that the host can be driven by the config directly.
+// Plugin instances are numbered by their position in the chain,
+// with plugins of named chains numbered after those of Plugins.
+// Connections made in chain order connect outputs of a plugin to
+// inputs of the next one, pairwise, so audio ports of plugins (by
+// URI) must be known for those; connections of the connections
+// section don't need them. Plugins are active as soon as mod-host
+// adds them, so activation writes nothing.
+type ModHost struct {
+	// Ports are audio ports of plugins, by URI
+	Ports map[string]AudioPorts
+
+	w    io.Writer
+	uris map[int]string
+}
+
+// NewModHost creates a mod-host command writer
+func NewModHost(w io.Writer, ports map[string]AudioPorts) *ModHost {
+	return &ModHost{ports, w, make(map[int]string)}
+}
+
+func (h *ModHost) command(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(h.w, format+"\n", args...)
+	return err
+}
+
+// port returns JACK name of a plugin port
+func (h *ModHost) port(plugin int, symbol string) string {
+	if plugin == SystemIndex {
+		return "system:" + symbol
+	}
+	return fmt.Sprintf("effect_%v:%v", plugin, symbol)
+}
+
+// Instantiate implements PlanConsumer
+func (h *ModHost) Instantiate(plugin int, uri string) error {
+	h.uris[plugin] = uri
+	return h.command("add %v %v", uri, plugin)
+}
+
+// SetParam implements PlanConsumer
+func (h *ModHost) SetParam(plugin int, symbol string, value float32) error {
+	return h.command("param_set %v %v %v", plugin, symbol, formatFloat(value))
+}
+
+// Connect implements PlanConsumer
+func (h *ModHost) Connect(from int, fromPort string, to int, toPort string) error {
+	if fromPort != "" || toPort != "" {
+		return h.command("connect %v %v", h.port(from, fromPort), h.port(to, toPort))
+	}
+	out, ok := h.Ports[h.uris[from]]
+	if !ok {
+		return fmt.Errorf("Audio ports of plugin '%v' are unknown", h.uris[from])
+	}
+	in, ok := h.Ports[h.uris[to]]
+	if !ok {
+		return fmt.Errorf("Audio ports of plugin '%v' are unknown", h.uris[to])
+	}
+	for i := 0; i < len(out.Outputs) && i < len(in.Inputs); i++ {
+		if err := h.command("connect %v %v", h.port(from, out.Outputs[i]), h.port(to, in.Inputs[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Activate implements PlanConsumer
+func (h *ModHost) Activate(plugin int) error {
+	return nil
+}
+
+// WriteModHost writes apply plan of an evaluated config to w as a
+// mod-host command script. Ports are audio ports of plugins, by URI,
+// which are needed unless the config has a connections section.
+func (c *LV2HostConfig) WriteModHost(w io.Writer, sampleRate float32, ports map[string]AudioPorts) error {
+	plan, err := c.ApplyPlan(sampleRate)
+	if err != nil {
+		return err
+	}
+	return ExecutePlan(plan, NewModHost(w, ports))
+}
@@ -0,0 +1,89 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+
+	"github.com/Knetic/govaluate"
+)
+
+// reservedVariables are variables set by the parser itself
+var reservedVariables = map[string]bool{
+	"reference": true,
+	"bpm":       true,
+	"beat_ms":   true,
+	"bar_ms":    true,
+}
+
+// builtinFunctions are names of all standard functions
+var builtinFunctions = func() map[string]bool {
+	lvc := &LV2HostConfig{FunctionMap: make(map[string]govaluate.ExpressionFunction)}
+	setUpLV2HostConfigFuncs(lvc)
+	names := make(map[string]bool)
+	for name := range lvc.FunctionMap {
+		names[name] = true
+	}
+	return names
+}()
+
+// NewEmptyLV2HostConfig creates new host config without any of
+// the standard functions, for hosts that want full control over
+// what expressions can do
+func NewEmptyLV2HostConfig() *LV2HostConfig {
+	return &LV2HostConfig{
+		Plugins:     make([]LV2PluginConfig, 0),
+		ValueMap:    make(map[string]interface{}),
+		FunctionMap: make(map[string]govaluate.ExpressionFunction),
+		Clock:       systemClock{},
+		memo:        newExprMemo(),
+	}
+}
+
+// IsBuiltinFunction checks if name is one of the standard functions
+func IsBuiltinFunction(name string) bool {
+	return builtinFunctions[name]
+}
+
+// RegisterFunction adds a function to FunctionMap. Unlike setting
+// FunctionMap entries directly, it fails instead of overriding if
+// the name is used by one of the standard functions (even if the
+// config was created without them), another registered function,
+// a variable or a constant.
+func (c *LV2HostConfig) RegisterFunction(name string, f govaluate.ExpressionFunction) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("Function name '%v' is not a valid identifier", name)
+	}
+	if IsBuiltinFunction(name) {
+		return fmt.Errorf("Function '%v' is a built-in function", name)
+	}
+	if _, ok := c.FunctionMap[name]; ok {
+		return fmt.Errorf("Function '%v' is already registered", name)
+	}
+	if _, ok := c.ValueMap[name]; ok {
+		return fmt.Errorf("Name '%v' is already used by a variable", name)
+	}
+	if _, ok := c.Constants[name]; ok {
+		return fmt.Errorf("Name '%v' is already used by a constant", name)
+	}
+	c.FunctionMap[name] = f
+	return nil
+}
+
+// SetVariable sets a variable in ValueMap. Existing variables can
+// be changed, but it fails if the name is reserved by the parser
+// or is used by a function or a constant.
+func (c *LV2HostConfig) SetVariable(name string, value interface{}) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("Variable name '%v' is not a valid identifier", name)
+	}
+	if reservedVariables[name] {
+		return fmt.Errorf("Variable '%v' is reserved", name)
+	}
+	if _, ok := c.FunctionMap[name]; ok || IsBuiltinFunction(name) {
+		return fmt.Errorf("Name '%v' is already used by a function", name)
+	}
+	if _, ok := c.Constants[name]; ok {
+		return fmt.Errorf("Name '%v' is already used by a constant", name)
+	}
+	c.ValueMap[name] = value
+	return nil
+}
@@ -0,0 +1,152 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Range mapping hints, specifying how parameter values
+// are mapped onto 0..1 controller positions.
+const (
+	MappingLinear = "linear"
+	MappingLog    = "log"
+)
+
+// LV2ParamRange is a value range of a parameter. Mapping
+// is one of MappingLinear (default) or MappingLog.
+type LV2ParamRange struct {
+	Min     float32 `yaml:"min"`
+	Max     float32 `yaml:"max"`
+	Mapping string  `yaml:"mapping,omitempty"`
+}
+
+func copyRanges(ranges map[string]LV2ParamRange) map[string]LV2ParamRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	r := make(map[string]LV2ParamRange)
+	for k, v := range ranges {
+		r[k] = v
+	}
+	return r
+}
+
+func (r LV2ParamRange) check() error {
+	if r.Min >= r.Max {
+		return fmt.Errorf("Range '%v-%v' is invalid", r.Min, r.Max)
+	}
+	switch r.Mapping {
+	case "", MappingLinear:
+	case MappingLog:
+		if r.Min <= 0 {
+			return fmt.Errorf("Range '%v-%v' can't be mapped logarithmically", r.Min, r.Max)
+		}
+	default:
+		return fmt.Errorf("Unknown range mapping '%v'", r.Mapping)
+	}
+	return nil
+}
+
+// normalize maps value to 0..1 range. Values outside of
+// the range are clamped.
+func (r LV2ParamRange) normalize(v float32) (float32, error) {
+	if err := r.check(); err != nil {
+		return float32(math.NaN()), err
+	}
+	v = float32(math.Max(math.Min(float64(v), float64(r.Max)), float64(r.Min)))
+	if r.Mapping == MappingLog {
+		t := math.Log(float64(v/r.Min)) / math.Log(float64(r.Max/r.Min))
+		return float32(t), nil
+	}
+	return (v - r.Min) / (r.Max - r.Min), nil
+}
+
+// denormalize maps 0..1 value back into the range. Values
+// outside of 0..1 are clamped.
+func (r LV2ParamRange) denormalize(t float32) (float32, error) {
+	if err := r.check(); err != nil {
+		return float32(math.NaN()), err
+	}
+	t = float32(math.Max(math.Min(float64(t), 1), 0))
+	if r.Mapping == MappingLog {
+		v := float64(r.Min) * math.Pow(float64(r.Max/r.Min), float64(t))
+		return float32(v), nil
+	}
+	return r.Min + t*(r.Max-r.Min), nil
+}
+
+// paramRange returns range of a parameter, which is taken from
+// plugin config if it is there (along with its mapping), or from
+// schemas or factory defaults database, which are mapped linearly
+func (c *LV2HostConfig) paramRange(pc *LV2PluginConfig, symbol string) (LV2ParamRange, error) {
+	if r, ok := pc.Ranges[symbol]; ok {
+		return r, nil
+	}
+	ports, _ := FactoryPorts(pc.PluginURI)
+	min, max, ok := c.knownRange(pc, symbol, ports)
+	if !ok {
+		return LV2ParamRange{}, fmt.Errorf("No range known for parameter '%v' of plugin '%v'", symbol, pc.PluginURI)
+	}
+	return LV2ParamRange{min, max, MappingLinear}, nil
+}
+
+func formatFloat(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+// setValue sets evaluated value of parameter. If the parameter
+// was not an expression, its formatted value is updated as well.
+func (pc *LV2PluginConfig) setValue(symbol string, v float32) {
+	pc.Data[symbol] = v
+	f, ok := pc.DataFmt[symbol]
+	if !ok {
+		pc.DataFmt[symbol] = formatFloat(v)
+		return
+	}
+	if _, err := strconv.ParseFloat(f, 32); err == nil {
+		pc.DataFmt[symbol] = formatFloat(v)
+	}
+}
+
+// Normalized returns value of plugin parameter mapped onto
+// 0..1 controller range, according to parameter's range, which is
+// looked up in Ranges of the plugin, registered schemas and factory
+// defaults database, in that order. Plugin is identified by its
+// name or URI. Config must have been evaluated for this to work.
+func (c *LV2HostConfig) Normalized(plugin, symbol string) (float32, error) {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return float32(math.NaN()), err
+	}
+	r, err := c.paramRange(pc, symbol)
+	if err != nil {
+		return float32(math.NaN()), err
+	}
+	v, ok := pc.Data[symbol]
+	if !ok {
+		return float32(math.NaN()), fmt.Errorf("Parameter '%v' of plugin '%v' has no value", symbol, plugin)
+	}
+	return r.normalize(v)
+}
+
+// SetNormalized sets value of plugin parameter from a 0..1
+// controller position, according to parameter's range. If
+// parameter value was not an expression, its formatted
+// value is updated too.
+func (c *LV2HostConfig) SetNormalized(plugin, symbol string, t float32) error {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return err
+	}
+	r, err := c.paramRange(pc, symbol)
+	if err != nil {
+		return err
+	}
+	v, err := r.denormalize(t)
+	if err != nil {
+		return err
+	}
+	pc.setValue(symbol, v)
+	return nil
+}
@@ -0,0 +1,57 @@
+package lv2hostconfig
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// NotifySource is a config file, which is watched for changes
+// using file system notifications (inotify, kqueue and so on)
+// instead of being polled. Directory of the file is watched rather
+// than the file itself, so that the file keeps being watched when
+// it is replaced by renaming another file over it, as editors and
+// WriteToFile do. Where notifications can't be used, it falls back
+// to polling every Interval, just like FileSource. Revisions and
+// Debounce work the same way as well.
+type NotifySource struct {
+	FileSource
+}
+
+// Wait implements Source
+func (s *NotifySource) Wait(ctx context.Context, rev string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return s.FileSource.Wait(ctx, rev)
+	}
+	defer w.Close()
+	if err := w.Add(filepath.Dir(s.Path)); err != nil {
+		return s.FileSource.Wait(ctx, rev)
+	}
+	// file may have changed before it was being watched
+	if cur, err := s.revision(); err == nil && cur != rev {
+		return s.settle(ctx, cur)
+	}
+	name := filepath.Clean(s.Path)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-w.Events:
+			if !ok {
+				return s.FileSource.Wait(ctx, rev)
+			}
+			if filepath.Clean(ev.Name) != name {
+				continue
+			}
+		case <-w.Errors:
+			// notifications may have been lost, so poll instead
+			return s.FileSource.Wait(ctx, rev)
+		}
+		// files that are being replaced may be briefly missing
+		if cur, err := s.revision(); err == nil && cur != rev {
+			return s.settle(ctx, cur)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+)
+
+// Start offset units. Seconds are the default.
+const (
+	OffsetSeconds      = "s"
+	OffsetMilliseconds = "ms"
+	OffsetSamples      = "samples"
+)
+
+func (c *LV2HostConfig) evaluateStartOffset(pc *LV2PluginConfig, vars map[string]interface{}, run *evalRun) error {
+	pc.StartOffset = 0
+	switch pc.StartOffsetUnit {
+	case "", OffsetSeconds, OffsetMilliseconds, OffsetSamples:
+	default:
+		return pc.valueError("startOffset", pc.StartOffsetFmt, fmt.Errorf("Unknown start offset unit '%v'", pc.StartOffsetUnit))
+	}
+	if pc.StartOffsetFmt == "" {
+		return nil
+	}
+	result, err := c.evaluateTimed(run, "startOffset", pc.StartOffsetFmt, vars)
+	if err != nil {
+		return pc.valueError("startOffset", pc.StartOffsetFmt, err)
+	}
+	if result < 0 {
+		return pc.valueError("startOffset", pc.StartOffsetFmt, fmt.Errorf("Start offset '%v' is negative", pc.StartOffsetFmt))
+	}
+	pc.StartOffset = result
+	return nil
+}
+
+// StartOffsetSamples returns evaluated start offset of the
+// plugin in samples, given the sample rate.
+func (pc *LV2PluginConfig) StartOffsetSamples(sampleRate float32) int64 {
+	switch pc.StartOffsetUnit {
+	case OffsetSamples:
+		return int64(math.Round(float64(pc.StartOffset)))
+	case OffsetMilliseconds:
+		return int64(math.Round(float64(pc.StartOffset) * float64(sampleRate) / 1000))
+	default:
+		return int64(math.Round(float64(pc.StartOffset) * float64(sampleRate)))
+	}
+}
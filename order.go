@@ -0,0 +1,105 @@
+package lv2hostconfig
+
+import (
+	yaml "gopkg.in/yaml.v3"
+)
+
+// mappingOrder returns keys of a mapping in document order, with
+// keys of merged mappings placed where the merge key is, unless
+// they are also specified explicitly
+func mappingOrder(n *yaml.Node) []string {
+	n, _ = resolveAlias(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	explicit := make(map[string]bool)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Tag != "!!merge" {
+			explicit[n.Content[i].Value] = true
+		}
+	}
+	res := make([]string, 0, len(n.Content)/2)
+	seen := make(map[string]bool)
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			res = append(res, key)
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Tag != "!!merge" {
+			add(k.Value)
+			continue
+		}
+		merged := []*yaml.Node{v}
+		if v, _ = resolveAlias(v); v != nil && v.Kind == yaml.SequenceNode {
+			merged = v.Content
+		}
+		for _, m := range merged {
+			for _, key := range mappingOrder(m) {
+				if !explicit[key] {
+					add(key)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// reorderMapping reorders entries of a mapping node, so that keys
+// listed in order come first, in that order. Other entries follow
+// in the order they were in.
+func reorderMapping(n *yaml.Node, order []string) {
+	if n == nil || n.Kind != yaml.MappingNode || len(order) == 0 {
+		return
+	}
+	pairs := make(map[string][2]*yaml.Node)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		pairs[n.Content[i].Value] = [2]*yaml.Node{n.Content[i], n.Content[i+1]}
+	}
+	content := make([]*yaml.Node, 0, len(n.Content))
+	used := make(map[string]bool)
+	for _, key := range order {
+		if p, ok := pairs[key]; ok && !used[key] {
+			used[key] = true
+			content = append(content, p[0], p[1])
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !used[n.Content[i].Value] {
+			content = append(content, n.Content[i], n.Content[i+1])
+		}
+	}
+	n.Content = content
+}
+
+// reorderParams orders parameters of every plugin of an encoded
+// config by ParamOrder of the plugin
+func reorderParams(top *yaml.Node, raw *lv2HostRaw) {
+	keys := keyNodes(top)
+	if plugins, ok := keys["plugins"]; ok {
+		reorderPluginParams(plugins[1], raw.Plugins)
+	}
+	if chains, ok := keys["chains"]; ok && chains[1].Kind == yaml.MappingNode {
+		for name, chain := range keyNodes(chains[1]) {
+			reorderPluginParams(chain[1], raw.Chains[name])
+		}
+	}
+}
+
+// reorderPluginParams orders parameters of every plugin entry of
+// an encoded chain
+func reorderPluginParams(plugins *yaml.Node, raws []lv2PluginRaw) {
+	if plugins.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range plugins.Content {
+		if i >= len(raws) || item.Kind != yaml.MappingNode {
+			break
+		}
+		if params, ok := keyNodes(item)["parameters"]; ok {
+			reorderMapping(params[1], raws[i].order)
+		}
+	}
+}
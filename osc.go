@@ -0,0 +1,90 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OSCBinding binds an OSC address to a plugin parameter. Plugin is
+// index of the plugin in the chain, and PluginID its instance ID.
+// Min and Max are range of the parameter, if it is known (see
+// HasRange). Values received on the address are plain parameter
+// values.
+type OSCBinding struct {
+	Path     string
+	Plugin   int
+	PluginID string
+	Symbol   string
+	Min      float32
+	Max      float32
+	HasRange bool
+}
+
+// OSCMap is a set of OSC bindings, sorted by address
+type OSCMap []OSCBinding
+
+// Lookup finds binding of an OSC address
+func (m OSCMap) Lookup(path string) (OSCBinding, bool) {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Path >= path })
+	if i < len(m) && m[i].Path == path {
+		return m[i], true
+	}
+	return OSCBinding{}, false
+}
+
+// checkOSCPath checks that path is a valid OSC address, which must
+// start with a slash and can't contain characters OSC reserves for
+// address patterns
+func checkOSCPath(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("OSC address '%v' doesn't start with '/'", path)
+	}
+	if strings.ContainsAny(path, " #*,?[]{}") {
+		return fmt.Errorf("OSC address '%v' contains reserved characters", path)
+	}
+	return nil
+}
+
+// MapOSC maps an OSC address to a plugin parameter, replacing any
+// parameter the address was mapped to before
+func (c *LV2HostConfig) MapOSC(path, plugin, symbol string) error {
+	if err := checkOSCPath(path); err != nil {
+		return err
+	}
+	if _, err := c.findPlugin(plugin); err != nil {
+		return err
+	}
+	if c.OSC == nil {
+		c.OSC = make(map[string]string)
+	}
+	c.OSC[path] = plugin + "." + symbol
+	return nil
+}
+
+// OSCMap resolves OSC addresses of the config to plugin parameters,
+// looking up ranges of parameters in plugin configs, schemas and the
+// factory defaults database. Invalid addresses, and addresses mapped
+// to unknown plugins, are reported as errors.
+func (c *LV2HostConfig) OSCMap() (OSCMap, error) {
+	m := make(OSCMap, 0, len(c.OSC))
+	for _, path := range sortedKeys(c.OSC) {
+		if err := checkOSCPath(path); err != nil {
+			return nil, err
+		}
+		plugin, symbol, err := splitParamRef(c.OSC[path])
+		if err == nil {
+			var i int
+			if i, err = c.findPluginIndex(plugin); err == nil {
+				pc := &c.Plugins[i]
+				ports, _ := FactoryPorts(pc.PluginURI)
+				min, max, ok := c.knownRange(pc, symbol, ports)
+				m = append(m, OSCBinding{path, i, c.InstanceID(i), symbol, min, max, ok})
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("OSC address '%v': %v", path, err)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,330 @@
+// Package oscbridge exposes an evaluated config as a tree of OSC
+// addresses, so that any OSC client can inspect (and optionally
+// tweak) a running host. Values of parameters are at
+// /host/<plugin>/<symbol>, where plugin is instance ID of the
+// plugin. Sending a message without arguments to an address queries
+// it: parameters reply with their values, and other addresses of the
+// tree reply with names of their children. If the bridge is
+// writable, sending a number to a parameter overrides its value (see
+// LV2HostConfig.SetOverride). Messages are encoded as described by
+// OSC 1.0 specification; bundles are not supported.
+package oscbridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/burillo-se/lv2hostconfig"
+)
+
+// Root is the address all parameters are under
+const Root = "/host"
+
+// ErrorAddress is the address errors are reported on, with address
+// of the failed request and the error message as arguments
+const ErrorAddress = "/error"
+
+// maxPacket is the largest OSC packet Serve can receive
+const maxPacket = 65536
+
+type param struct {
+	plugin, symbol string
+}
+
+// Bridge is an OSC address tree of a config. Tree is built when
+// the bridge is created, so a new bridge must be created if plugins
+// or parameters of the config change. Bridge serializes its own
+// access to the config, and others must use Do to access the config
+// while the bridge is in use.
+type Bridge struct {
+	// Writable allows clients to override parameter values
+	Writable bool
+
+	mu       sync.Mutex
+	c        *lv2hostconfig.LV2HostConfig
+	params   map[string]param
+	children map[string][]string
+}
+
+// Escape escapes characters that are not allowed in parts of OSC
+// addresses, the way URLs are escaped
+func Escape(part string) string {
+	var b strings.Builder
+	for i := 0; i < len(part); i++ {
+		ch := part[i]
+		if ch <= ' ' || ch >= 0x7f || strings.IndexByte("%#*,/?[]{}", ch) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		} else {
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// New creates an address tree of an evaluated config
+func New(c *lv2hostconfig.LV2HostConfig, writable bool) *Bridge {
+	b := &Bridge{
+		Writable: writable,
+		c:        c,
+		params:   make(map[string]param),
+		children: make(map[string][]string),
+	}
+	b.children["/"] = []string{strings.TrimPrefix(Root, "/")}
+	b.children[Root] = make([]string, 0, len(c.Plugins))
+	for i := range c.Plugins {
+		pc := &c.Plugins[i]
+		id := c.InstanceID(i)
+		dir := Root + "/" + Escape(id)
+		b.children[Root] = append(b.children[Root], Escape(id))
+
+		symbols := make([]string, 0, len(pc.DataFmt))
+		for symbol := range pc.DataFmt {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+		b.children[dir] = make([]string, 0, len(symbols))
+		for _, symbol := range symbols {
+			b.children[dir] = append(b.children[dir], Escape(symbol))
+			b.params[dir+"/"+Escape(symbol)] = param{id, symbol}
+		}
+	}
+	return b
+}
+
+// Do calls fn with the config, while no OSC requests are handled
+func (b *Bridge) Do(fn func(c *lv2hostconfig.LV2HostConfig)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fn(b.c)
+}
+
+// Paths returns addresses of all parameters, sorted
+func (b *Bridge) Paths() []string {
+	paths := make([]string, 0, len(b.params))
+	for path := range b.params {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// List returns names of children of an address
+func (b *Bridge) List(path string) ([]string, error) {
+	children, ok := b.children[path]
+	if !ok {
+		return nil, fmt.Errorf("Address '%v' not found", path)
+	}
+	return append([]string(nil), children...), nil
+}
+
+// Get returns value of a parameter
+func (b *Bridge) Get(path string) (lv2hostconfig.ParamValue, error) {
+	p, ok := b.params[path]
+	if !ok {
+		return lv2hostconfig.ParamValue{}, fmt.Errorf("Address '%v' is not a parameter", path)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.c.Value(p.plugin, p.symbol)
+}
+
+// Set overrides value of a parameter, if the bridge is writable
+func (b *Bridge) Set(path string, v float32) error {
+	p, ok := b.params[path]
+	if !ok {
+		return fmt.Errorf("Address '%v' is not a parameter", path)
+	}
+	if !b.Writable {
+		return fmt.Errorf("Address '%v' is read-only", path)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.c.SetOverride(p.plugin, p.symbol, v)
+}
+
+// Handle handles an OSC request packet, returning the reply packet.
+// Failed requests are replied to on ErrorAddress.
+func (b *Bridge) Handle(packet []byte) []byte {
+	path, args, err := decodeMessage(packet)
+	if err == nil {
+		var reply []byte
+		if reply, err = b.handle(path, args); err == nil {
+			return reply
+		}
+	}
+	return encodeMessage(ErrorAddress, path, err.Error())
+}
+
+func (b *Bridge) handle(path string, args []interface{}) ([]byte, error) {
+	if _, ok := b.params[path]; !ok {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("Address '%v' is not a parameter", path)
+		}
+		children, err := b.List(path)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(children))
+		for i, child := range children {
+			values[i] = child
+		}
+		return encodeMessage(path, values...), nil
+	}
+	switch len(args) {
+	case 0:
+	case 1:
+		var v float32
+		switch arg := args[0].(type) {
+		case float32:
+			v = arg
+		case bool:
+			if arg {
+				v = 1
+			}
+		default:
+			return nil, fmt.Errorf("Value of '%v' must be a number", path)
+		}
+		if err := b.Set(path, v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Too many arguments for '%v'", path)
+	}
+	v, err := b.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	switch v.Kind {
+	case lv2hostconfig.KindInt:
+		return encodeMessage(path, int32(v.Int)), nil
+	case lv2hostconfig.KindBool:
+		return encodeMessage(path, v.Bool), nil
+	}
+	return encodeMessage(path, v.Float), nil
+}
+
+// Serve handles OSC requests arriving on conn, replying to their
+// senders, until reading from conn fails
+func (b *Bridge) Serve(conn net.PacketConn) error {
+	buf := make([]byte, maxPacket)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.WriteTo(b.Handle(buf[:n]), addr); err != nil {
+			return err
+		}
+	}
+}
+
+// encodeString encodes an OSC string, which is null-terminated and
+// padded to a multiple of 4 bytes
+func encodeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.Write(make([]byte, 4-len(s)%4))
+}
+
+// encodeMessage encodes an OSC message. Arguments are float32,
+// int32, string or bool.
+func encodeMessage(path string, args ...interface{}) []byte {
+	var buf bytes.Buffer
+	encodeString(&buf, path)
+	tags := ","
+	var data bytes.Buffer
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case float32:
+			tags += "f"
+			binary.Write(&data, binary.BigEndian, math.Float32bits(v))
+		case int32:
+			tags += "i"
+			binary.Write(&data, binary.BigEndian, v)
+		case string:
+			tags += "s"
+			encodeString(&data, v)
+		case bool:
+			if v {
+				tags += "T"
+			} else {
+				tags += "F"
+			}
+		}
+	}
+	encodeString(&buf, tags)
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+// decodeString decodes an OSC string at the start of data, returning
+// the rest of data
+func decodeString(data []byte) (string, []byte, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("String is not terminated")
+	}
+	size := end + 4 - end%4
+	if size > len(data) {
+		return "", nil, fmt.Errorf("String is not padded")
+	}
+	return string(data[:end]), data[size:], nil
+}
+
+// decodeMessage decodes an OSC message. Numeric arguments are all
+// decoded as float32, and booleans as bool.
+func decodeMessage(packet []byte) (string, []interface{}, error) {
+	path, data, err := decodeString(packet)
+	if err != nil {
+		return "", nil, fmt.Errorf("Invalid OSC message: %v", err)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return path, nil, fmt.Errorf("Invalid OSC message: address '%v' doesn't start with '/'", path)
+	}
+	args := make([]interface{}, 0)
+	// type tags may be missing in messages of old clients
+	if len(data) == 0 {
+		return path, args, nil
+	}
+	tags, data, err := decodeString(data)
+	if err != nil || !strings.HasPrefix(tags, ",") {
+		return path, nil, fmt.Errorf("Invalid OSC message: bad type tags")
+	}
+	for _, tag := range tags[1:] {
+		switch tag {
+		case 'f', 'i':
+			if len(data) < 4 {
+				return path, nil, fmt.Errorf("Invalid OSC message: argument is truncated")
+			}
+			bits := binary.BigEndian.Uint32(data)
+			if tag == 'f' {
+				args = append(args, math.Float32frombits(bits))
+			} else {
+				args = append(args, float32(int32(bits)))
+			}
+			data = data[4:]
+		case 'd':
+			if len(data) < 8 {
+				return path, nil, fmt.Errorf("Invalid OSC message: argument is truncated")
+			}
+			args = append(args, float32(math.Float64frombits(binary.BigEndian.Uint64(data))))
+			data = data[8:]
+		case 's':
+			var s string
+			if s, data, err = decodeString(data); err != nil {
+				return path, nil, fmt.Errorf("Invalid OSC message: %v", err)
+			}
+			args = append(args, s)
+		case 'T', 'F':
+			args = append(args, tag == 'T')
+		default:
+			return path, nil, fmt.Errorf("Invalid OSC message: unsupported type '%c'", tag)
+		}
+	}
+	return path, args, nil
+}
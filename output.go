@@ -0,0 +1,20 @@
+package lv2hostconfig
+
+// SetOutput stores value of plugin output port, e.g. a value
+// measured by the host, so that it can be saved along with the
+// config as a session report. Output ports are never evaluated.
+func (c *LV2HostConfig) SetOutput(plugin, symbol string, v float32) error {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return err
+	}
+	pc.Outputs[symbol] = v
+	return nil
+}
+
+// ClearOutputs removes all output port values from the config
+func (c *LV2HostConfig) ClearOutputs() {
+	for i := range c.Plugins {
+		c.Plugins[i].Outputs = make(map[string]float32)
+	}
+}
@@ -0,0 +1,115 @@
+package lv2hostconfig
+
+import "fmt"
+
+// copyValues copies a map of evaluated values
+func copyValues(values map[string]float32) map[string]float32 {
+	if values == nil {
+		return nil
+	}
+	res := make(map[string]float32, len(values))
+	for k, v := range values {
+		res[k] = v
+	}
+	return res
+}
+
+// evaluated returns evaluated value of a parameter, ignoring any
+// override
+func (pc *LV2PluginConfig) evaluated(symbol string) (float32, bool) {
+	if _, ok := pc.overrides[symbol]; ok {
+		v, ok := pc.shadowed[symbol]
+		return v, ok
+	}
+	v, ok := pc.Data[symbol]
+	return v, ok
+}
+
+// applyOverrides shadows freshly evaluated values with overrides,
+// keeping the evaluated values so that overrides can be cleared
+func (pc *LV2PluginConfig) applyOverrides() {
+	if len(pc.overrides) == 0 {
+		return
+	}
+	pc.shadowed = make(map[string]float32)
+	for symbol, v := range pc.overrides {
+		if ev, ok := pc.Data[symbol]; ok {
+			pc.shadowed[symbol] = ev
+		}
+		pc.Data[symbol] = v
+	}
+}
+
+// SetOverride overrides evaluated value of a plugin parameter, for
+// temporary live tweaks. Overrides take precedence over evaluated
+// values (including frozen ones) until they are cleared, and so
+// are seen by Compare, ApplyPlan and anything else that looks at
+// Data, and they survive re-evaluation. They never change DataFmt,
+// so they are not written to config files. Reading a config file
+// drops all overrides.
+func (c *LV2HostConfig) SetOverride(plugin, symbol string, v float32) error {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return err
+	}
+	if _, ok := pc.DataFmt[symbol]; !ok {
+		return fmt.Errorf("Parameter '%v' of plugin '%v' not found", symbol, plugin)
+	}
+	if pc.overrides == nil {
+		pc.overrides = make(map[string]float32)
+		pc.shadowed = make(map[string]float32)
+	}
+	if _, ok := pc.overrides[symbol]; !ok {
+		if ev, ok := pc.Data[symbol]; ok {
+			pc.shadowed[symbol] = ev
+		}
+	}
+	pc.overrides[symbol] = v
+	pc.Data[symbol] = v
+	return nil
+}
+
+// ClearOverride removes override of a plugin parameter, restoring
+// its evaluated value
+func (c *LV2HostConfig) ClearOverride(plugin, symbol string) error {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return err
+	}
+	pc.clearOverride(symbol)
+	return nil
+}
+
+func (pc *LV2PluginConfig) clearOverride(symbol string) {
+	if _, ok := pc.overrides[symbol]; !ok {
+		return
+	}
+	if v, ok := pc.shadowed[symbol]; ok {
+		pc.Data[symbol] = v
+	} else {
+		delete(pc.Data, symbol)
+	}
+	delete(pc.overrides, symbol)
+	delete(pc.shadowed, symbol)
+}
+
+// ClearOverrides removes all overrides, restoring evaluated values
+func (c *LV2HostConfig) ClearOverrides() {
+	c.eachPlugin(func(pc *LV2PluginConfig) {
+		for symbol := range pc.overrides {
+			pc.clearOverride(symbol)
+		}
+		pc.overrides = nil
+		pc.shadowed = nil
+	})
+}
+
+// IsOverridden checks whether a plugin parameter is overridden
+func (c *LV2HostConfig) IsOverridden(plugin, symbol string) (bool, error) {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return false, err
+	}
+	_, ok := pc.overrides[symbol]
+	return ok, nil
+}
@@ -0,0 +1,116 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+)
+
+// ParamKind is kind of parameter value
+type ParamKind int
+
+// kinds of parameter values
+const (
+	KindFloat ParamKind = iota
+	KindInt
+	KindBool
+)
+
+func (k ParamKind) String() string {
+	switch k {
+	case KindInt:
+		return ParamInt
+	case KindBool:
+		return ParamToggle
+	}
+	return ParamFloat
+}
+
+// ParamValue is a typed value of a plugin parameter. Values are
+// still passed to plugins as floats, so Float is always valid.
+type ParamValue struct {
+	Kind  ParamKind
+	Float float32
+	Int   int
+	Bool  bool
+}
+
+func (v ParamValue) String() string {
+	switch v.Kind {
+	case KindInt:
+		return fmt.Sprint(v.Int)
+	case KindBool:
+		return fmt.Sprint(v.Bool)
+	}
+	return formatFloat(v.Float)
+}
+
+// paramKind returns kind of a parameter, as set in plugin's types
+// or in schema registered for the plugin. Parameters are floats
+// unless said otherwise.
+func (c *LV2HostConfig) paramKind(pc *LV2PluginConfig, symbol string) ParamKind {
+	t, ok := pc.Types[symbol]
+	if !ok {
+		t = c.Schemas[pc.PluginURI][symbol].Type
+	}
+	switch t {
+	case ParamInt:
+		return KindInt
+	case ParamToggle:
+		return KindBool
+	}
+	return KindFloat
+}
+
+// coerce converts evaluated value to parameter kind. Integers are
+// rounded to nearest, and toggles are on if value is positive, so
+// that plugins get exact values even if expressions are off by a
+// rounding error.
+func (k ParamKind) coerce(v float32) ParamValue {
+	switch k {
+	case KindInt:
+		i := int(math.Round(float64(v)))
+		return ParamValue{Kind: KindInt, Float: float32(i), Int: i}
+	case KindBool:
+		if v > 0 {
+			return ParamValue{Kind: KindBool, Float: 1, Int: 1, Bool: true}
+		}
+		return ParamValue{Kind: KindBool}
+	}
+	return ParamValue{Kind: KindFloat, Float: v, Int: int(v), Bool: v > 0}
+}
+
+// checkTypes checks that parameter types are known
+func checkTypes(types map[string]string) error {
+	for symbol, t := range types {
+		switch t {
+		case ParamFloat, ParamInt, ParamToggle:
+		default:
+			return fmt.Errorf("Failed to parse config: unknown type '%v' of parameter '%v'", t, symbol)
+		}
+	}
+	return nil
+}
+
+// resolveValue applies range policy to an evaluated value, then
+// coerces it to parameter kind
+func (c *LV2HostConfig) resolveValue(pc *LV2PluginConfig, symbol string, v float32, ports map[string]FactoryPort) (float32, error) {
+	v, err := c.checkRange(pc, symbol, v, ports)
+	if err != nil {
+		return v, err
+	}
+	return c.paramKind(pc, symbol).coerce(v).Float, nil
+}
+
+// Value returns typed evaluated value of plugin parameter. Plugin
+// is identified by its name, UUID or URI.
+func (c *LV2HostConfig) Value(plugin, symbol string) (ParamValue, error) {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return ParamValue{}, err
+	}
+	v, ok := pc.Data[symbol]
+	if !ok {
+		return ParamValue{}, fmt.Errorf("Parameter '%v' of plugin '%v' has no value", symbol, plugin)
+	}
+	return c.paramKind(pc, symbol).coerce(v), nil
+}
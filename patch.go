@@ -0,0 +1,206 @@
+package lv2hostconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ParamChange is a new value of a single plugin parameter
+type ParamChange struct {
+	Plugin string
+	Param  string
+	Value  float32
+}
+
+// patchEdit replaces bytes between start and end with text
+type patchEdit struct {
+	start, end int
+	text       string
+}
+
+// sourceHost builds a host config that only has enough plugin
+// data to look plugins up by identifier
+func sourceHost(raw *lv2HostRaw, sources []*pluginSource) *LV2HostConfig {
+	h := &LV2HostConfig{}
+	for i, rpd := range raw.Plugins {
+		if i >= len(sources) {
+			break
+		}
+		h.Plugins = append(h.Plugins, LV2PluginConfig{
+			Name:      rpd.Name,
+			UUID:      rpd.UUID,
+			PluginURI: rpd.URI,
+			source:    sources[i],
+		})
+	}
+	return h
+}
+
+// lineStarts returns byte offsets of the beginning of each line
+func lineStarts(data []byte) []int {
+	res := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			res = append(res, i+1)
+		}
+	}
+	return res
+}
+
+// scalarEdit locates bytes of a scalar node and returns an edit
+// replacing its value, keeping quoting style of the original
+func scalarEdit(data []byte, lines []int, n *yaml.Node, value string) (patchEdit, error) {
+	if n.Kind != yaml.ScalarNode || n.Line < 1 || n.Line > len(lines) {
+		return patchEdit{}, fmt.Errorf("not a scalar value")
+	}
+	// columns are counted in characters, not bytes
+	start := lines[n.Line-1]
+	for i := 1; i < n.Column && start < len(data); i++ {
+		_, size := utf8.DecodeRune(data[start:])
+		start += size
+	}
+	if start >= len(data) {
+		return patchEdit{}, fmt.Errorf("value could not be located")
+	}
+
+	var e patchEdit
+	switch n.Style {
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		quote := data[start]
+		end := bytes.IndexAny(data[start+1:], string(quote)+"\n")
+		if end < 0 || data[start+1+end] != quote {
+			return patchEdit{}, fmt.Errorf("unterminated or multi-line value")
+		}
+		end += start + 1
+		if string(data[start+1:end]) != n.Value {
+			return patchEdit{}, fmt.Errorf("value is escaped")
+		}
+		e = patchEdit{start, end + 1, string(quote) + value + string(quote)}
+	case 0:
+		// float literals never contain any of these
+		end := start
+		for end < len(data) && bytes.IndexByte([]byte(" \t\r\n,]}#"), data[end]) < 0 {
+			end++
+		}
+		if string(data[start:end]) != n.Value {
+			return patchEdit{}, fmt.Errorf("value could not be located")
+		}
+		e = patchEdit{start, end, value}
+	default:
+		return patchEdit{}, fmt.Errorf("unsupported value style")
+	}
+	return e, nil
+}
+
+// PatchFile changes parameter values in an existing config file,
+// rewriting only the affected values and preserving everything
+// else (comments, formatting, key order) byte for byte. Only
+// parameters that are already present in the file and have a
+// literal value can be patched; values shared via anchors or merge
+// keys are refused, since changing them would affect other values
+// too. File is replaced atomically, and it is left untouched if
+// any of the changes can't be made.
+func PatchFile(path string, changes []ParamChange) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read config: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read config: %v", err)
+	}
+	raw, sources, err := parseConfig(path, data, nil)
+	if err != nil {
+		return err
+	}
+	h := sourceHost(raw, sources.plugins)
+	lines := lineStarts(data)
+
+	// later changes to the same parameter override earlier ones
+	edits := make(map[int]patchEdit)
+	for _, ch := range changes {
+		i, err := h.findPluginIndex(ch.Plugin)
+		if err != nil {
+			return fmt.Errorf("Failed to patch config: %v", err)
+		}
+		ref, ok := h.Plugins[i].source.params[ch.Param]
+		if !ok {
+			return fmt.Errorf("Failed to patch config: parameter '%v.%v' not found", ch.Plugin, ch.Param)
+		}
+		if ref.shared {
+			return fmt.Errorf("Failed to patch config: parameter '%v.%v' is defined through an anchor or merge key", ch.Plugin, ch.Param)
+		}
+		if _, err := strconv.ParseFloat(ref.node.Value, 32); err != nil {
+			return fmt.Errorf("Failed to patch config: parameter '%v.%v' is an expression", ch.Plugin, ch.Param)
+		}
+		e, err := scalarEdit(data, lines, ref.node, formatFloat(ch.Value))
+		if err != nil {
+			return fmt.Errorf("Failed to patch config: parameter '%v.%v': %v", ch.Plugin, ch.Param, err)
+		}
+		edits[e.start] = e
+	}
+
+	// apply edits back to front, so that offsets stay valid
+	sorted := make([]patchEdit, 0, len(edits))
+	for _, e := range edits {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].start > sorted[b].start })
+	patched := append([]byte(nil), data...)
+	for _, e := range sorted {
+		patched = append(patched[:e.start], append([]byte(e.text), patched[e.end:]...)...)
+	}
+
+	// make sure the result still parses to what we expect
+	raw, sources, err = parseConfig(path, patched, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to patch config: %v", err)
+	}
+	h = sourceHost(raw, sources.plugins)
+	for _, ch := range changes {
+		i, err := h.findPluginIndex(ch.Plugin)
+		if err != nil {
+			return fmt.Errorf("Failed to patch config: %v", err)
+		}
+		ref, ok := h.Plugins[i].source.params[ch.Param]
+		if !ok || ref.node.Value != formatFloat(ch.Value) {
+			return fmt.Errorf("Failed to patch config: parameter '%v.%v' was not patched correctly", ch.Plugin, ch.Param)
+		}
+	}
+
+	return writeFileAtomic(path, patched, info.Mode().Perm())
+}
+
+// writeFileAtomic replaces file contents atomically, by writing a
+// temporary file in the same directory and renaming it
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to write config: %v", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, file)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write config: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ActionKind is the kind of action in apply plan
+type ActionKind int
+
+// kinds of actions in apply plan
+const (
+	ActionInstantiate ActionKind = iota
+	ActionSetParam
+	ActionConnect
+	ActionActivate
+)
+
+func (k ActionKind) String() string {
+	switch k {
+	case ActionInstantiate:
+		return "instantiate"
+	case ActionSetParam:
+		return "set"
+	case ActionConnect:
+		return "connect"
+	case ActionActivate:
+		return "activate"
+	}
+	return fmt.Sprintf("ActionKind(%d)", int(k))
+}
+
+// PlanAction is a single step of bringing up the plugin chain.
+// Plugin is index of plugin in the chain. URI is set for
+// instantiation, Symbol and Value for setting parameters, and To
+// (index of downstream plugin) for connections. Connections made
+// by the connections section also have Symbol and ToSymbol set to
+// the ports being connected, and either end may be SystemIndex.
+// Chain is name of the named chain plugins are in, and is empty for
+// Plugins, and plugin indices are positions in that chain.
+type PlanAction struct {
+	Kind     ActionKind
+	Chain    string
+	Plugin   int
+	URI      string
+	Symbol   string
+	Value    float32
+	To       int
+	ToSymbol string
+}
+
+func (a PlanAction) String() string {
+	switch a.Kind {
+	case ActionInstantiate:
+		return fmt.Sprintf("instantiate %v (%v)", a.planIndex(a.Plugin), a.URI)
+	case ActionSetParam:
+		return fmt.Sprintf("set %v.%v = %v", a.planIndex(a.Plugin), a.Symbol, a.Value)
+	case ActionConnect:
+		if a.Symbol != "" || a.ToSymbol != "" {
+			return fmt.Sprintf("connect %v:%v -> %v:%v", a.planIndex(a.Plugin), a.Symbol, a.planIndex(a.To), a.ToSymbol)
+		}
+		return fmt.Sprintf("connect %v -> %v", a.planIndex(a.Plugin), a.planIndex(a.To))
+	}
+	return fmt.Sprintf("%v %v", a.Kind, a.planIndex(a.Plugin))
+}
+
+// planIndex formats plugin index of an action, prefixed with name
+// of the named chain of the action
+func (a PlanAction) planIndex(i int) string {
+	if i == SystemIndex {
+		return SystemPlugin
+	}
+	if a.Chain != "" {
+		return fmt.Sprintf("%v/%v", a.Chain, i)
+	}
+	return fmt.Sprint(i)
+}
+
+// instantiated checks if host should instantiate the plugin, which
+// is not the case for missing plugins that are skipped or bypassed
+func (pc *LV2PluginConfig) instantiated() bool {
+	if pc.Substitution == nil {
+		return true
+	}
+	return pc.Substitution.Action == MissingFallback
+}
+
+// ApplyPlan returns the order in which the host should bring up the
+// plugin chain of an evaluated config: all plugins are instantiated
+// and their parameters are set first (in chain order), then plugins
+// are connected in chain order, and finally they are activated in
+// order of their start offsets (keeping chain order for plugins with
+// the same offset). Sample rate is needed to compare offsets in
+// different units. Plugins skipped or bypassed by ResolveMissing are
+// left out, and their neighbours are connected to each other. If the
+// config has a connections section, plugins are connected as it
+// says instead, in the order connections are listed, and connections
+// of plugins that are left out are dropped. Named chains, which have
+// no connections section, are planned the same way after Plugins,
+// in order of chain names.
+func (c *LV2HostConfig) ApplyPlan(sampleRate float32) ([]PlanAction, error) {
+	if c.EvaluatedAt.IsZero() {
+		return nil, fmt.Errorf("Config has not been evaluated")
+	}
+	g, err := c.Graph()
+	if err != nil {
+		return nil, err
+	}
+	var edges []GraphEdge
+	if c.Connections != nil {
+		edges = g.Edges
+	}
+	plan := planChain("", c.Plugins, edges, sampleRate)
+	for _, name := range c.ChainNames() {
+		plan = append(plan, planChain(name, c.Chains[name], nil, sampleRate)...)
+	}
+	return plan, nil
+}
+
+// planChain returns apply plan of a chain, which is connected as
+// edges say, or in chain order if edges are nil
+func planChain(chain string, pcs []LV2PluginConfig, edges []GraphEdge, sampleRate float32) []PlanAction {
+	plan := make([]PlanAction, 0)
+	active := make([]int, 0, len(pcs))
+	for i := range pcs {
+		pc := &pcs[i]
+		if !pc.instantiated() {
+			continue
+		}
+		active = append(active, i)
+		plan = append(plan, PlanAction{Kind: ActionInstantiate, Chain: chain, Plugin: i, URI: pc.PluginURI})
+		for _, symbol := range sortedKeys(pc.Data) {
+			plan = append(plan, PlanAction{Kind: ActionSetParam, Chain: chain, Plugin: i, Symbol: symbol, Value: pc.Data[symbol]})
+		}
+	}
+	if edges == nil {
+		for j := 1; j < len(active); j++ {
+			plan = append(plan, PlanAction{Kind: ActionConnect, Chain: chain, Plugin: active[j-1], To: active[j]})
+		}
+	} else {
+		for _, e := range edges {
+			if (e.From != SystemIndex && !pcs[e.From].instantiated()) ||
+				(e.To != SystemIndex && !pcs[e.To].instantiated()) {
+				continue
+			}
+			plan = append(plan, PlanAction{Kind: ActionConnect, Chain: chain, Plugin: e.From, Symbol: e.FromPort, To: e.To, ToSymbol: e.ToPort})
+		}
+	}
+
+	order := append([]int(nil), active...)
+	sort.SliceStable(order, func(a, b int) bool {
+		return pcs[order[a]].StartOffsetSamples(sampleRate) < pcs[order[b]].StartOffsetSamples(sampleRate)
+	})
+	for _, i := range order {
+		plan = append(plan, PlanAction{Kind: ActionActivate, Chain: chain, Plugin: i})
+	}
+	return plan
+}
@@ -0,0 +1,102 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+)
+
+// checkPosition checks that i is a valid position in a chain of n
+// plugins
+func checkPosition(i, n int) error {
+	if i < 0 || i >= n {
+		return fmt.Errorf("Plugin index %v is out of range [0, %v)", i, n)
+	}
+	return nil
+}
+
+// MovePlugin moves plugin at position i of the chain to position j,
+// shifting plugins in between by one. Order of the chain is the
+// order plugins are serialized in, and connected in unless there
+// are explicit connections, which keep referring to the same
+// plugins. Note that instance IDs of plugins identified by URI may
+// change.
+func (c *LV2HostConfig) MovePlugin(i, j int) error {
+	if err := checkPosition(i, len(c.Plugins)); err != nil {
+		return err
+	}
+	if err := checkPosition(j, len(c.Plugins)); err != nil {
+		return err
+	}
+	c.rearrange(func() {
+		pc := c.Plugins[i]
+		if i < j {
+			copy(c.Plugins[i:j], c.Plugins[i+1:j+1])
+		} else {
+			copy(c.Plugins[j+1:i+1], c.Plugins[j:i])
+		}
+		c.Plugins[j] = pc
+	}, func(k int) int {
+		switch {
+		case k == i:
+			return j
+		case i < j && k > i && k <= j:
+			return k - 1
+		case j < i && k >= j && k < i:
+			return k + 1
+		}
+		return k
+	})
+	return nil
+}
+
+// InsertPlugin inserts a copy of plugin config at position i of the
+// chain, so that it precedes the plugin that was there. Position
+// equal to chain length appends the plugin. Name and UUID of the
+// plugin, if set, must not be used by other plugins of the chain.
+// The config must be re-evaluated for values of inserted plugin to
+// be resolved.
+func (c *LV2HostConfig) InsertPlugin(i int, cfg LV2PluginConfig) error {
+	if err := checkPosition(i, len(c.Plugins)+1); err != nil {
+		return err
+	}
+	for _, pc := range c.Plugins {
+		if cfg.Name != "" && pc.Name == cfg.Name {
+			return fmt.Errorf("Plugin name '%v' is already in use", cfg.Name)
+		}
+		if cfg.UUID != "" && pc.UUID == cfg.UUID {
+			return fmt.Errorf("Plugin UUID '%v' is already in use", cfg.UUID)
+		}
+	}
+	c.rearrange(func() {
+		c.Plugins = append(c.Plugins, LV2PluginConfig{})
+		copy(c.Plugins[i+1:], c.Plugins[i:])
+		c.Plugins[i] = cfg.clone()
+	}, func(k int) int {
+		if k >= i {
+			return k + 1
+		}
+		return k
+	})
+	return nil
+}
+
+// RemovePlugin removes plugin at position i from the chain, along
+// with its connections, and returns it. Expressions of other plugins
+// are left as is.
+func (c *LV2HostConfig) RemovePlugin(i int) (LV2PluginConfig, error) {
+	if err := checkPosition(i, len(c.Plugins)); err != nil {
+		return LV2PluginConfig{}, err
+	}
+	pc := c.Plugins[i]
+	c.rearrange(func() {
+		c.Plugins = append(c.Plugins[:i], c.Plugins[i+1:]...)
+	}, func(k int) int {
+		switch {
+		case k == i:
+			return -1
+		case k > i:
+			return k - 1
+		}
+		return k
+	})
+	return pc, nil
+}
@@ -0,0 +1,77 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+)
+
+// PresetResolver looks up presets of plugins, which are named by
+// preset URI or label. Values of the preset are returned by symbol,
+// formatted the way parameters are.
+type PresetResolver interface {
+	ResolvePreset(pluginURI, preset string) (map[string]string, error)
+}
+
+// PresetResolverFunc allows using a function as preset resolver
+type PresetResolverFunc func(pluginURI, preset string) (map[string]string, error)
+
+// ResolvePreset calls f(pluginURI, preset)
+func (f PresetResolverFunc) ResolvePreset(pluginURI, preset string) (map[string]string, error) {
+	return f(pluginURI, preset)
+}
+
+// FromPreset returns whether value of a parameter came from preset
+// of the plugin, rather than being set in the config
+func (pc *LV2PluginConfig) FromPreset(symbol string) bool {
+	v, ok := pc.PresetValues[symbol]
+	return ok && pc.DataFmt[symbol] == v
+}
+
+// applyPreset replaces values that came from the previous preset
+// with values, keeping parameters set in the config as overrides
+func (pc *LV2PluginConfig) applyPreset(values map[string]string) {
+	for symbol := range pc.PresetValues {
+		if pc.FromPreset(symbol) {
+			delete(pc.DataFmt, symbol)
+		}
+	}
+	pc.PresetValues = nil
+	for _, symbol := range sortedKeys(values) {
+		if _, ok := pc.DataFmt[symbol]; ok {
+			continue
+		}
+		if pc.PresetValues == nil {
+			pc.PresetValues = make(map[string]string)
+		}
+		pc.DataFmt[symbol] = values[symbol]
+		pc.PresetValues[symbol] = values[symbol]
+	}
+}
+
+// ResolvePresets looks up presets of plugins that have one, and
+// uses values of the preset for parameters that are not set in the
+// config, so that parameters of the config act as overrides on top
+// of the preset. Values that came from a preset are recorded in
+// PresetValues of the plugin (see FromPreset), and are not saved
+// with the config, since they are looked up again when the config
+// is read. Resolving is atomic: if any preset fails to resolve, the
+// config is left as it was. The values are not evaluated until
+// Evaluate is called.
+func (c *LV2HostConfig) ResolvePresets(r PresetResolver) error {
+	// resolving should be atomic, so operate on a copy
+	pcs := make([]LV2PluginConfig, 0, len(c.Plugins))
+	for i := range c.Plugins {
+		pc := c.Plugins[i].clone()
+		var values map[string]string
+		if pc.Preset != "" {
+			var err error
+			values, err = r.ResolvePreset(pc.PluginURI, pc.Preset)
+			if err != nil {
+				return fmt.Errorf("Failed to resolve preset '%v' of plugin '%v': %v", pc.Preset, c.InstanceID(i), err)
+			}
+		}
+		pc.applyPreset(values)
+		pcs = append(pcs, pc)
+	}
+	c.Plugins = pcs
+	return nil
+}
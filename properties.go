@@ -0,0 +1,71 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"os"
+)
+
+// expandProperty expands ${variable} references in a string
+// property. Numbers are formatted the same way literal values are,
+// and $$ stands for a single dollar sign.
+func expandProperty(value string, vars map[string]interface{}) (string, error) {
+	var err error
+	res := os.Expand(value, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		v, ok := vars[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("Unknown variable '%v' in property '%v'", name, value)
+			}
+			return ""
+		}
+		switch v := v.(type) {
+		case string:
+			return v
+		case float32:
+			return formatFloat(v)
+		case float64:
+			return formatFloat(float32(v))
+		}
+		return fmt.Sprint(v)
+	})
+	return res, err
+}
+
+// evaluateProperties expands string properties of a plugin config
+func (pc *LV2PluginConfig) evaluateProperties(vars map[string]interface{}) error {
+	if len(pc.PropertiesFmt) == 0 {
+		pc.Properties = nil
+		return nil
+	}
+	pc.Properties = make(map[string]string)
+	for _, k := range sortedKeys(pc.PropertiesFmt) {
+		v, err := expandProperty(pc.PropertiesFmt[k], vars)
+		if err != nil {
+			return pc.valueError(k, pc.PropertiesFmt[k], err)
+		}
+		pc.Properties[k] = v
+	}
+	return nil
+}
+
+// renameProperty renames variables referenced by a string property
+func renameProperty(value string, rename func(string) (string, bool)) string {
+	renamed := false
+	res := os.Expand(value, func(name string) string {
+		if name == "$" {
+			return "$$"
+		}
+		if n, ok := rename(name); ok {
+			name = n
+			renamed = true
+		}
+		return "${" + name + "}"
+	})
+	if !renamed {
+		return value
+	}
+	return res
+}
@@ -0,0 +1,84 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"time"
+)
+
+// ValueProvider supplies values for expression evaluation from
+// a live source, such as JACK sample rate or current tempo.
+// Providers are consulted on every evaluation, and their values
+// take precedence over those in ValueMap.
+type ValueProvider interface {
+	Values() (map[string]interface{}, error)
+}
+
+// Clock is a source of time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// now returns current time according to config's clock
+func (c *LV2HostConfig) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
+
+// AddProvider registers a value provider with the config
+func (c *LV2HostConfig) AddProvider(p ValueProvider) {
+	c.Providers = append(c.Providers, p)
+}
+
+// evalValues builds a set of values to evaluate expressions
+// with, including parameters other parameters refer to. ValueMap
+// itself is never modified.
+func (c *LV2HostConfig) evalValues() (map[string]interface{}, error) {
+	vars, err := c.baseValues()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.resolveReferences(vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// baseValues builds a set of values that don't depend on any
+// parameters
+func (c *LV2HostConfig) baseValues() (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	for k, v := range c.Constants {
+		vars[k] = v
+	}
+	if _, ok := vars["MINUS_INF_DB"]; ok && c.SilenceFloor != 0 {
+		vars["MINUS_INF_DB"] = float64(c.SilenceFloor)
+	}
+	for k, v := range c.ValueMap {
+		vars[k] = v
+	}
+	tempo, err := c.tempoValues()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range tempo {
+		vars[k] = v
+	}
+	for _, p := range c.Providers {
+		values, err := p.Values()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get provider values: %v", err)
+		}
+		for k, v := range values {
+			vars[k] = v
+		}
+	}
+	return vars, nil
+}
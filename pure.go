@@ -0,0 +1,129 @@
+package lv2hostconfig
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/Knetic/govaluate"
+)
+
+// RawConfig is a parsed, but not yet evaluated config. It is never
+// modified once parsed, so it can be loaded and resolved any number
+// of times, from any number of goroutines.
+type RawConfig struct {
+	raw     *lv2HostRaw
+	sources *configSource
+	opts    ParseOptions
+}
+
+// ParseOptions say how a config is parsed. File is the name errors
+// are reported with, Format is format of the config (YAML, which
+// includes JSON, by default), and Fields and Strict work the way
+// fields of LV2HostConfig with the same names do.
+type ParseOptions struct {
+	File   string
+	Format Format
+	Fields FieldMap
+	Strict bool
+}
+
+// Env is what resolving a config depends on, apart from the config
+// itself. Values are added to the value map and Functions are added
+// to the standard functions, overriding them if names are the same.
+type Env struct {
+	Values    map[string]interface{}
+	Functions map[string]govaluate.ExpressionFunction
+}
+
+// ResolvedConfig is an evaluated config. It is not shared with
+// anything, so it belongs to whoever resolved it.
+type ResolvedConfig struct {
+	*LV2HostConfig
+}
+
+// parse parses a config without touching any LV2HostConfig
+func parse(r io.Reader, opts ParseOptions) (RawConfig, int64, error) {
+	raw, sources, n, err := readConfig(r, opts.File, opts.Fields, opts.Format)
+	if err != nil {
+		return RawConfig{}, n, err
+	}
+	if opts.Strict {
+		if err := checkStrict(raw, sources); err != nil {
+			return RawConfig{}, n, err
+		}
+	}
+	if opts.Fields != nil {
+		fields := make(FieldMap, len(opts.Fields))
+		for k, v := range opts.Fields {
+			fields[k] = v
+		}
+		opts.Fields = fields
+	}
+	return RawConfig{raw, sources, opts}, n, nil
+}
+
+// Parse parses a YAML (or JSON) config, without evaluating it
+func Parse(raw []byte) (RawConfig, error) {
+	return ParseWith(raw, ParseOptions{})
+}
+
+// ParseWith parses a config with given options, without evaluating
+// it, the way ReadFile parses files
+func ParseWith(raw []byte, opts ParseOptions) (RawConfig, error) {
+	rc, _, err := parse(bytes.NewReader(raw), opts)
+	return rc, err
+}
+
+// Resolve evaluates a parsed config in env, returning a new config,
+// which keeps Fields and Strict the config was parsed with, so it
+// is written back the way it was read. Neither rc nor env is
+// modified. This is what reading a file into a new config and
+// evaluating it does. Methods of LV2HostConfig reading configs
+// parse them the way Parse does and load them with Load, while
+// evaluation goes the other way around: Resolve is built on
+// Evaluate, which evaluates configs in place, so that validators,
+// providers and everything else set up on the config are used.
+func Resolve(rc RawConfig, env Env) (ResolvedConfig, error) {
+	c := NewLV2HostConfig()
+	c.Fields = rc.opts.Fields
+	for k, f := range env.Functions {
+		c.FunctionMap[k] = f
+	}
+	for k, v := range env.Values {
+		c.ValueMap[k] = v
+	}
+	if err := c.Load(rc); err != nil {
+		return ResolvedConfig{}, err
+	}
+	// strictness was checked by parsing
+	c.Strict = rc.opts.Strict
+	if err := c.Evaluate(); err != nil {
+		return ResolvedConfig{}, err
+	}
+	return ResolvedConfig{c}, nil
+}
+
+// Env returns a copy of values and functions of the config, to
+// resolve other configs with
+func (c *LV2HostConfig) Env() Env {
+	env := Env{
+		Values:    make(map[string]interface{}, len(c.ValueMap)),
+		Functions: make(map[string]govaluate.ExpressionFunction, len(c.FunctionMap)),
+	}
+	for k, v := range c.ValueMap {
+		env.Values[k] = v
+	}
+	for k, f := range c.FunctionMap {
+		env.Functions[k] = f
+	}
+	return env
+}
+
+// Load replaces contents of the config with a parsed config, just
+// like ReadFile does
+func (c *LV2HostConfig) Load(rc RawConfig) error {
+	if rc.raw == nil {
+		rc.raw = newLV2HostRaw()
+	}
+	return c.fromRaw(rc.raw, rc.sources)
+}
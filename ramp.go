@@ -0,0 +1,80 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Knetic/govaluate"
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// rampFunc makes a ramp function, which maps step index onto a
+// value between start and end, shaping progress with ease
+func rampFunc(name string, ease func(t float64) float64) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 4 {
+			return math.NaN(), fmt.Errorf("Function '%v' expects exactly 4 arguments", name)
+		}
+		var start, end, steps, index float32
+		floatPtrs := []*float32{&start, &end, &steps, &index}
+		for i, arg := range args {
+			v, err := convert.Float32(arg)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("Value '%v' was not a float", arg)
+			}
+			*floatPtrs[i] = v
+		}
+		if steps < 1 || steps != float32(math.Trunc(float64(steps))) {
+			return math.NaN(), fmt.Errorf("Number of steps '%v' is invalid", steps)
+		}
+		if index < 0 || index > steps-1 {
+			return math.NaN(), fmt.Errorf("Index '%v' is not within range '0-%v'", index, steps-1)
+		}
+		if steps == 1 {
+			return float64(start), nil
+		}
+		t := ease(float64(index) / float64(steps-1))
+		return float64(start) + (float64(end)-float64(start))*t, nil
+	}
+}
+
+func setUpRampFuncs(lvc *LV2HostConfig) {
+	lvc.FunctionMap["ramp"] = rampFunc("ramp", func(t float64) float64 {
+		return t
+	})
+	lvc.FunctionMap["ramp_in"] = rampFunc("ramp_in", func(t float64) float64 {
+		return t * t
+	})
+	lvc.FunctionMap["ramp_out"] = rampFunc("ramp_out", func(t float64) float64 {
+		return t * (2 - t)
+	})
+	lvc.FunctionMap["ramp_inout"] = rampFunc("ramp_inout", func(t float64) float64 {
+		return t * t * (3 - 2*t)
+	})
+	// exponential ramp has a constant ratio between steps, which is
+	// what e.g. frequencies or times usually want
+	exp := rampFunc("ramp_exp", func(t float64) float64 { return t })
+	lvc.FunctionMap["ramp_exp"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 4 {
+			return exp(args...)
+		}
+		start, err := convert.Float32(args[0])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
+		}
+		end, err := convert.Float32(args[1])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[1])
+		}
+		if start == 0 || end == 0 || (start < 0) != (end < 0) {
+			return math.NaN(), fmt.Errorf("Exponential ramp from '%v' to '%v' is invalid", start, end)
+		}
+		// interpolate geometrically, using linear ramp for progress
+		t, err := exp(0, 1, args[2], args[3])
+		if err != nil {
+			return math.NaN(), err
+		}
+		ratio := float64(end) / float64(start)
+		return float64(start) * math.Pow(ratio, t.(float64)), nil
+	}
+}
@@ -0,0 +1,455 @@
+package lv2hostconfig
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// Interval is a closed range of values
+type Interval struct {
+	Min float64
+	Max float64
+}
+
+func (i Interval) String() string {
+	if i.Min == i.Max {
+		return fmt.Sprint(i.Min)
+	}
+	return fmt.Sprintf("[%v, %v]", i.Min, i.Max)
+}
+
+// RangeWarning is a parameter whose expression can evaluate to a
+// value outside of range of the parameter. Result is range of values
+// the expression may evaluate to. Always is set if none of them are
+// within range of the parameter.
+type RangeWarning struct {
+	Plugin string
+	Symbol string
+	Expr   string
+	Result Interval
+	Min    float32
+	Max    float32
+	Always bool
+}
+
+func (w RangeWarning) String() string {
+	how := "can be"
+	if w.Always {
+		how = "is always"
+	}
+	return fmt.Sprintf("%v.%v: '%v' %v outside of range [%v, %v] (evaluates to %v)",
+		w.Plugin, w.Symbol, w.Expr, how, w.Min, w.Max, w.Result)
+}
+
+// errNotAnalyzable is returned for expressions range analysis can't
+// handle
+var errNotAnalyzable = errors.New("Expression can't be analyzed")
+
+// value of a subexpression during range analysis, which is either a
+// range of numbers or a string
+type rangeValue struct {
+	Interval
+	str   string
+	isStr bool
+}
+
+func pointValue(v float64) rangeValue {
+	return rangeValue{Interval: Interval{v, v}}
+}
+
+func (v rangeValue) point() bool {
+	return v.isStr || v.Min == v.Max
+}
+
+// cornerFuncs are built-in functions that are monotonic in each of
+// their arguments (for fixed values of others), by number of leading
+// arguments that may vary (-1 for all). Such functions take their
+// extreme values at corners of ranges of their arguments.
+var cornerFuncs = map[string]int{
+	"min":            -1,
+	"max":            -1,
+	"clamp":          -1,
+	"linear":         -1,
+	"decibel":        -1,
+	"sqrt":           -1,
+	"deg":            -1,
+	"rad":            -1,
+	"dbsum":          -1,
+	"gain_to_target": -1,
+	"lufs_gain":      -1,
+	"tp_margin":      -1,
+	"spl_gain":       -1,
+	"delay_ms":       -1,
+	"scale":          1,
+	"midi":           1,
+	"frommidi":       1,
+}
+
+// maxCorners limits number of function calls made for a single
+// function call of an expression
+const maxCorners = 16
+
+// rangeParser computes range of values of an expression, given
+// ranges of its variables
+type rangeParser struct {
+	c    *LV2HostConfig
+	vars func(name string) (rangeValue, error)
+	s    string
+	pos  int
+}
+
+func (p *rangeParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek checks if next token starts with given text
+func (p *rangeParser) peek(text string) bool {
+	p.skipSpace()
+	return strings.HasPrefix(p.s[p.pos:], text)
+}
+
+func (p *rangeParser) accept(text string) bool {
+	if !p.peek(text) {
+		return false
+	}
+	p.pos += len(text)
+	return true
+}
+
+func (p *rangeParser) parse() (rangeValue, error) {
+	v, err := p.sum()
+	if err != nil {
+		return v, err
+	}
+	if p.skipSpace(); p.pos != len(p.s) {
+		return v, errNotAnalyzable
+	}
+	return v, nil
+}
+
+func (p *rangeParser) sum() (rangeValue, error) {
+	v, err := p.product()
+	for err == nil {
+		var w rangeValue
+		switch {
+		case p.accept("+"):
+			if w, err = p.product(); err == nil {
+				v, err = arith('+', v, w)
+			}
+		case p.accept("-"):
+			if w, err = p.product(); err == nil {
+				v, err = arith('-', v, w)
+			}
+		default:
+			return v, nil
+		}
+	}
+	return v, err
+}
+
+func (p *rangeParser) product() (rangeValue, error) {
+	v, err := p.unary()
+	for err == nil {
+		var w rangeValue
+		switch {
+		case p.peek("**"):
+			return v, nil
+		case p.accept("*"):
+			if w, err = p.unary(); err == nil {
+				v, err = arith('*', v, w)
+			}
+		case p.accept("/"):
+			if w, err = p.unary(); err == nil {
+				v, err = arith('/', v, w)
+			}
+		default:
+			return v, nil
+		}
+	}
+	return v, err
+}
+
+func (p *rangeParser) unary() (rangeValue, error) {
+	if p.accept("-") {
+		v, err := p.unary()
+		if err != nil || v.isStr {
+			return v, errNotAnalyzable
+		}
+		return rangeValue{Interval: Interval{-v.Max, -v.Min}}, nil
+	}
+	v, err := p.primary()
+	if err != nil {
+		return v, err
+	}
+	if p.accept("**") {
+		w, err := p.unary()
+		if err != nil {
+			return w, err
+		}
+		return p.call("pow", []rangeValue{v, w})
+	}
+	return v, nil
+}
+
+func (p *rangeParser) primary() (rangeValue, error) {
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return rangeValue{}, errNotAnalyzable
+	}
+	ch := p.s[p.pos]
+	switch {
+	case ch == '(':
+		p.pos++
+		v, err := p.sum()
+		if err == nil && !p.accept(")") {
+			err = errNotAnalyzable
+		}
+		return v, err
+	case ch == '\'' || ch == '"':
+		end := strings.IndexByte(p.s[p.pos+1:], ch)
+		if end < 0 {
+			return rangeValue{}, errNotAnalyzable
+		}
+		str := p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return rangeValue{str: str, isStr: true}, nil
+	case ch == '[':
+		end := strings.IndexByte(p.s[p.pos:], ']')
+		if end < 0 {
+			return rangeValue{}, errNotAnalyzable
+		}
+		name := p.s[p.pos+1 : p.pos+end]
+		p.pos += end + 1
+		return p.vars(name)
+	case ch == '.' || (ch >= '0' && ch <= '9'):
+		start := p.pos
+		for p.pos < len(p.s) && (isNumberChar(p.s[p.pos]) ||
+			((p.s[p.pos] == '+' || p.s[p.pos] == '-') && (p.s[p.pos-1] == 'e' || p.s[p.pos-1] == 'E'))) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return rangeValue{}, errNotAnalyzable
+		}
+		return pointValue(v), nil
+	case ch == '_' || unicode.IsLetter(rune(ch)):
+		start := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] == '_' || unicode.IsLetter(rune(p.s[p.pos])) || unicode.IsDigit(rune(p.s[p.pos]))) {
+			p.pos++
+		}
+		name := p.s[start:p.pos]
+		if !p.accept("(") {
+			return p.vars(name)
+		}
+		args := make([]rangeValue, 0)
+		for !p.accept(")") {
+			if len(args) != 0 && !p.accept(",") {
+				return rangeValue{}, errNotAnalyzable
+			}
+			v, err := p.sum()
+			if err != nil {
+				return v, err
+			}
+			args = append(args, v)
+		}
+		return p.call(name, args)
+	}
+	return rangeValue{}, errNotAnalyzable
+}
+
+func isNumberChar(ch byte) bool {
+	return ch == '.' || ch == 'e' || ch == 'E' || (ch >= '0' && ch <= '9')
+}
+
+// arith applies an arithmetic operator to ranges
+func arith(op byte, a, b rangeValue) (rangeValue, error) {
+	if a.isStr || b.isStr {
+		return rangeValue{}, errNotAnalyzable
+	}
+	var r Interval
+	switch op {
+	case '+':
+		r = Interval{a.Min + b.Min, a.Max + b.Max}
+	case '-':
+		r = Interval{a.Min - b.Max, a.Max - b.Min}
+	case '*', '/':
+		if op == '/' {
+			if b.Min <= 0 && b.Max >= 0 {
+				return rangeValue{}, errNotAnalyzable
+			}
+			b.Interval = Interval{1 / b.Max, 1 / b.Min}
+		}
+		products := []float64{a.Min * b.Min, a.Min * b.Max, a.Max * b.Min, a.Max * b.Max}
+		r = Interval{math.Inf(1), math.Inf(-1)}
+		for _, x := range products {
+			r.Min = math.Min(r.Min, x)
+			r.Max = math.Max(r.Max, x)
+		}
+	}
+	if math.IsNaN(r.Min) || math.IsNaN(r.Max) {
+		return rangeValue{}, errNotAnalyzable
+	}
+	return rangeValue{Interval: r}, nil
+}
+
+// call computes range of a function call
+func (p *rangeParser) call(name string, args []rangeValue) (rangeValue, error) {
+	fn, ok := p.c.FunctionMap[name]
+	if !ok {
+		return rangeValue{}, errNotAnalyzable
+	}
+	varying, n := -1, 0
+	for i, a := range args {
+		if !a.point() {
+			varying = i
+			n++
+		}
+	}
+	if 1<<uint(n) > maxCorners {
+		return rangeValue{}, errNotAnalyzable
+	}
+	if varying >= 0 {
+		switch name {
+		case "abs":
+			a := args[0].Interval
+			switch {
+			case a.Min >= 0:
+				return rangeValue{Interval: a}, nil
+			case a.Max <= 0:
+				return rangeValue{Interval: Interval{-a.Max, -a.Min}}, nil
+			}
+			return rangeValue{Interval: Interval{0, math.Max(-a.Min, a.Max)}}, nil
+		case "pow":
+			// powers of positive numbers are monotonic in both arguments
+			if args[0].Min <= 0 {
+				return rangeValue{}, errNotAnalyzable
+			}
+		default:
+			if lead, ok := cornerFuncs[name]; !ok || (lead >= 0 && varying >= lead) {
+				return rangeValue{}, errNotAnalyzable
+			}
+		}
+	}
+
+	r := Interval{math.Inf(1), math.Inf(-1)}
+	corner := make([]interface{}, len(args))
+	for k := 0; k < 1<<uint(n); k++ {
+		bits := k
+		for i, a := range args {
+			switch {
+			case a.isStr:
+				corner[i] = a.str
+			case a.point():
+				corner[i] = a.Min
+			default:
+				corner[i] = a.Min
+				if bits&1 != 0 {
+					corner[i] = a.Max
+				}
+				bits >>= 1
+			}
+		}
+		res, err := fn(corner...)
+		if err != nil {
+			return rangeValue{}, errNotAnalyzable
+		}
+		if s, ok := res.(string); ok {
+			return rangeValue{str: s, isStr: true}, nil
+		}
+		v, err := convert.Float32(res)
+		if err != nil || math.IsNaN(float64(v)) {
+			return rangeValue{}, errNotAnalyzable
+		}
+		r.Min = math.Min(r.Min, float64(v))
+		r.Max = math.Max(r.Max, float64(v))
+	}
+	return rangeValue{Interval: r}, nil
+}
+
+// AnalyzeRanges finds parameters whose expressions can evaluate to
+// values outside of known ranges of the parameters (see knownRange),
+// given ranges of variables that may change. Other variables keep
+// their current values, and ranges of parameters other parameters
+// refer to are analyzed in order of their dependencies. Analysis is
+// best-effort: it handles arithmetic and built-in functions that are
+// monotonic in their arguments, skipping expressions using anything
+// else (as well as parameters that are part of dependency cycles),
+// and the ranges it computes may be wider than actual ones.
+func (c *LV2HostConfig) AnalyzeRanges(ranges map[string]Interval) ([]RangeWarning, error) {
+	base, err := c.baseValues()
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]rangeValue)
+	vars := func(name string) (rangeValue, error) {
+		if r, ok := ranges[name]; ok {
+			if r.Min > r.Max {
+				return rangeValue{}, errNotAnalyzable
+			}
+			return rangeValue{Interval: r}, nil
+		}
+		if r, ok := refs[name]; ok {
+			return r, nil
+		}
+		v, ok := base[name]
+		if !ok {
+			return rangeValue{}, errNotAnalyzable
+		}
+		if s, ok := v.(string); ok {
+			return rangeValue{str: s, isStr: true}, nil
+		}
+		f, err := convert.Float32(v)
+		if err != nil {
+			return rangeValue{}, errNotAnalyzable
+		}
+		return pointValue(float64(f)), nil
+	}
+
+	g := c.dependencies(base)
+	index := make(map[string]int)
+	for i := range c.Plugins {
+		index[c.InstanceID(i)] = i
+	}
+	warnings := make([]RangeWarning, 0)
+	for _, comp := range g.Components() {
+		r := comp[0]
+		if len(comp) > 1 || g.refersTo(r, r) {
+			continue
+		}
+		pc := &c.Plugins[index[r.Plugin]]
+		value := pc.DataFmt[r.Symbol]
+		var res rangeValue
+		if v, ok := pc.evaluated(r.Symbol); ok && pc.Frozen[r.Symbol] {
+			res = pointValue(float64(v))
+		} else if v, ok := c.enumValue(pc, r.Symbol, value); ok {
+			res = pointValue(float64(v))
+		} else {
+			p := &rangeParser{c: c, vars: vars, s: value}
+			if res, err = p.parse(); err != nil || res.isStr {
+				continue
+			}
+		}
+		for _, name := range g.names[r] {
+			refs[name] = res
+		}
+
+		ports, _ := FactoryPorts(pc.PluginURI)
+		min, max, ok := c.knownRange(pc, r.Symbol, ports)
+		lo, hi := float32(res.Min), float32(res.Max)
+		if !ok || (lo >= min && hi <= max) {
+			continue
+		}
+		warnings = append(warnings, RangeWarning{
+			r.Plugin, r.Symbol, value, res.Interval, min, max, hi < min || lo > max,
+		})
+	}
+	return warnings, nil
+}
@@ -0,0 +1,67 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+)
+
+// policies for evaluated values outside of known parameter ranges
+const (
+	AllowAll          = "allow"
+	ClampToRange      = "clamp"
+	ErrorOnOutOfRange = "error"
+)
+
+// rangePorts returns factory defaults database entry for a plugin,
+// if range policy needs it
+func (c *LV2HostConfig) rangePorts(pc *LV2PluginConfig) map[string]FactoryPort {
+	if c.RangePolicy == "" || c.RangePolicy == AllowAll {
+		return nil
+	}
+	ports, _ := FactoryPorts(pc.PluginURI)
+	return ports
+}
+
+// knownRange returns range of a parameter, looking it up in plugin
+// config, schemas and factory defaults database, in that order
+func (c *LV2HostConfig) knownRange(pc *LV2PluginConfig, symbol string, ports map[string]FactoryPort) (float32, float32, bool) {
+	if r, ok := pc.Ranges[symbol]; ok {
+		return r.Min, r.Max, true
+	}
+	if ps, ok := c.Schemas[pc.PluginURI][symbol]; ok && ps.Min < ps.Max {
+		return ps.Min, ps.Max, true
+	}
+	if fp, ok := ports[symbol]; ok && fp.Min < fp.Max {
+		return fp.Min, fp.Max, true
+	}
+	return 0, 0, false
+}
+
+// checkRange applies range policy to an evaluated value. Values
+// are allowed by default, clamped to range with ClampToRange, and
+// rejected with ErrorOnOutOfRange. NaN is not within any range,
+// nor can it be clamped to one, so it is rejected whatever the
+// policy.
+func (c *LV2HostConfig) checkRange(pc *LV2PluginConfig, symbol string, v float32, ports map[string]FactoryPort) (float32, error) {
+	if math.IsNaN(float64(v)) {
+		return v, fmt.Errorf("Value is not a number")
+	}
+	switch c.RangePolicy {
+	case "", AllowAll:
+		return v, nil
+	case ClampToRange, ErrorOnOutOfRange:
+	default:
+		return v, fmt.Errorf("Unknown range policy '%v'", c.RangePolicy)
+	}
+	min, max, ok := c.knownRange(pc, symbol, ports)
+	if !ok || (v >= min && v <= max) {
+		return v, nil
+	}
+	if c.RangePolicy == ErrorOnOutOfRange {
+		return v, fmt.Errorf("Value '%v' is not within range '%v-%v'", v, min, max)
+	}
+	if v < min {
+		return min, nil
+	}
+	return max, nil
+}
@@ -0,0 +1,26 @@
+package lv2hostconfig_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+func TestRangePolicyRejectsNaN(t *testing.T) {
+	for _, policy := range []string{"", lv2hostconfig.AllowAll, lv2hostconfig.ClampToRange, lv2hostconfig.ErrorOnOutOfRange} {
+		t.Run(policy, func(t *testing.T) {
+			c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    ranges:\n      gain: {min: -12, max: 12}\n    parameters:\n      gain: 0 / 0\n      freq: 100\n")
+			c.RangePolicy = policy
+			err := c.Evaluate()
+			var ce *lv2hostconfig.ConfigError
+			if !errors.As(err, &ce) {
+				t.Fatalf("Evaluating NaN returned '%v', want a ConfigError", err)
+			}
+			if ce.Param != "gain" {
+				t.Errorf("Error is about parameter '%v', want 'gain'", ce.Param)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package lv2hostconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Source is where configs are loaded from and watched for changes,
+// such as a file or a key in a key-value store. Revisions are
+// opaque strings that change whenever contents do.
+type Source interface {
+	// Load returns current contents and their revision
+	Load(ctx context.Context) ([]byte, string, error)
+	// Wait blocks until revision differs from rev, or ctx is done
+	Wait(ctx context.Context, rev string) error
+}
+
+// KVStore is a key-value store configs can be kept in, such as
+// etcd or Consul. Implementations are thin adapters over clients
+// of the store: revision is etcd's mod revision or Consul's modify
+// index, and WaitChange maps onto an etcd watch or a Consul
+// blocking query.
+type KVStore interface {
+	Get(ctx context.Context, key string) ([]byte, string, error)
+	WaitChange(ctx context.Context, key, rev string) error
+}
+
+// KVSource is a config kept under a key in a key-value store
+type KVSource struct {
+	Store KVStore
+	Key   string
+}
+
+// Load implements Source
+func (s *KVSource) Load(ctx context.Context) ([]byte, string, error) {
+	data, rev, err := s.Store.Get(ctx, s.Key)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to read config from key '%v': %v", s.Key, err)
+	}
+	return data, rev, nil
+}
+
+// Wait implements Source
+func (s *KVSource) Wait(ctx context.Context, rev string) error {
+	return s.Store.WaitChange(ctx, s.Key, rev)
+}
+
+// DefaultPollInterval is how often FileSource checks for changes
+// by default
+const DefaultPollInterval = time.Second
+
+// DefaultDebounce is how long Watch waits for a changed file to
+// settle before reloading it
+const DefaultDebounce = 200 * time.Millisecond
+
+// FileSource is a config file, which is polled for changes.
+// Revision is file's modification time and size. If Debounce is
+// set, a change is only reported once the file stays unchanged for
+// that long, so that files written in several steps (as some
+// editors do) are not loaded half-written. Quiet period is measured
+// with Clock, or system clock if it is nil.
+type FileSource struct {
+	Path     string
+	Interval time.Duration
+	Debounce time.Duration
+	Clock    Clock
+}
+
+func (s *FileSource) now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
+
+func (s *FileSource) revision() (string, error) {
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v:%v", fi.ModTime().UnixNano(), fi.Size()), nil
+}
+
+// Load implements Source
+func (s *FileSource) Load(ctx context.Context) ([]byte, string, error) {
+	rev, err := s.revision()
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to read config: %v", err)
+	}
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to read config: %v", err)
+	}
+	return data, rev, nil
+}
+
+// Wait implements Source
+func (s *FileSource) Wait(ctx context.Context, rev string) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		// files that are being replaced may be briefly missing
+		if cur, err := s.revision(); err == nil && cur != rev {
+			return s.settle(ctx, cur)
+		}
+	}
+}
+
+// settle waits until revision of the file stops changing for
+// Debounce
+func (s *FileSource) settle(ctx context.Context, rev string) error {
+	if s.Debounce <= 0 {
+		return nil
+	}
+	changed := s.now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.Debounce):
+		}
+		cur, err := s.revision()
+		if err != nil || cur != rev {
+			rev = cur
+			changed = s.now()
+			continue
+		}
+		if s.now().Sub(changed) >= s.Debounce {
+			return nil
+		}
+	}
+}
+
+// ReadSource reads config in given format from a source. Just like
+// ReadFile, values are not evaluated until Evaluate is called.
+// Returns revision of the config that was read.
+func (c *LV2HostConfig) ReadSource(ctx context.Context, src Source, format Format) (string, error) {
+	data, rev, err := src.Load(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := c.ReadFromFormat(bytes.NewReader(data), format); err != nil {
+		return "", err
+	}
+	return rev, nil
+}
+
+// WatchSource loads config from a source whenever it changes, until
+// ctx is done. Every revision is read into a clone of c (so that it
+// keeps functions, validators and so on) and evaluated, and fn is
+// called with the result, or with an error if the revision could
+// not be loaded, in which case the host should keep using the last
+// good config. c itself is never modified. The first revision is
+// loaded right away. Returns ctx.Err() when ctx is done.
+func (c *LV2HostConfig) WatchSource(ctx context.Context, src Source, format Format, fn func(*LV2HostConfig, error)) error {
+	return c.WatchSourceWith(ctx, src, format, ReloadPolicy{}, fn)
+}
+
+// WatchSourceWith works like WatchSource, but rate limits reloads
+// according to policy (see ReloadLimiter), measuring time with
+// Clock of c: revisions are loaded as soon as they appear, but only
+// applied (read, evaluated and passed to fn) once Debounce passed
+// without further changes, and no sooner than MinInterval after
+// the previous apply. The first revision is applied right away. fn
+// is never called concurrently with itself, nor after return.
+func (c *LV2HostConfig) WatchSourceWith(ctx context.Context, src Source, format Format, policy ReloadPolicy, fn func(*LV2HostConfig, error)) error {
+	var fnMu sync.Mutex
+	call := func(nc *LV2HostConfig, err error) {
+		fnMu.Lock()
+		defer fnMu.Unlock()
+		fn(nc, err)
+	}
+	var mu sync.Mutex
+	var latest []byte
+	l := NewReloadLimiter(policy, func() {
+		mu.Lock()
+		data := latest
+		mu.Unlock()
+		c.applyRevision(data, format, call)
+	})
+	l.Clock = c.Clock
+	defer l.Stop()
+
+	first := true
+	for {
+		data, rev, err := src.Load(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			call(nil, err)
+			// don't spin on a source that keeps failing
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(DefaultPollInterval):
+			}
+			continue
+		}
+		mu.Lock()
+		latest = data
+		mu.Unlock()
+		if first {
+			l.fire()
+			first = false
+		} else {
+			l.Trigger()
+		}
+		if err := src.Wait(ctx, rev); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			call(nil, err)
+		}
+	}
+}
+
+// applyRevision reads and evaluates a revision loaded from a source
+// in a clone of c, and passes the result to fn
+func (c *LV2HostConfig) applyRevision(data []byte, format Format, fn func(*LV2HostConfig, error)) {
+	nc := c.Clone()
+	err := nc.ReadFromFormat(bytes.NewReader(data), format)
+	if err == nil {
+		err = nc.Evaluate()
+	}
+	if err != nil {
+		// broken revision, wait for it to be fixed
+		fn(nil, err)
+	} else {
+		fn(nc, nil)
+	}
+}
+
+// Watch watches a config file, re-reading and re-evaluating it
+// whenever it changes, until ctx is done. It works like WatchSource
+// with a NotifySource, debounced by DefaultDebounce, and format is
+// guessed from file extension the way ReadFile does it. onChange is
+// called with nil and an error if the file is broken, so a broken
+// file never replaces the last good config. Changes are picked up
+// through file system notifications (see NotifySource), falling
+// back to polling where those are not available.
+func (c *LV2HostConfig) Watch(ctx context.Context, path string, onChange func(*LV2HostConfig, error)) error {
+	return c.WatchWith(ctx, path, ReloadPolicy{Debounce: DefaultDebounce}, onChange)
+}
+
+// WatchWith works like Watch, but rate limits reloads according to
+// policy, the way WatchSourceWith does it. Debounce is how long the
+// file must stay unchanged for before it is loaded, according to
+// Clock of c.
+func (c *LV2HostConfig) WatchWith(ctx context.Context, path string, policy ReloadPolicy, onChange func(*LV2HostConfig, error)) error {
+	src := &NotifySource{FileSource{Path: path, Debounce: policy.Debounce, Clock: c.Clock}}
+	policy.Debounce = 0
+	return c.WatchSourceWith(ctx, src, FormatOf(path), policy, onChange)
+}
@@ -0,0 +1,68 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"io"
+)
+
+// displayName returns human-readable identifier of plugin
+func (pc *LV2PluginConfig) displayName() string {
+	if pc.Name != "" {
+		return pc.Name
+	}
+	return pc.PluginURI
+}
+
+// chainPlugins returns plugins of a named chain, or Plugins if name
+// is empty
+func (c *LV2HostConfig) chainPlugins(name string) []LV2PluginConfig {
+	if name == "" {
+		return c.Plugins
+	}
+	return c.Chains[name]
+}
+
+// ChangeReport writes a human-readable summary of differences
+// between evaluated values of two configs, one line per change:
+//
+//	compressor.threshold: -18 → -20
+//
+// Plugins of named chains are prefixed with chain name, as in
+// "vocals/compressor.threshold".
+// The report is suitable for commit messages, emails, or
+// confirmation dialogs.
+func ChangeReport(old, new *LV2HostConfig, w io.Writer) error {
+	diffs := old.Compare(new, 0)
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+	for _, d := range diffs {
+		name := func(c *LV2HostConfig) string {
+			pc := &c.chainPlugins(d.Chain)[d.Plugin]
+			if d.Chain != "" {
+				return d.Chain + "/" + pc.displayName()
+			}
+			return pc.displayName()
+		}
+		var line string
+		switch d.Kind {
+		case DiffPluginAdded:
+			line = fmt.Sprintf("%v: added (%v)", name(new), d.URI)
+		case DiffPluginRemoved:
+			line = fmt.Sprintf("%v: removed", name(old))
+		case DiffPluginURI:
+			line = fmt.Sprintf("%v: replaced with %v", name(old), d.URI)
+		case DiffParamAdded:
+			line = fmt.Sprintf("%v.%v: added (%v)", name(new), d.Symbol, formatFloat(d.New))
+		case DiffParamRemoved:
+			line = fmt.Sprintf("%v.%v: removed (was %v)", name(old), d.Symbol, formatFloat(d.Old))
+		case DiffParamValue:
+			line = fmt.Sprintf("%v.%v: %v → %v", name(new), d.Symbol, formatFloat(d.Old), formatFloat(d.New))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResourceConflict is an exclusive resource required by more
+// than one plugin. Plugins are indices of plugins in the chain.
+type ResourceConflict struct {
+	Resource string
+	Plugins  []int
+}
+
+// ResourceConflictError is returned when plugins in the chain
+// require the same exclusive resources
+type ResourceConflictError struct {
+	Conflicts []ResourceConflict
+}
+
+func (e *ResourceConflictError) Error() string {
+	msgs := make([]string, 0, len(e.Conflicts))
+	for _, rc := range e.Conflicts {
+		msgs = append(msgs, fmt.Sprintf("resource '%v' is required by plugins %v", rc.Resource, rc.Plugins))
+	}
+	return fmt.Sprintf("Resource conflicts: %v", strings.Join(msgs, "; "))
+}
+
+// ResourceConflicts finds all exclusive resources required by
+// more than one plugin in the chain
+func (c *LV2HostConfig) ResourceConflicts() []ResourceConflict {
+	users := make(map[string][]int)
+	for i, pc := range c.Plugins {
+		for _, r := range pc.Resources {
+			// plugin listing the same resource twice is not a conflict
+			if l := users[r]; len(l) != 0 && l[len(l)-1] == i {
+				continue
+			}
+			users[r] = append(users[r], i)
+		}
+	}
+	conflicts := make([]ResourceConflict, 0)
+	for r, plugins := range users {
+		if len(plugins) > 1 {
+			conflicts = append(conflicts, ResourceConflict{r, plugins})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Resource < conflicts[j].Resource
+	})
+	return conflicts
+}
+
+// CheckResources returns an error if any exclusive resources are
+// required by more than one plugin in the chain
+func (c *LV2HostConfig) CheckResources() error {
+	if conflicts := c.ResourceConflicts(); len(conflicts) != 0 {
+		return &ResourceConflictError{conflicts}
+	}
+	return nil
+}
+
+// ResourceValidator returns a validator vetoing configs with
+// resource conflicts, to be used with AddValidator
+func ResourceValidator() Validator {
+	return ValidatorFunc(func(c *LV2HostConfig) ([]ValidationMessage, error) {
+		msgs := make([]ValidationMessage, 0)
+		for _, rc := range c.ResourceConflicts() {
+			for _, i := range rc.Plugins {
+				msgs = append(msgs, ValidationMessage{
+					c.Plugins[i].displayName(),
+					"",
+					fmt.Sprintf("exclusive resource '%v' is also required by other plugins", rc.Resource),
+				})
+			}
+		}
+		return msgs, nil
+	})
+}
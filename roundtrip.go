@@ -0,0 +1,108 @@
+package lv2hostconfig
+
+import (
+	"strconv"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Bake replaces formatted values of all evaluated parameters, CV
+// ports and start offsets (of Plugins and named chains) with their
+// evaluated values, so that the
+// config can be saved as a snapshot that doesn't depend on any
+// variables, functions or providers. Values are formatted so that
+// they parse back to exactly the same float32, including negative
+// zero, subnormals and infinities. Overrides are never persisted,
+// so overridden parameters are baked with the value they were
+// evaluated to. Values that failed to evaluate are left as is.
+func (c *LV2HostConfig) Bake() {
+	// shortest representation that parses back to the same value,
+	// in exponent form for very large and very small values
+	format := func(v float32) string {
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	}
+	c.eachPlugin(func(pc *LV2PluginConfig) {
+		for symbol := range pc.Data {
+			if v, ok := pc.evaluated(symbol); ok {
+				pc.DataFmt[symbol] = format(v)
+			}
+		}
+		for port, v := range pc.CV {
+			if cv, ok := pc.CVFmt[port]; ok {
+				cv.Value = format(v)
+				pc.CVFmt[port] = cv
+			}
+		}
+		if pc.StartOffsetFmt != "" {
+			pc.StartOffsetFmt = format(pc.StartOffset)
+		}
+	})
+}
+
+// numericTag returns the tag YAML would resolve value to when
+// written as a plain scalar, if that is a number
+func numericTag(value string) (string, bool) {
+	if _, err := strconv.ParseFloat(value, 32); err != nil {
+		if ne, ok := err.(*strconv.NumError); !ok || ne.Err != strconv.ErrRange {
+			return "", false
+		}
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil || len(doc.Content) == 0 {
+		return "", false
+	}
+	n := doc.Content[0]
+	if n.Kind != yaml.ScalarNode || n.Value != value || (n.Tag != "!!int" && n.Tag != "!!float") {
+		return "", false
+	}
+	return n.Tag, true
+}
+
+// restoreLiterals makes numeric parameter values of an encoded
+// config look the way they did in the source file, so that literal
+// values are written back byte for byte. Parameters that were not
+// read from a file are written as plain numbers, if they are.
+func restoreLiterals(top *yaml.Node, plugins []*pluginSource, chains map[string][]*pluginSource) {
+	keys := keyNodes(top)
+	if items, ok := keys["plugins"]; ok {
+		restorePluginLiterals(items[1], plugins)
+	}
+	if items, ok := keys["chains"]; ok && items[1].Kind == yaml.MappingNode {
+		for name, chain := range keyNodes(items[1]) {
+			restorePluginLiterals(chain[1], chains[name])
+		}
+	}
+}
+
+// restorePluginLiterals restores literal values of every plugin
+// entry of an encoded chain
+func restorePluginLiterals(items *yaml.Node, plugins []*pluginSource) {
+	if items.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range items.Content {
+		params, ok := keyNodes(item)["parameters"]
+		if !ok || params[1].Kind != yaml.MappingNode {
+			continue
+		}
+		var src *pluginSource
+		if i < len(plugins) {
+			src = plugins[i]
+		}
+		for j := 0; j+1 < len(params[1].Content); j += 2 {
+			k, v := params[1].Content[j], params[1].Content[j+1]
+			if v.Kind != yaml.ScalarNode {
+				continue
+			}
+			if src != nil {
+				if ref, ok := src.params[k.Value]; ok && ref.node.Kind == yaml.ScalarNode && ref.node.Value == v.Value {
+					v.Tag, v.Style = ref.node.Tag, ref.node.Style
+					continue
+				}
+			}
+			if tag, ok := numericTag(v.Value); ok {
+				v.Tag, v.Style = tag, 0
+			}
+		}
+	}
+}
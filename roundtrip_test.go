@@ -0,0 +1,120 @@
+package lv2hostconfig_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+// reread writes config and reads it back, failing the test on error
+func reread(t *testing.T, c *lv2hostconfig.LV2HostConfig) *lv2hostconfig.LV2HostConfig {
+	t.Helper()
+	data, err := c.WriteBytes()
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	nc := lv2hostconfig.NewLV2HostConfig()
+	if err := nc.ReadBytes(data); err != nil {
+		t.Fatalf("Failed to read written config: %v\n%s", err, data)
+	}
+	return nc
+}
+
+func TestBakeExactValues(t *testing.T) {
+	values := map[string]float32{
+		"subnormal":    math.SmallestNonzeroFloat32,
+		"subnormalneg": -math.SmallestNonzeroFloat32 * 3,
+		"normalmin":    math.Float32frombits(0x00800000),
+		"negzero":      float32(math.Copysign(0, -1)),
+		"max":          math.MaxFloat32,
+		"large":        -1.2345678e37,
+		"small":        1.1754944e-30,
+		"third":        float32(1) / 3,
+	}
+	pc := lv2hostconfig.NewLV2PluginConfig()
+	pc.PluginURI = "urn:test:eq"
+	c := lv2hostconfigtest.Config(pc)
+	for name, v := range values {
+		c.ValueMap["v_"+name] = float64(v)
+		c.Plugins[0].DataFmt[name] = "v_" + name
+	}
+	lv2hostconfigtest.MustEvaluate(t, c)
+	c.Bake()
+
+	nc := reread(t, c)
+	for name := range values {
+		if strings.Contains(nc.Plugins[0].DataFmt[name], "v_") {
+			t.Errorf("Parameter '%v' was not baked: '%v'", name, nc.Plugins[0].DataFmt[name])
+		}
+	}
+	lv2hostconfigtest.MustEvaluate(t, nc)
+	for name, want := range values {
+		got := nc.Plugins[0].Data[name]
+		if math.Float32bits(got) != math.Float32bits(want) {
+			t.Errorf("Parameter '%v' is %v (%#x) after baking, want %v (%#x)",
+				name, got, math.Float32bits(got), want, math.Float32bits(want))
+		}
+	}
+}
+
+func TestBakeSkipsOverrides(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - name: eq\n    pluginUri: urn:test:eq\n    parameters:\n      gain: 1 + 2\n      freq: 500 * 2\n")
+	lv2hostconfigtest.MustEvaluate(t, c)
+	if err := c.SetOverride("eq", "gain", 9); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	c.Bake()
+	if got := c.Plugins[0].DataFmt["gain"]; got != "3" {
+		t.Errorf("Overridden parameter was baked as '%v', want '3'", got)
+	}
+	if got := c.Plugins[0].DataFmt["freq"]; got != "1000" {
+		t.Errorf("Parameter was baked as '%v', want '1000'", got)
+	}
+	// the override is still in effect until cleared
+	lv2hostconfigtest.AssertValue(t, c, 0, "gain", 9, 0)
+}
+
+func TestLiteralsRoundTrip(t *testing.T) {
+	src := `plugins:
+  - pluginUri: urn:test:eq
+    parameters:
+      a: 0.30
+      b: "0.3"
+      c: 1e3
+      d: -0.0
+      e: 1.401298464324817e-45
+      f: 3.4028235e+38
+      g: 00012
+      h: 'quoted'
+`
+	c := lv2hostconfigtest.Parse(t, src)
+	data, err := c.WriteBytes()
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	for _, line := range strings.Split(src, "\n")[3:] {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("Line '%v' was not written back as is:\n%s", line, data)
+		}
+	}
+}
+
+func TestBakedLiteralsArePlain(t *testing.T) {
+	c := lv2hostconfigtest.Parse(t, "plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      a: '1 + 1'\n      b: -0.5 * 0\n      c: 2 * pow(10, 38)\n")
+	lv2hostconfigtest.MustEvaluate(t, c)
+	c.Bake()
+	data, err := c.WriteBytes()
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// baked values are numbers, so they are not quoted (even the
+	// one that was quoted as an expression)
+	for _, line := range []string{"      a: 2\n", "      b: -0\n", "      c: 2e+38\n"} {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("Line '%v' not found in:\n%s", strings.TrimSpace(line), data)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SystemPlugin is the plugin identifier of host's own ports, such
+// as "system:capture_1" or "system:playback_1"
+const SystemPlugin = "system"
+
+// SystemIndex is the plugin index of host's own ports in routing
+// graphs and apply plans
+const SystemIndex = -1
+
+// LV2Endpoint is a port of a plugin (or of the host itself), written
+// as "<plugin>:<port>" in configs. Plugin is a plugin identifier
+// (name, UUID, URI or instance ID) or SystemPlugin.
+type LV2Endpoint struct {
+	Plugin string
+	Port   string
+}
+
+func (e LV2Endpoint) String() string {
+	return e.Plugin + ":" + e.Port
+}
+
+// parseEndpoint parses "<plugin>:<port>". URIs contain colons, but
+// port symbols never do, so plugin is everything up to the last one.
+func parseEndpoint(s string) (LV2Endpoint, error) {
+	sep := strings.LastIndexByte(s, ':')
+	if sep <= 0 || sep == len(s)-1 {
+		return LV2Endpoint{}, fmt.Errorf("endpoint '%v' is not of the form '<plugin>:<port>'", s)
+	}
+	return LV2Endpoint{s[:sep], s[sep+1:]}, nil
+}
+
+// LV2Connection connects an output port to an input port
+type LV2Connection struct {
+	From LV2Endpoint
+	To   LV2Endpoint
+}
+
+func (conn LV2Connection) String() string {
+	return fmt.Sprintf("%v -> %v", conn.From, conn.To)
+}
+
+// lv2ConnectionRaw is a connection as written in config files
+type lv2ConnectionRaw struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+func connectionsFromRaw(raw []lv2ConnectionRaw) ([]LV2Connection, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	res := make([]LV2Connection, 0, len(raw))
+	for i, rc := range raw {
+		from, err := parseEndpoint(rc.From)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse config: connection #%v: %v", i+1, err)
+		}
+		to, err := parseEndpoint(rc.To)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse config: connection #%v: %v", i+1, err)
+		}
+		res = append(res, LV2Connection{from, to})
+	}
+	return res, nil
+}
+
+func connectionsToRaw(conns []LV2Connection) []lv2ConnectionRaw {
+	if conns == nil {
+		return nil
+	}
+	res := make([]lv2ConnectionRaw, 0, len(conns))
+	for _, conn := range conns {
+		res = append(res, lv2ConnectionRaw{conn.From.String(), conn.To.String()})
+	}
+	return res
+}
+
+// GraphEdge is a connection between ports of plugins at given
+// positions in the chain. Host's own ports are at SystemIndex.
+type GraphEdge struct {
+	From     int
+	FromPort string
+	To       int
+	ToPort   string
+}
+
+// RoutingCycleError is returned when connections feed output of a
+// plugin back into its own input, directly or through other plugins.
+// Path lists plugin identifiers along the cycle, starting and ending
+// with the same plugin.
+type RoutingCycleError struct {
+	Path []string
+}
+
+func (e *RoutingCycleError) Error() string {
+	return fmt.Sprintf("Connections form a cycle: %v", strings.Join(e.Path, " -> "))
+}
+
+// ConnectionGraph is the audio routing graph of a chain
+type ConnectionGraph struct {
+	// Edges are connections, in the order they are configured
+	Edges []GraphEdge
+
+	ids []string
+}
+
+// Outputs returns edges leaving plugin at given position
+func (g *ConnectionGraph) Outputs(plugin int) []GraphEdge {
+	res := make([]GraphEdge, 0)
+	for _, e := range g.Edges {
+		if e.From == plugin {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Inputs returns edges entering plugin at given position
+func (g *ConnectionGraph) Inputs(plugin int) []GraphEdge {
+	res := make([]GraphEdge, 0)
+	for _, e := range g.Edges {
+		if e.To == plugin {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Order returns positions of all plugins of the chain in an order
+// in which every plugin comes after all plugins feeding it, keeping
+// chain order where connections don't say otherwise
+func (g *ConnectionGraph) Order() []int {
+	indegree := make([]int, len(g.ids))
+	for _, e := range g.Edges {
+		if e.From != SystemIndex && e.To != SystemIndex && e.From != e.To {
+			indegree[e.To]++
+		}
+	}
+	res := make([]int, 0, len(g.ids))
+	done := make([]bool, len(g.ids))
+	for len(res) < len(g.ids) {
+		// graph is acyclic, so there's always a plugin to pick
+		for i := range g.ids {
+			if done[i] || indegree[i] != 0 {
+				continue
+			}
+			done[i] = true
+			res = append(res, i)
+			for _, e := range g.Outputs(i) {
+				if e.To != SystemIndex {
+					indegree[e.To]--
+				}
+			}
+			break
+		}
+	}
+	return res
+}
+
+// findCycle returns a cycle in the graph, if there is one
+func (g *ConnectionGraph) findCycle() []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.ids))
+	succ := make([][]int, len(g.ids))
+	for _, e := range g.Edges {
+		if e.From != SystemIndex && e.To != SystemIndex {
+			succ[e.From] = append(succ[e.From], e.To)
+		}
+	}
+	stack := make([]int, 0)
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, j := range succ[i] {
+			switch state[j] {
+			case visiting:
+				for k := range stack {
+					if stack[k] == j {
+						return append(append([]int(nil), stack[k:]...), j)
+					}
+				}
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range g.ids {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// resolveEndpoint returns position of endpoint's plugin in the chain
+func (c *LV2HostConfig) resolveEndpoint(e LV2Endpoint) (int, error) {
+	if e.Plugin == SystemPlugin {
+		return SystemIndex, nil
+	}
+	return c.findPluginIndex(e.Plugin)
+}
+
+// resolveConnections resolves plugins of all connections
+func (c *LV2HostConfig) resolveConnections() ([]GraphEdge, error) {
+	edges := make([]GraphEdge, 0, len(c.Connections))
+	for _, conn := range c.Connections {
+		from, err := c.resolveEndpoint(conn.From)
+		if err != nil {
+			return nil, fmt.Errorf("Connection '%v': %v", conn, err)
+		}
+		to, err := c.resolveEndpoint(conn.To)
+		if err != nil {
+			return nil, fmt.Errorf("Connection '%v': %v", conn, err)
+		}
+		edges = append(edges, GraphEdge{from, conn.From.Port, to, conn.To.Port})
+	}
+	return edges, nil
+}
+
+// Graph returns the routing graph of the chain. If there is no
+// connections section, plugins are connected in chain order, with
+// all ports of each plugin feeding the next one (edges have empty
+// port names then). Fails if connections refer to unknown plugins,
+// or if they form a cycle, in which case a RoutingCycleError is
+// returned.
+func (c *LV2HostConfig) Graph() (*ConnectionGraph, error) {
+	g := &ConnectionGraph{ids: make([]string, len(c.Plugins))}
+	for i := range c.Plugins {
+		g.ids[i] = c.InstanceID(i)
+	}
+	if c.Connections == nil {
+		g.Edges = make([]GraphEdge, 0)
+		for i := 1; i < len(c.Plugins); i++ {
+			g.Edges = append(g.Edges, GraphEdge{From: i - 1, To: i})
+		}
+		return g, nil
+	}
+	edges, err := c.resolveConnections()
+	if err != nil {
+		return nil, err
+	}
+	g.Edges = edges
+	if cycle := g.findCycle(); cycle != nil {
+		path := make([]string, 0, len(cycle))
+		for _, i := range cycle {
+			path = append(path, g.ids[i])
+		}
+		return nil, &RoutingCycleError{path}
+	}
+	return g, nil
+}
+
+// Connect adds a connection between two ports, given as
+// "<plugin>:<port>". Adding a connection to a config without one
+// replaces chain order routing with explicit connections.
+func (c *LV2HostConfig) Connect(from, to string) error {
+	fe, err := parseEndpoint(from)
+	if err != nil {
+		return fmt.Errorf("Invalid connection: %v", err)
+	}
+	te, err := parseEndpoint(to)
+	if err != nil {
+		return fmt.Errorf("Invalid connection: %v", err)
+	}
+	nc := c.Clone()
+	nc.Connections = append(nc.Connections, LV2Connection{fe, te})
+	if _, err := nc.Graph(); err != nil {
+		return err
+	}
+	c.Connections = nc.Connections
+	return nil
+}
+
+// copyConnections copies a list of connections
+func copyConnections(conns []LV2Connection) []LV2Connection {
+	if conns == nil {
+		return nil
+	}
+	return append(make([]LV2Connection, 0, len(conns)), conns...)
+}
+
+// remapConnections rewrites connections after plugins have been
+// moved around, given their positions before the change, so that
+// connections keep referring to the same plugins even if those are
+// identified by instance IDs. index maps old positions to new ones,
+// or to -1 for plugins that are gone, in which case connections of
+// the plugin are dropped.
+func (c *LV2HostConfig) remapConnections(edges []GraphEdge, index func(int) int) {
+	conns := make([]LV2Connection, 0, len(edges))
+	endpoint := func(i int, port string) (LV2Endpoint, bool) {
+		if i == SystemIndex {
+			return LV2Endpoint{SystemPlugin, port}, true
+		}
+		if i = index(i); i < 0 {
+			return LV2Endpoint{}, false
+		}
+		return LV2Endpoint{c.InstanceID(i), port}, true
+	}
+	for _, e := range edges {
+		from, ok := endpoint(e.From, e.FromPort)
+		if !ok {
+			continue
+		}
+		to, ok := endpoint(e.To, e.ToPort)
+		if !ok {
+			continue
+		}
+		conns = append(conns, LV2Connection{from, to})
+	}
+	c.Connections = conns
+}
+
+// rearrange runs a change to the chain which moves plugins around,
+// keeping connections pointed at the same plugins. Connections that
+// can't be resolved before the change are left as they are.
+func (c *LV2HostConfig) rearrange(change func(), index func(int) int) {
+	if c.Connections == nil {
+		change()
+		return
+	}
+	edges, err := c.resolveConnections()
+	change()
+	if err == nil {
+		c.remapConnections(edges, index)
+	}
+}
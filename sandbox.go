@@ -0,0 +1,428 @@
+package lv2hostconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// SandboxEnv is set in the environment of sandbox processes
+const SandboxEnv = "LV2HOSTCONFIG_SANDBOX"
+
+// Sandbox describes how to run evaluation in a separate process,
+// so that crashes or hangs of custom functions can't take down
+// the host. Sandbox process runs Command (host's own executable,
+// if empty) with Args, which must call SandboxMain before doing
+// anything else. If Timeout is set, sandbox process is killed if
+// evaluation takes longer than that.
+type Sandbox struct {
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+// sandboxPlugin is what sandbox process needs to evaluate a plugin.
+// Ranges and Types include those host knows from schemas and the
+// factory defaults database, and Enums are enum tables of the plugin.
+type sandboxPlugin struct {
+	Name            string
+	UUID            string
+	Label           string
+	PluginURI       string
+	DataFmt         map[string]string
+	CVFmt           map[string]LV2CVPort
+	StartOffsetFmt  string
+	StartOffsetUnit string
+	PropertiesFmt   map[string]string
+	Ranges          map[string]LV2ParamRange
+	Types           map[string]string
+	Enums           map[string]EnumTable
+	Frozen          map[string]bool
+	Data            map[string]float32
+}
+
+type sandboxRequest struct {
+	Values       map[string]interface{}
+	SilenceFloor float32
+	RangePolicy  string
+	Plugins      []sandboxPlugin
+	Chains       map[string][]sandboxPlugin
+}
+
+// evaluated values are sent as strings, since JSON can't
+// represent infinities
+type sandboxResult struct {
+	Data        map[string]string
+	CV          map[string]string
+	StartOffset string
+	Properties  map[string]string
+}
+
+// sandboxResponse has either results for all plugins, or the
+// error evaluation failed with
+type sandboxResponse struct {
+	Plugins []sandboxResult
+	Chains  map[string][]sandboxResult
+	Error   string
+}
+
+func formatValues(values map[string]float32) map[string]string {
+	res := make(map[string]string, len(values))
+	for k, v := range values {
+		res[k] = formatFloat(v)
+	}
+	return res
+}
+
+func parseValues(values map[string]string) (map[string]float32, error) {
+	res := make(map[string]float32, len(values))
+	for k, v := range values {
+		f, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Value '%v' is not a float", v)
+		}
+		res[k] = float32(f)
+	}
+	return res, nil
+}
+
+// sandboxPlugin describes a plugin for sandbox process
+func (c *LV2HostConfig) sandboxPlugin(pc *LV2PluginConfig) sandboxPlugin {
+	sp := sandboxPlugin{
+		Name:            pc.Name,
+		UUID:            pc.UUID,
+		Label:           pc.Label,
+		PluginURI:       pc.PluginURI,
+		DataFmt:         pc.DataFmt,
+		CVFmt:           pc.CVFmt,
+		StartOffsetFmt:  pc.StartOffsetFmt,
+		StartOffsetUnit: pc.StartOffsetUnit,
+		PropertiesFmt:   pc.PropertiesFmt,
+		Ranges:          make(map[string]LV2ParamRange),
+		Types:           make(map[string]string),
+		Enums:           c.Enums[pc.PluginURI],
+		Frozen:          pc.Frozen,
+		// only frozen parameters need their current values
+		Data: make(map[string]float32),
+	}
+	ports := c.rangePorts(pc)
+	for symbol := range pc.DataFmt {
+		if min, max, ok := c.knownRange(pc, symbol, ports); ok {
+			r := pc.Ranges[symbol]
+			r.Min, r.Max = min, max
+			sp.Ranges[symbol] = r
+		}
+		if k := c.paramKind(pc, symbol); k == KindInt {
+			sp.Types[symbol] = ParamInt
+		} else if k == KindBool {
+			sp.Types[symbol] = ParamToggle
+		}
+	}
+	for symbol := range pc.Frozen {
+		if v, ok := pc.evaluated(symbol); ok {
+			sp.Data[symbol] = v
+		}
+	}
+	return sp
+}
+
+// plugin rebuilds plugin config in sandbox process
+func (sp *sandboxPlugin) plugin() LV2PluginConfig {
+	pc := NewLV2PluginConfig()
+	pc.Name = sp.Name
+	pc.UUID = sp.UUID
+	pc.Label = sp.Label
+	pc.PluginURI = sp.PluginURI
+	if sp.DataFmt != nil {
+		pc.DataFmt = sp.DataFmt
+	}
+	pc.CVFmt = sp.CVFmt
+	pc.StartOffsetFmt = sp.StartOffsetFmt
+	pc.StartOffsetUnit = sp.StartOffsetUnit
+	pc.PropertiesFmt = sp.PropertiesFmt
+	pc.Ranges = sp.Ranges
+	pc.Types = sp.Types
+	pc.Frozen = sp.Frozen
+	if sp.Data != nil {
+		pc.Data = sp.Data
+	}
+	return pc
+}
+
+// sandboxResultOf returns evaluated values of a plugin, the way
+// sandbox process sends them
+func sandboxResultOf(pc *LV2PluginConfig) sandboxResult {
+	return sandboxResult{
+		Data:        formatValues(pc.Data),
+		CV:          formatValues(pc.CV),
+		StartOffset: formatFloat(pc.StartOffset),
+		Properties:  copyStringMap(pc.Properties),
+	}
+}
+
+// applyResult sets evaluated values of a copy of plugin config
+func (pc *LV2PluginConfig) applyResult(res *sandboxResult) (LV2PluginConfig, error) {
+	npc := pc.clone()
+	var err error
+	if npc.Data, err = parseValues(res.Data); err != nil {
+		return npc, err
+	}
+	if npc.CV, err = parseValues(res.CV); err != nil {
+		return npc, err
+	}
+	offset, err := strconv.ParseFloat(res.StartOffset, 32)
+	if err != nil {
+		return npc, fmt.Errorf("Start offset '%v' is not a float", res.StartOffset)
+	}
+	npc.StartOffset = float32(offset)
+	npc.Properties = copyStringMap(res.Properties)
+	npc.applyOverrides()
+	return npc, nil
+}
+
+// sandboxResults returns evaluated values of plugins of a chain
+func sandboxResults(chain []LV2PluginConfig) []sandboxResult {
+	res := make([]sandboxResult, 0, len(chain))
+	for i := range chain {
+		res = append(res, sandboxResultOf(&chain[i]))
+	}
+	return res
+}
+
+// applyResults sets evaluated values of copies of plugins of a chain
+func applyResults(chain []LV2PluginConfig, results []sandboxResult) ([]LV2PluginConfig, error) {
+	if len(results) != len(chain) {
+		return nil, fmt.Errorf("Got %v plugins instead of %v", len(results), len(chain))
+	}
+	pcs := make([]LV2PluginConfig, 0, len(chain))
+	for i := range chain {
+		pc, err := chain[i].applyResult(&results[i])
+		if err != nil {
+			return nil, err
+		}
+		pcs = append(pcs, pc)
+	}
+	return pcs, nil
+}
+
+// sandboxEvaluate evaluates a request in sandbox process
+func sandboxEvaluate(c *LV2HostConfig, req *sandboxRequest, resp *sandboxResponse) error {
+	// validators and providers are run by the host, and so are
+	// schema checks, which is why ranges and types come with plugins
+	c.Validators = nil
+	c.Providers = nil
+	c.Schemas = nil
+	c.ValueMap = req.Values
+	if c.ValueMap == nil {
+		c.ValueMap = make(map[string]interface{})
+	}
+	c.SilenceFloor = req.SilenceFloor
+	c.RangePolicy = req.RangePolicy
+	c.Enums = make(map[string]map[string]EnumTable)
+	chain := func(sps []sandboxPlugin) []LV2PluginConfig {
+		pcs := make([]LV2PluginConfig, 0, len(sps))
+		for i := range sps {
+			if len(sps[i].Enums) != 0 {
+				c.Enums[sps[i].PluginURI] = sps[i].Enums
+			}
+			pcs = append(pcs, sps[i].plugin())
+		}
+		return pcs
+	}
+	c.Plugins = chain(req.Plugins)
+	c.Chains = nil
+	if req.Chains != nil {
+		c.Chains = make(map[string][]LV2PluginConfig, len(req.Chains))
+		for name, sps := range req.Chains {
+			c.Chains[name] = chain(sps)
+		}
+	}
+	if err := c.Evaluate(); err != nil {
+		return err
+	}
+	resp.Plugins = sandboxResults(c.Plugins)
+	if c.Chains != nil {
+		resp.Chains = make(map[string][]sandboxResult, len(c.Chains))
+		for name, pcs := range c.Chains {
+			resp.Chains[name] = sandboxResults(pcs)
+		}
+	}
+	return nil
+}
+
+// SandboxMain must be called at the very beginning of main() of
+// any program used as a sandbox. If the program was started as a
+// sandbox process, it serves evaluation requests and exits,
+// otherwise it returns immediately. newConfig creates configs used
+// for evaluation, and should set up any custom functions.
+func SandboxMain(newConfig func() *LV2HostConfig) {
+	if os.Getenv(SandboxEnv) == "" {
+		return
+	}
+	// requests and responses are JSON objects, and they use
+	// their own file descriptors, so that custom functions
+	// writing to stdout can't break the protocol
+	dec := json.NewDecoder(os.NewFile(3, "request"))
+	enc := json.NewEncoder(os.NewFile(4, "response"))
+	for {
+		var req sandboxRequest
+		if err := dec.Decode(&req); err != nil {
+			if err == io.EOF {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Failed to read sandbox request: %v\n", err)
+			os.Exit(1)
+		}
+		var resp sandboxResponse
+		if err := sandboxEvaluate(newConfig(), &req, &resp); err != nil {
+			resp = sandboxResponse{Error: err.Error()}
+		}
+		if err := enc.Encode(&resp); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write sandbox response: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// run evaluates request in a new sandbox process
+func (s *Sandbox) run(req *sandboxRequest) (*sandboxResponse, error) {
+	command := s.Command
+	if command == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start sandbox: %v", err)
+		}
+		command = exe
+	}
+	reqR, reqW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to start sandbox: %v", err)
+	}
+	respR, respW, err := os.Pipe()
+	if err != nil {
+		reqR.Close()
+		reqW.Close()
+		return nil, fmt.Errorf("Failed to start sandbox: %v", err)
+	}
+
+	cmd := exec.Command(command, s.Args...)
+	cmd.Env = append(os.Environ(), SandboxEnv+"=1")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{reqR, respW}
+	err = cmd.Start()
+	// child has its own copies of these
+	reqR.Close()
+	respW.Close()
+	if err != nil {
+		reqW.Close()
+		respR.Close()
+		return nil, fmt.Errorf("Failed to start sandbox: %v", err)
+	}
+
+	var resp sandboxResponse
+	done := make(chan error, 1)
+	go func() {
+		if err := json.NewEncoder(reqW).Encode(req); err != nil {
+			done <- err
+			return
+		}
+		done <- json.NewDecoder(respR).Decode(&resp)
+	}()
+	var timeout <-chan time.Time
+	if s.Timeout > 0 {
+		timer := time.NewTimer(s.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	timedOut := false
+	select {
+	case err = <-done:
+	case <-timeout:
+		cmd.Process.Kill()
+		timedOut = true
+	}
+	// closing requests makes sandbox process exit
+	reqW.Close()
+	werr := cmd.Wait()
+	respR.Close()
+	if timedOut {
+		return nil, fmt.Errorf("Sandbox evaluation timed out after %v", s.Timeout)
+	}
+	if err != nil && werr != nil {
+		return nil, fmt.Errorf("Sandbox process failed: %v", werr)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("Sandbox process exited unexpectedly")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%v", resp.Error)
+	}
+	return &resp, nil
+}
+
+// EvaluateSandboxed works like Evaluate, but expressions are
+// evaluated in a separate sandbox process. Enums, range policy,
+// parameter types and properties are handled just like Evaluate
+// does it, with ranges and types known from schemas and the factory
+// defaults database sent along with plugins. Providers, validators
+// and schema checks are still run in the host process, and all
+// values must be representable as JSON. Evaluation budgets are not
+// enforced, use sandbox Timeout instead.
+func (c *LV2HostConfig) EvaluateSandboxed(s *Sandbox) error {
+	// references between parameters are resolved by the sandbox,
+	// as they run expressions too
+	vars, err := c.baseValues()
+	if err != nil {
+		return err
+	}
+	req := sandboxRequest{Values: vars, SilenceFloor: c.SilenceFloor, RangePolicy: c.RangePolicy}
+	chain := func(pcs []LV2PluginConfig) []sandboxPlugin {
+		sps := make([]sandboxPlugin, 0, len(pcs))
+		for i := range pcs {
+			sps = append(sps, c.sandboxPlugin(&pcs[i]))
+		}
+		return sps
+	}
+	req.Plugins = chain(c.Plugins)
+	if c.Chains != nil {
+		req.Chains = make(map[string][]sandboxPlugin, len(c.Chains))
+		for name, pcs := range c.Chains {
+			req.Chains[name] = chain(pcs)
+		}
+	}
+	resp, err := s.run(&req)
+	if err != nil {
+		return err
+	}
+
+	// evaluation should be atomic, so operate on a copy
+	pcs, err := applyResults(c.Plugins, resp.Plugins)
+	if err != nil {
+		return fmt.Errorf("Sandbox returned invalid data: %v", err)
+	}
+	chains, err := c.mapChains(func(name string, chain []LV2PluginConfig) ([]LV2PluginConfig, error) {
+		return applyResults(chain, resp.Chains[name])
+	})
+	if err != nil {
+		return fmt.Errorf("Sandbox returned invalid data: %v", err)
+	}
+
+	if err := c.validate(pcs); err != nil {
+		return err
+	}
+	if err := c.validateChains(chains); err != nil {
+		return err
+	}
+	c.Plugins = pcs
+	c.Chains = chains
+	c.EvaluatedAt = c.now()
+	return nil
+}
@@ -0,0 +1,183 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LV2Scene is a named set of parameter values. Parameters map
+// plugin identifiers to formatted parameter values, which replace
+// existing ones when the scene is applied. Freeze and Thaw list
+// parameters, as "<plugin>.<symbol>", to be frozen or thawed when
+// the scene is applied. Triggers are MIDI messages the host
+// should apply the scene on.
+type LV2Scene struct {
+	Parameters map[string]map[string]string `yaml:"parameters,omitempty"`
+	Freeze     []string                     `yaml:"freeze,omitempty"`
+	Thaw       []string                     `yaml:"thaw,omitempty"`
+	Triggers   []LV2SceneTrigger            `yaml:"triggers,omitempty"`
+}
+
+func (s LV2Scene) clone() LV2Scene {
+	ns := LV2Scene{
+		nil,
+		append([]string(nil), s.Freeze...),
+		append([]string(nil), s.Thaw...),
+		append([]LV2SceneTrigger(nil), s.Triggers...),
+	}
+	if s.Parameters != nil {
+		ns.Parameters = make(map[string]map[string]string)
+		for plugin, params := range s.Parameters {
+			ns.Parameters[plugin] = make(map[string]string)
+			for k, v := range params {
+				ns.Parameters[plugin][k] = v
+			}
+		}
+	}
+	return ns
+}
+
+func copyScenes(scenes map[string]LV2Scene) map[string]LV2Scene {
+	if len(scenes) == 0 {
+		return nil
+	}
+	s := make(map[string]LV2Scene)
+	for k, v := range scenes {
+		s[k] = v.clone()
+	}
+	return s
+}
+
+func copyFrozen(frozen map[string]bool) map[string]bool {
+	if len(frozen) == 0 {
+		return nil
+	}
+	f := make(map[string]bool)
+	for k, v := range frozen {
+		if v {
+			f[k] = true
+		}
+	}
+	return f
+}
+
+func frozenFromList(frozen []string) map[string]bool {
+	if len(frozen) == 0 {
+		return nil
+	}
+	f := make(map[string]bool)
+	for _, sym := range frozen {
+		f[sym] = true
+	}
+	return f
+}
+
+func frozenToList(frozen map[string]bool) []string {
+	l := make([]string, 0, len(frozen))
+	for sym, v := range frozen {
+		if v {
+			l = append(l, sym)
+		}
+	}
+	if len(l) == 0 {
+		return nil
+	}
+	sort.Strings(l)
+	return l
+}
+
+// splitParamRef splits "<plugin>.<symbol>" parameter reference.
+// LV2 symbols can't contain dots, but plugin URIs can, so the
+// reference is split at the last dot.
+func splitParamRef(ref string) (string, string, error) {
+	i := strings.LastIndexByte(ref, '.')
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("Parameter reference '%v' is invalid", ref)
+	}
+	return ref[:i], ref[i+1:], nil
+}
+
+// Freeze freezes a plugin parameter: it will not be changed by
+// scenes, and will keep its value when the config is re-evaluated.
+func (c *LV2HostConfig) Freeze(plugin, symbol string) error {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return err
+	}
+	if pc.Frozen == nil {
+		pc.Frozen = make(map[string]bool)
+	}
+	pc.Frozen[symbol] = true
+	return nil
+}
+
+// Thaw unfreezes a plugin parameter
+func (c *LV2HostConfig) Thaw(plugin, symbol string) error {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return err
+	}
+	delete(pc.Frozen, symbol)
+	return nil
+}
+
+// IsFrozen checks whether a plugin parameter is frozen
+func (c *LV2HostConfig) IsFrozen(plugin, symbol string) (bool, error) {
+	pc, err := c.findPlugin(plugin)
+	if err != nil {
+		return false, err
+	}
+	return pc.Frozen[symbol], nil
+}
+
+// ApplyScene replaces formatted parameter values with those of
+// a scene, skipping any frozen parameters, after which scene's
+// freeze and thaw lists are applied. Applying is atomic. Config
+// must be re-evaluated for new values to take effect.
+func (c *LV2HostConfig) ApplyScene(name string) error {
+	scene, ok := c.Scenes[name]
+	if !ok {
+		return fmt.Errorf("Scene '%v' not found", name)
+	}
+	pcs := make([]LV2PluginConfig, 0, len(c.Plugins))
+	for _, pc := range c.Plugins {
+		pcs = append(pcs, pc.clone())
+	}
+	nc := c.cloneEmpty()
+	nc.Plugins = pcs
+
+	for plugin, params := range scene.Parameters {
+		pc, err := nc.findPlugin(plugin)
+		if err != nil {
+			return fmt.Errorf("Scene '%v': %v", name, err)
+		}
+		for sym, value := range params {
+			if !pc.Frozen[sym] {
+				pc.DataFmt[sym] = value
+			}
+		}
+	}
+	for _, ref := range scene.Freeze {
+		plugin, sym, err := splitParamRef(ref)
+		if err == nil {
+			err = nc.Freeze(plugin, sym)
+		}
+		if err != nil {
+			return fmt.Errorf("Scene '%v': %v", name, err)
+		}
+	}
+	for _, ref := range scene.Thaw {
+		plugin, sym, err := splitParamRef(ref)
+		if err == nil {
+			err = nc.Thaw(plugin, sym)
+		}
+		if err != nil {
+			return fmt.Errorf("Scene '%v': %v", name, err)
+		}
+	}
+
+	c.Plugins = pcs
+	c.Scene = name
+	return nil
+}
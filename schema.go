@@ -0,0 +1,107 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+)
+
+// Parameter types known to schemas. Float is the default.
+const (
+	ParamFloat  = "float"
+	ParamInt    = "int"
+	ParamToggle = "toggle"
+)
+
+// ParamSchema describes a single plugin parameter. Min and Max
+// are only checked if Min < Max. Default is the value the plugin
+// uses when the parameter is not set, and is not enforced.
+type ParamSchema struct {
+	Type     string
+	Min      float32
+	Max      float32
+	Default  float32
+	Required bool
+}
+
+// PluginSchema describes parameters of a plugin, by symbol
+type PluginSchema map[string]ParamSchema
+
+// RegisterSchema registers schema of plugin with given URI,
+// replacing any schema already registered for it. Evaluated
+// values of plugins with that URI are checked against the schema,
+// and any range or type violations and missing required
+// parameters reject the evaluation with a ValidationError, the
+// same way validators do.
+func (c *LV2HostConfig) RegisterSchema(uri string, schema PluginSchema) {
+	if c.Schemas == nil {
+		c.Schemas = make(map[string]PluginSchema)
+	}
+	s := make(PluginSchema, len(schema))
+	for k, v := range schema {
+		s[k] = v
+	}
+	c.Schemas[uri] = s
+}
+
+// copySchemas copies a map of schemas
+func copySchemas(schemas map[string]PluginSchema) map[string]PluginSchema {
+	if schemas == nil {
+		return nil
+	}
+	res := make(map[string]PluginSchema, len(schemas))
+	for uri, schema := range schemas {
+		s := make(PluginSchema, len(schema))
+		for k, v := range schema {
+			s[k] = v
+		}
+		res[uri] = s
+	}
+	return res
+}
+
+// check checks a single value against parameter schema
+func (ps ParamSchema) check(v float32) string {
+	switch ps.Type {
+	case "", ParamFloat:
+	case ParamInt:
+		if float64(v) != math.Trunc(float64(v)) {
+			return fmt.Sprintf("value %v is not an integer", v)
+		}
+	case ParamToggle:
+		if v != 0 && v != 1 {
+			return fmt.Sprintf("value %v is not a toggle (0 or 1)", v)
+		}
+	default:
+		return fmt.Sprintf("unknown parameter type '%v'", ps.Type)
+	}
+	if ps.Min < ps.Max && (v < ps.Min || v > ps.Max) {
+		return fmt.Sprintf("value %v is out of range [%v, %v]", v, ps.Min, ps.Max)
+	}
+	return ""
+}
+
+// schemaMessages checks evaluated plugin data against schemas
+func (c *LV2HostConfig) schemaMessages(pcs []LV2PluginConfig) []ValidationMessage {
+	msgs := make([]ValidationMessage, 0)
+	for i := range pcs {
+		pc := &pcs[i]
+		schema, ok := c.Schemas[pc.PluginURI]
+		if !ok {
+			continue
+		}
+		for _, symbol := range sortedKeys(schema) {
+			ps := schema[symbol]
+			v, ok := pc.Data[symbol]
+			if !ok {
+				if ps.Required {
+					msgs = append(msgs, ValidationMessage{pc.id(), symbol, "required parameter is missing"})
+				}
+				continue
+			}
+			if m := ps.check(v); m != "" {
+				msgs = append(msgs, ValidationMessage{pc.id(), symbol, m})
+			}
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,72 @@
+package lv2hostconfig
+
+// evaluateFiltered re-evaluates plugins of a chain filter accepts
+func (c *LV2HostConfig) evaluateFiltered(chain []LV2PluginConfig, filter func(LV2PluginConfig) bool, vars map[string]interface{}, run *evalRun) ([]LV2PluginConfig, error) {
+	pcs := make([]LV2PluginConfig, 0, len(chain))
+	for i := range chain {
+		if !filter(chain[i]) {
+			pcs = append(pcs, chain[i].clone())
+			continue
+		}
+		run.plugin = i
+		pc, err := c.evaluatePlugin(&chain[i], vars, run)
+		if err != nil {
+			return nil, err
+		}
+		pcs = append(pcs, pc)
+	}
+	return pcs, nil
+}
+
+// EvaluatePlugins works like Evaluate, but only re-evaluates
+// plugins filter accepts (in Plugins and in named chains), leaving
+// the rest as is. This is useful when the host knows only some
+// plugins are affected by a change. Validators still see whole
+// chains. EvaluatedAt is not updated, as the rest of the config was
+// not re-evaluated.
+func (c *LV2HostConfig) EvaluatePlugins(filter func(LV2PluginConfig) bool) error {
+	if c.Audit != nil {
+		c.Audit.Reset()
+	}
+	vars, err := c.evalValues()
+	if err != nil {
+		return err
+	}
+	run := c.newEvalRun()
+
+	// evaluation should be atomic, so evaluate into copies, and
+	// only store them once all plugins and chains succeed
+	pcs, err := c.evaluateFiltered(c.Plugins, filter, vars, run)
+	if err != nil {
+		return err
+	}
+	chains, err := c.mapChains(func(_ string, chain []LV2PluginConfig) ([]LV2PluginConfig, error) {
+		return c.evaluateFiltered(chain, filter, vars, run)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.validate(pcs); err != nil {
+		return err
+	}
+	if err := c.validateChains(chains); err != nil {
+		return err
+	}
+	c.Plugins = pcs
+	c.Chains = chains
+	c.timings = run.timings
+	return nil
+}
+
+// PluginFilter returns a filter for EvaluatePlugins accepting
+// plugins with any of given names, UUIDs or URIs
+func PluginFilter(ids ...string) func(LV2PluginConfig) bool {
+	set := make(map[string]bool)
+	for _, id := range ids {
+		set[id] = true
+	}
+	return func(pc LV2PluginConfig) bool {
+		return (pc.Name != "" && set[pc.Name]) || (pc.UUID != "" && set[pc.UUID]) || set[pc.PluginURI]
+	}
+}
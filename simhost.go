@@ -0,0 +1,229 @@
+package lv2hostconfig
+
+import "fmt"
+
+// PlanConsumer is a host that apply plans are executed against.
+// Ports of connections are empty when plugins are connected in
+// chain order, and either plugin may be SystemIndex otherwise.
+type PlanConsumer interface {
+	Instantiate(plugin int, uri string) error
+	SetParam(plugin int, symbol string, value float32) error
+	Connect(from int, fromPort string, to int, toPort string) error
+	Activate(plugin int) error
+}
+
+// ExecutePlan executes apply plan against a host, action by action,
+// stopping at the first action host has failed. Hosts see plugins
+// numbered host-wide: plugins of Plugins by their position, followed
+// by plugins of named chains, chain after chain.
+func ExecutePlan(plan []PlanAction, host PlanConsumer) error {
+	instance := planInstances(plan)
+	for _, a := range plan {
+		var err error
+		switch a.Kind {
+		case ActionInstantiate:
+			err = host.Instantiate(instance(a.Chain, a.Plugin), a.URI)
+		case ActionSetParam:
+			err = host.SetParam(instance(a.Chain, a.Plugin), a.Symbol, a.Value)
+		case ActionConnect:
+			err = host.Connect(instance(a.Chain, a.Plugin), a.Symbol, instance(a.Chain, a.To), a.ToSymbol)
+		case ActionActivate:
+			err = host.Activate(instance(a.Chain, a.Plugin))
+		default:
+			err = fmt.Errorf("Unknown action")
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to %v: %v", a, err)
+		}
+	}
+	return nil
+}
+
+// planInstances returns function numbering plugins of a plan
+// host-wide, with plugins of every named chain numbered after those
+// of chains that precede it in the plan
+func planInstances(plan []PlanAction) func(chain string, i int) int {
+	sizes := make(map[string]int)
+	chains := make([]string, 0)
+	for _, a := range plan {
+		if _, ok := sizes[a.Chain]; !ok && a.Chain != "" {
+			chains = append(chains, a.Chain)
+		}
+		for _, i := range []int{a.Plugin, a.To} {
+			if i >= sizes[a.Chain] {
+				sizes[a.Chain] = i + 1
+			}
+		}
+	}
+	offsets := make(map[string]int)
+	n := sizes[""]
+	for _, chain := range chains {
+		offsets[chain] = n
+		n += sizes[chain]
+	}
+	return func(chain string, i int) int {
+		if i == SystemIndex {
+			return i
+		}
+		return offsets[chain] + i
+	}
+}
+
+// SimulationProblem is an action a simulated host could not perform
+type SimulationProblem struct {
+	Action  PlanAction
+	Message string
+}
+
+func (p SimulationProblem) String() string {
+	return fmt.Sprintf("%v: %v", p.Action, p.Message)
+}
+
+// SimulatedHost is a host that performs no audio processing, but
+// records actions executed against it and flags impossible ones:
+// actions on plugins that were never instantiated, repeated
+// instantiations, connections and activations, and writes to ports
+// that schema of the plugin (if there is one) doesn't know about.
+// Problems don't stop execution, so a single run reports all of
+// them. This lets configs be "applied" in CI without audio hardware.
+type SimulatedHost struct {
+	// Schemas of plugins (by URI), for checking port writes
+	Schemas map[string]PluginSchema
+	// Actions that were executed, in order
+	Actions []PlanAction
+	// Problems with actions that were executed
+	Problems []SimulationProblem
+
+	uris      map[int]string
+	params    map[int]map[string]float32
+	connected map[simConnection]bool
+	active    map[int]bool
+}
+
+// NewSimulatedHost creates a simulated host, which checks port
+// writes against given schemas
+func NewSimulatedHost(schemas map[string]PluginSchema) *SimulatedHost {
+	return &SimulatedHost{
+		Schemas:   copySchemas(schemas),
+		Actions:   make([]PlanAction, 0),
+		Problems:  make([]SimulationProblem, 0),
+		uris:      make(map[int]string),
+		params:    make(map[int]map[string]float32),
+		connected: make(map[simConnection]bool),
+		active:    make(map[int]bool),
+	}
+}
+
+type simConnection struct {
+	from, to         int
+	fromPort, toPort string
+}
+
+func (h *SimulatedHost) record(a PlanAction, problem string) {
+	h.Actions = append(h.Actions, a)
+	if problem != "" {
+		h.Problems = append(h.Problems, SimulationProblem{a, problem})
+	}
+}
+
+// instanceProblem checks that plugin was instantiated. Host's own
+// ports are always there.
+func (h *SimulatedHost) instanceProblem(plugin int) string {
+	if plugin == SystemIndex {
+		return ""
+	}
+	if _, ok := h.uris[plugin]; !ok {
+		return fmt.Sprintf("plugin %v was not instantiated", plugin)
+	}
+	return ""
+}
+
+// Instantiate implements PlanConsumer
+func (h *SimulatedHost) Instantiate(plugin int, uri string) error {
+	a := PlanAction{Kind: ActionInstantiate, Plugin: plugin, URI: uri}
+	if _, ok := h.uris[plugin]; ok {
+		h.record(a, fmt.Sprintf("plugin %v was already instantiated", plugin))
+		return nil
+	}
+	h.uris[plugin] = uri
+	h.params[plugin] = make(map[string]float32)
+	h.record(a, "")
+	return nil
+}
+
+// SetParam implements PlanConsumer
+func (h *SimulatedHost) SetParam(plugin int, symbol string, value float32) error {
+	a := PlanAction{Kind: ActionSetParam, Plugin: plugin, Symbol: symbol, Value: value}
+	if p := h.instanceProblem(plugin); p != "" {
+		h.record(a, p)
+		return nil
+	}
+	if schema, ok := h.Schemas[h.uris[plugin]]; ok {
+		if _, ok := schema[symbol]; !ok {
+			h.record(a, fmt.Sprintf("plugin has no port '%v'", symbol))
+			return nil
+		}
+	}
+	h.params[plugin][symbol] = value
+	h.record(a, "")
+	return nil
+}
+
+// Connect implements PlanConsumer
+func (h *SimulatedHost) Connect(from int, fromPort string, to int, toPort string) error {
+	a := PlanAction{Kind: ActionConnect, Plugin: from, Symbol: fromPort, To: to, ToSymbol: toPort}
+	problem := h.instanceProblem(from)
+	if problem == "" {
+		problem = h.instanceProblem(to)
+	}
+	conn := simConnection{from, to, fromPort, toPort}
+	switch {
+	case problem != "":
+	case from == to && from != SystemIndex:
+		problem = fmt.Sprintf("plugin %v is connected to itself", from)
+	case h.connected[conn]:
+		problem = "ports are already connected"
+	default:
+		h.connected[conn] = true
+	}
+	h.record(a, problem)
+	return nil
+}
+
+// Activate implements PlanConsumer
+func (h *SimulatedHost) Activate(plugin int) error {
+	a := PlanAction{Kind: ActionActivate, Plugin: plugin}
+	problem := h.instanceProblem(plugin)
+	if problem == "" && h.active[plugin] {
+		problem = fmt.Sprintf("plugin %v was already activated", plugin)
+	}
+	h.active[plugin] = true
+	h.record(a, problem)
+	return nil
+}
+
+// Param returns value last written to a port of a plugin
+func (h *SimulatedHost) Param(plugin int, symbol string) (float32, bool) {
+	v, ok := h.params[plugin][symbol]
+	return v, ok
+}
+
+// Active checks if plugin was activated
+func (h *SimulatedHost) Active(plugin int) bool {
+	return h.active[plugin] && h.instanceProblem(plugin) == ""
+}
+
+// DryRun executes apply plan of an evaluated config against a
+// simulated host, which checks port writes against schemas of the
+// config. Problems found are in Problems of the returned host.
+func (c *LV2HostConfig) DryRun(sampleRate float32) (*SimulatedHost, error) {
+	plan, err := c.ApplyPlan(sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	h := NewSimulatedHost(c.Schemas)
+	if err := ExecutePlan(plan, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
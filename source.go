@@ -0,0 +1,172 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// SourcePos is a position within a config file
+type SourcePos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p SourcePos) String() string {
+	return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Column)
+}
+
+// nodeRef is a YAML node a value was parsed from. Shared nodes
+// are reached through an alias or a merge key, so they may also
+// be used by other plugins or parameters.
+type nodeRef struct {
+	node   *yaml.Node
+	shared bool
+}
+
+// pluginSource links plugin config back to YAML nodes it was
+// parsed from. Nodes are never modified once parsed, so plugin
+// configs cloned from each other can share the same source.
+type pluginSource struct {
+	file   string
+	node   nodeRef
+	params map[string]nodeRef
+	order  []string
+}
+
+// configSource links config back to the YAML document it was
+// parsed from, so that comments can be preserved when it is saved
+type configSource struct {
+	file    string
+	doc     *yaml.Node
+	plugins []*pluginSource
+	chains  map[string][]*pluginSource
+}
+
+// pos returns position of a node within source file
+func (s *pluginSource) pos(n *yaml.Node) SourcePos {
+	return SourcePos{File: s.file, Line: n.Line, Column: n.Column}
+}
+
+// resolveAlias follows aliases until it reaches the actual node
+func resolveAlias(n *yaml.Node) (*yaml.Node, bool) {
+	aliased := false
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+		aliased = true
+	}
+	return n, aliased
+}
+
+// mappingNodes returns value nodes of a mapping, resolving merge
+// keys the same way the decoder does: keys specified explicitly
+// take precedence over merged ones, and earlier merged mappings
+// take precedence over later ones
+func mappingNodes(n *yaml.Node, shared bool) map[string]nodeRef {
+	res := make(map[string]nodeRef)
+	n, aliased := resolveAlias(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return res
+	}
+	shared = shared || aliased
+
+	merged := make([]*yaml.Node, 0)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Tag == "!!merge" {
+			v, _ = resolveAlias(v)
+			if v != nil && v.Kind == yaml.SequenceNode {
+				merged = append(merged, v.Content...)
+			} else {
+				merged = append(merged, v)
+			}
+			continue
+		}
+		v, a := resolveAlias(v)
+		res[k.Value] = nodeRef{node: v, shared: shared || a}
+	}
+	for _, m := range merged {
+		for k, v := range mappingNodes(m, true) {
+			if _, ok := res[k]; !ok {
+				res[k] = v
+			}
+		}
+	}
+	return res
+}
+
+// pluginSources returns sources of plugin entries in a parsed
+// document, in the same order as they were decoded. Fields are
+// looked up by the names they have in the file.
+func pluginSources(file string, doc *yaml.Node, fields FieldMap) []*pluginSource {
+	if len(doc.Content) == 0 {
+		return make([]*pluginSource, 0)
+	}
+	plugins, ok := mappingNodes(doc.Content[0], false)[fields.fileName("plugins")]
+	if !ok {
+		return make([]*pluginSource, 0)
+	}
+	return sequenceSources(file, plugins, fields)
+}
+
+// chainSources returns sources of plugins of named chains
+func chainSources(file string, doc *yaml.Node, fields FieldMap) map[string][]*pluginSource {
+	res := make(map[string][]*pluginSource)
+	if len(doc.Content) == 0 {
+		return res
+	}
+	chains, ok := mappingNodes(doc.Content[0], false)[fields.fileName("chains")]
+	if !ok {
+		return res
+	}
+	for name, plugins := range mappingNodes(chains.node, chains.shared) {
+		res[name] = sequenceSources(file, plugins, fields)
+	}
+	return res
+}
+
+// sequenceSources returns sources of plugins in a sequence node
+func sequenceSources(file string, plugins nodeRef, fields FieldMap) []*pluginSource {
+	res := make([]*pluginSource, 0)
+	if plugins.node == nil || plugins.node.Kind != yaml.SequenceNode {
+		return res
+	}
+	for _, item := range plugins.node.Content {
+		n, aliased := resolveAlias(item)
+		shared := plugins.shared || aliased
+		src := &pluginSource{
+			file:   file,
+			node:   nodeRef{node: n, shared: shared},
+			params: make(map[string]nodeRef),
+		}
+		if params, ok := mappingNodes(n, shared)[fields.fileName("parameters")]; ok {
+			src.params = mappingNodes(params.node, params.shared)
+			src.order = mappingOrder(params.node)
+		}
+		res = append(res, src)
+	}
+	return res
+}
+
+// Pos returns position of plugin entry in the file it was
+// read from, if the plugin was read from a file
+func (pc *LV2PluginConfig) Pos() (SourcePos, bool) {
+	if pc.source == nil {
+		return SourcePos{}, false
+	}
+	return pc.source.pos(pc.source.node.node), true
+}
+
+// ParamPos returns position of parameter value in the file
+// plugin was read from, if the parameter was read from a file
+func (pc *LV2PluginConfig) ParamPos(symbol string) (SourcePos, bool) {
+	if pc.source == nil {
+		return SourcePos{}, false
+	}
+	ref, ok := pc.source.params[symbol]
+	if !ok {
+		return SourcePos{}, false
+	}
+	return pc.source.pos(ref.node), true
+}
@@ -0,0 +1,118 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// hostState is runtime state of a config, as saved by SaveState.
+// Overrides are keyed by plugin identifier and parameter symbol.
+type hostState struct {
+	Values        map[string]interface{}        `yaml:"values,omitempty"`
+	Scene         string                        `yaml:"scene,omitempty"`
+	Tempo         float64                       `yaml:"tempo,omitempty"`
+	TimeSignature string                        `yaml:"timeSignature,omitempty"`
+	Overrides     map[string]map[string]float32 `yaml:"overrides,omitempty"`
+}
+
+// id returns identifier plugin can be looked up by
+func (pc *LV2PluginConfig) id() string {
+	if pc.Name != "" {
+		return pc.Name
+	}
+	if pc.UUID != "" {
+		return pc.UUID
+	}
+	return pc.PluginURI
+}
+
+// SaveState writes runtime state of the config to w: values in
+// ValueMap (apart from those set by the parser), last applied
+// scene, tempo and overrides. State is kept separately from the
+// config file, so that the host can restore its runtime tweaks
+// after a restart while keeping the shared config pristine. All
+// values must be representable as YAML.
+func (c *LV2HostConfig) SaveState(w io.Writer) error {
+	state := hostState{
+		Values:        make(map[string]interface{}),
+		Scene:         c.Scene,
+		Tempo:         c.Tempo,
+		TimeSignature: c.TimeSignature,
+	}
+	for k, v := range c.ValueMap {
+		if !reservedVariables[k] {
+			state.Values[k] = v
+		}
+	}
+	for i := range c.Plugins {
+		pc := &c.Plugins[i]
+		if len(pc.overrides) == 0 {
+			continue
+		}
+		id := c.InstanceID(i)
+		if j, _ := c.findPluginIndex(id); j != i {
+			return fmt.Errorf("Failed to save state: overridden plugin '%v' can't be identified uniquely", id)
+		}
+		if state.Overrides == nil {
+			state.Overrides = make(map[string]map[string]float32)
+		}
+		state.Overrides[id] = copyValues(pc.overrides)
+	}
+	data, err := yaml.Marshal(&state)
+	if err != nil {
+		return fmt.Errorf("Failed to save state: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("Failed to save state: %v", err)
+	}
+	return nil
+}
+
+// LoadState restores runtime state saved by SaveState. Values are
+// added to (or replace existing values in) ValueMap, the scene is
+// applied, and overrides replace any current ones. Numbers are
+// restored as int or float64 values, whatever type they were
+// saved as. Loading is atomic. Config must be re-evaluated for
+// restored values and scene to take effect.
+func (c *LV2HostConfig) LoadState(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("Failed to load state: %v", err)
+	}
+	var state hostState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("Failed to load state: %v", err)
+	}
+
+	// loading should be atomic, so operate on a copy
+	nc := c.Clone()
+	for k, v := range state.Values {
+		if err := nc.SetVariable(k, v); err != nil {
+			return fmt.Errorf("Failed to load state: %v", err)
+		}
+	}
+	if state.Tempo != 0 {
+		if err := checkTempo(state.Tempo, state.TimeSignature); err != nil {
+			return fmt.Errorf("Failed to load state: %v", err)
+		}
+		nc.Tempo, nc.TimeSignature = state.Tempo, state.TimeSignature
+	}
+	if state.Scene != "" {
+		if err := nc.ApplyScene(state.Scene); err != nil {
+			return fmt.Errorf("Failed to load state: %v", err)
+		}
+	}
+	nc.ClearOverrides()
+	for plugin, params := range state.Overrides {
+		for symbol, v := range params {
+			if err := nc.SetOverride(plugin, symbol, v); err != nil {
+				return fmt.Errorf("Failed to load state: %v", err)
+			}
+		}
+	}
+	*c = *nc
+	return nil
+}
@@ -0,0 +1,77 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// keyPos returns position of a key in a mapping node
+func keyPos(n *yaml.Node, key string) (int, int, bool) {
+	n, _ = resolveAlias(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return 0, 0, false
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i].Line, n.Content[i].Column, true
+		}
+	}
+	return 0, 0, false
+}
+
+// unknownField returns an error about the first (in sorted order)
+// unrecognized field, if there are any
+func unknownField(extras map[string]interface{}, file string, n *yaml.Node, where string) error {
+	if len(extras) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(extras))
+	for k := range extras {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	ce := &ConfigError{File: file, Err: fmt.Errorf("Failed to parse config: unknown field '%v' %v", names[0], where)}
+	if n != nil {
+		ce.Line, ce.Column, _ = keyPos(n, names[0])
+	}
+	return ce
+}
+
+// checkStrict makes sure there are no unrecognized fields at top
+// level or in plugin entries of a parsed config
+func checkStrict(raw *lv2HostRaw, sources *configSource) error {
+	var file string
+	var top *yaml.Node
+	if sources != nil {
+		file = sources.file
+		if len(sources.doc.Content) > 0 {
+			top = sources.doc.Content[0]
+		}
+	}
+	if err := unknownField(raw.Extras, file, top, "at top level"); err != nil {
+		return err
+	}
+	for i, rpd := range raw.Plugins {
+		var n *yaml.Node
+		if sources != nil && i < len(sources.plugins) {
+			n = sources.plugins[i].node.node
+		}
+		if err := unknownField(rpd.Extras, file, n, fmt.Sprintf("in plugin #%v", i+1)); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedKeys(raw.Chains) {
+		for i, rpd := range raw.Chains[name] {
+			var n *yaml.Node
+			if sources != nil && i < len(sources.chains[name]) {
+				n = sources.chains[name][i].node.node
+			}
+			if err := unknownField(rpd.Extras, file, n, fmt.Sprintf("in plugin #%v of chain '%v'", i+1, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
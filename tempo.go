@@ -0,0 +1,219 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultTimeSignature is used when tempo is set without time signature
+const DefaultTimeSignature = "4/4"
+
+// tempoVariables are variables set from tempo and time signature
+var tempoVariables = []string{"bpm", "beat_ms", "bar_ms"}
+
+// parseTimeSignature parses time signature such as "6/8" into number
+// of beats per bar and note value of a beat
+func parseTimeSignature(sig string) (int, int, error) {
+	if sig == "" {
+		sig = DefaultTimeSignature
+	}
+	parts := strings.Split(sig, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid time signature '%v'", sig)
+	}
+	beats, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || beats <= 0 {
+		return 0, 0, fmt.Errorf("Invalid time signature '%v'", sig)
+	}
+	unit, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || unit <= 0 {
+		return 0, 0, fmt.Errorf("Invalid time signature '%v'", sig)
+	}
+	return beats, unit, nil
+}
+
+// checkTempo makes sure tempo settings are valid
+func checkTempo(bpm float64, sig string) error {
+	if bpm < 0 {
+		return fmt.Errorf("Tempo %v is negative", bpm)
+	}
+	_, _, err := parseTimeSignature(sig)
+	return err
+}
+
+// tempoValues returns values of tempo variables, or nothing if
+// tempo is not set. Tempo is in beats per minute, where a beat is
+// the note value of the time signature.
+func (c *LV2HostConfig) tempoValues() (map[string]interface{}, error) {
+	if c.Tempo == 0 {
+		return nil, nil
+	}
+	if err := checkTempo(c.Tempo, c.TimeSignature); err != nil {
+		return nil, err
+	}
+	beats, _, _ := parseTimeSignature(c.TimeSignature)
+	beat := 60000 / c.Tempo
+	return map[string]interface{}{
+		"bpm":     c.Tempo,
+		"beat_ms": beat,
+		"bar_ms":  beat * float64(beats),
+	}, nil
+}
+
+// dependsOn checks if an expression references any of names
+func (c *LV2HostConfig) dependsOn(value string, names map[string]bool) (bool, error) {
+	if _, err := strconv.ParseFloat(value, 32); err == nil {
+		return false, nil
+	}
+	expr, err := c.compileExpr(value)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range expr.Vars() {
+		if names[name] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tempoNames returns names of variables that depend on tempo: tempo
+// variables themselves, and references to parameters that depend on
+// tempo, directly or through other references. vars must not have
+// references resolved yet.
+func (c *LV2HostConfig) tempoNames(vars map[string]interface{}) map[string]bool {
+	names := make(map[string]bool)
+	for _, v := range tempoVariables {
+		names[v] = true
+	}
+	g := c.dependencies(vars)
+	// components come after everything they depend on
+	for _, comp := range g.Components() {
+		for _, r := range comp {
+			if dep, err := c.dependsOn(g.exprs[r], names); err == nil && dep {
+				for _, name := range g.names[r] {
+					names[name] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// SetTempo changes tempo (and time signature, unless sig is empty)
+// of the config. If the config was evaluated, only the values that
+// depend on tempo variables (directly, or by referring to other
+// parameters that do) are re-evaluated, and validators are
+// run on the result. Frozen parameters keep their values. Returns
+// parameters that have been re-evaluated, as ActionSetParam
+// actions (with Chain set for plugins of named chains), so that the
+// host can pass them on to running plugins. Like Evaluate, this is
+// atomic.
+func (c *LV2HostConfig) SetTempo(bpm float64, sig string) ([]PlanAction, error) {
+	if sig == "" {
+		sig = c.TimeSignature
+	}
+	if bpm <= 0 {
+		return nil, fmt.Errorf("Tempo %v is not positive", bpm)
+	}
+	if err := checkTempo(bpm, sig); err != nil {
+		return nil, err
+	}
+	if c.EvaluatedAt.IsZero() {
+		c.Tempo, c.TimeSignature = bpm, sig
+		return nil, nil
+	}
+
+	nc := c.cloneEmpty()
+	nc.Tempo, nc.TimeSignature = bpm, sig
+	// references are resolved against current plugins
+	nc.Plugins = c.Plugins
+	vars, err := nc.baseValues()
+	if err != nil {
+		return nil, err
+	}
+	names := nc.tempoNames(vars)
+	if err := nc.resolveReferences(vars); err != nil {
+		return nil, err
+	}
+	run := nc.newEvalRun()
+
+	// changing tempo should be atomic, so re-evaluate into copies
+	changes := make([]PlanAction, 0)
+	pcs, err := nc.retempoChain("", c.Plugins, vars, names, run, &changes)
+	if err != nil {
+		return nil, err
+	}
+	chains, err := c.mapChains(func(name string, chain []LV2PluginConfig) ([]LV2PluginConfig, error) {
+		return nc.retempoChain(name, chain, vars, names, run, &changes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.validate(pcs); err != nil {
+		return nil, err
+	}
+	if err := c.validateChains(chains); err != nil {
+		return nil, err
+	}
+
+	c.Plugins = pcs
+	c.Chains = chains
+	c.Tempo, c.TimeSignature = bpm, sig
+	c.EvaluatedAt = c.now()
+	return changes, nil
+}
+
+// retempoChain re-evaluates values of a chain that depend on names,
+// adding changed parameters to changes
+func (c *LV2HostConfig) retempoChain(name string, chain []LV2PluginConfig, vars map[string]interface{}, names map[string]bool, run *evalRun, changes *[]PlanAction) ([]LV2PluginConfig, error) {
+	pcs := make([]LV2PluginConfig, 0, len(chain))
+	for i := range chain {
+		run.plugin = i
+		pc := chain[i].clone()
+		ports := c.rangePorts(&pc)
+		for _, param := range sortedKeys(pc.Data) {
+			value, ok := pc.DataFmt[param]
+			if !ok || pc.Frozen[param] {
+				continue
+			}
+			dep, err := c.dependsOn(value, names)
+			if err != nil || !dep {
+				continue
+			}
+			result, err := c.evaluateTimed(run, param, value, vars)
+			if err == nil {
+				result, err = c.resolveValue(&pc, param, result, ports)
+			}
+			if err != nil {
+				return nil, pc.valueError(param, value, err)
+			}
+			if _, ok := pc.overrides[param]; ok {
+				pc.shadowed[param] = result
+				continue
+			}
+			pc.Data[param] = result
+			*changes = append(*changes, PlanAction{Kind: ActionSetParam, Chain: name, Plugin: i, Symbol: param, Value: result})
+		}
+		for port, cv := range pc.CVFmt {
+			if dep, err := c.dependsOn(cv.Value, names); err != nil || !dep {
+				continue
+			}
+			result, err := c.evaluateTimed(run, port, cv.Value, vars)
+			if err != nil {
+				return nil, pc.valueError(port, cv.Value, err)
+			}
+			pc.CV[port] = result
+		}
+		if pc.StartOffsetFmt != "" {
+			if dep, err := c.dependsOn(pc.StartOffsetFmt, names); err == nil && dep {
+				if err := c.evaluateStartOffset(&pc, vars, run); err != nil {
+					return nil, err
+				}
+			}
+		}
+		pcs = append(pcs, pc)
+	}
+	return pcs, nil
+}
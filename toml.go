@@ -0,0 +1,61 @@
+package lv2hostconfig
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// parseTOML parses a TOML config. TOML is converted into a YAML
+// document, so that it is decoded the same way YAML configs are.
+// Positions of values are not known in that case.
+func parseTOML(data []byte, fields FieldMap) (*lv2HostRaw, *configSource, error) {
+	var v map[string]interface{}
+	if err := toml.Unmarshal(data, &v); err != nil {
+		return nil, nil, fmt.Errorf("Failed to parse config: %v", err)
+	}
+	var top yaml.Node
+	if err := top.Encode(v); err != nil {
+		return nil, nil, fmt.Errorf("Failed to parse config: %v", err)
+	}
+	doc := yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&top}}
+	host, err := decodeConfig("", &doc, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return host, nil, nil
+}
+
+// encodeTOML encodes a document as TOML, via generic values
+func encodeTOML(doc *yaml.Node) ([]byte, error) {
+	var v map[string]interface{}
+	if err := doc.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadFileTOML reads a TOML config, regardless of file extension
+func (c *LV2HostConfig) ReadFileTOML(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read config: %v", err)
+	}
+	defer f.Close()
+	_, err = c.readFrom(f, file, FormatTOML)
+	return err
+}
+
+// WriteToFileTOML writes config in TOML form, regardless of file
+// extension
+func (c *LV2HostConfig) WriteToFileTOML(file string) error {
+	return c.writeFile(file, FormatTOML)
+}
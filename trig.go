@@ -0,0 +1,50 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Knetic/govaluate"
+	"github.com/burillo-se/lv2hostconfig/convert"
+)
+
+// unaryFunc wraps a single-argument math function into an
+// expression function
+func unaryFunc(name string, f func(float64) float64) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return math.NaN(), fmt.Errorf("Function '%v' expects exactly 1 argument", name)
+		}
+		v, err := convert.Float32(args[0])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
+		}
+		return f(float64(v)), nil
+	}
+}
+
+func setUpTrigFuncs(lvc *LV2HostConfig) {
+	lvc.FunctionMap["sin"] = unaryFunc("sin", math.Sin)
+	lvc.FunctionMap["cos"] = unaryFunc("cos", math.Cos)
+	lvc.FunctionMap["tan"] = unaryFunc("tan", math.Tan)
+	lvc.FunctionMap["deg"] = unaryFunc("deg", func(rad float64) float64 {
+		return rad * 180 / math.Pi
+	})
+	lvc.FunctionMap["rad"] = unaryFunc("rad", func(deg float64) float64 {
+		return deg * math.Pi / 180
+	})
+	lvc.FunctionMap["atan2"] = func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return math.NaN(), fmt.Errorf("Function 'atan2' expects exactly 2 arguments")
+		}
+		y, err := convert.Float32(args[0])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[0])
+		}
+		x, err := convert.Float32(args[1])
+		if err != nil {
+			return math.NaN(), fmt.Errorf("Value '%v' was not a float", args[1])
+		}
+		return math.Atan2(float64(y), float64(x)), nil
+	}
+}
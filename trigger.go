@@ -0,0 +1,111 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"sort"
+)
+
+// scene trigger types
+const (
+	TriggerProgram = "program"
+	TriggerNote    = "note"
+)
+
+// LV2SceneTrigger is a MIDI message that triggers a scene: either
+// a program change (Number is program number) or a note on (Number
+// is note number). Channel is 1-16, or 0 to match any channel.
+type LV2SceneTrigger struct {
+	Type    string `yaml:"type"`
+	Channel int    `yaml:"channel,omitempty"`
+	Number  int    `yaml:"number"`
+}
+
+func (t LV2SceneTrigger) String() string {
+	if t.Channel == 0 {
+		return fmt.Sprintf("%v %v", t.Type, t.Number)
+	}
+	return fmt.Sprintf("%v %v on channel %v", t.Type, t.Number, t.Channel)
+}
+
+func (t LV2SceneTrigger) check() error {
+	if t.Type != TriggerProgram && t.Type != TriggerNote {
+		return fmt.Errorf("Unknown trigger type '%v'", t.Type)
+	}
+	if t.Channel < 0 || t.Channel > 16 {
+		return fmt.Errorf("Trigger channel %v is out of range", t.Channel)
+	}
+	if t.Number < 0 || t.Number > 127 {
+		return fmt.Errorf("Trigger number %v is out of range", t.Number)
+	}
+	return nil
+}
+
+// matches checks if trigger matches a message on a given channel
+func (t LV2SceneTrigger) matches(typ string, channel, number int) bool {
+	return t.Type == typ && t.Number == number && (t.Channel == 0 || t.Channel == channel)
+}
+
+// overlaps checks if both triggers can match the same message
+func (t LV2SceneTrigger) overlaps(o LV2SceneTrigger) bool {
+	return t.Type == o.Type && t.Number == o.Number &&
+		(t.Channel == 0 || o.Channel == 0 || t.Channel == o.Channel)
+}
+
+// SceneBinding binds a trigger to the scene it triggers
+type SceneBinding struct {
+	Scene   string
+	Trigger LV2SceneTrigger
+}
+
+// SceneBindings returns triggers of all scenes, sorted by scene
+// name. Triggers are checked, and bindings where the same message
+// would trigger different scenes are reported as errors.
+func (c *LV2HostConfig) SceneBindings() ([]SceneBinding, error) {
+	names := make([]string, 0, len(c.Scenes))
+	for name := range c.Scenes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bindings := make([]SceneBinding, 0)
+	for _, name := range names {
+		for _, t := range c.Scenes[name].Triggers {
+			if err := t.check(); err != nil {
+				return nil, fmt.Errorf("Scene '%v': %v", name, err)
+			}
+			for _, b := range bindings {
+				if b.Scene != name && b.Trigger.overlaps(t) {
+					return nil, fmt.Errorf("Scenes '%v' and '%v' are both triggered by %v", b.Scene, name, t)
+				}
+			}
+			bindings = append(bindings, SceneBinding{name, t})
+		}
+	}
+	return bindings, nil
+}
+
+// sceneFor finds scene triggered by a message
+func (c *LV2HostConfig) sceneFor(typ string, channel, number int) (string, bool) {
+	bindings, err := c.SceneBindings()
+	if err != nil {
+		return "", false
+	}
+	for _, b := range bindings {
+		if b.Trigger.matches(typ, channel, number) {
+			return b.Scene, true
+		}
+	}
+	return "", false
+}
+
+// SceneForProgram returns name of the scene triggered by program
+// change on a given channel (1-16), if there is one
+func (c *LV2HostConfig) SceneForProgram(channel, program int) (string, bool) {
+	return c.sceneFor(TriggerProgram, channel, program)
+}
+
+// SceneForNote returns name of the scene triggered by note on
+// a given channel (1-16), if there is one
+func (c *LV2HostConfig) SceneForNote(channel, note int) (string, bool) {
+	return c.sceneFor(TriggerNote, channel, note)
+}
@@ -0,0 +1,647 @@
+// Package turtle is a small parser for Turtle, the RDF syntax LV2
+// plugin metadata is written in. It supports everything commonly
+// found in LV2 bundles: prefixes, base IRIs, prefixed names, blank
+// node property lists, collections and all kinds of literals.
+package turtle
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Well-known IRIs
+const (
+	RDFType  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	RDFFirst = "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"
+	RDFRest  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"
+	RDFNil   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"
+
+	XSDString  = "http://www.w3.org/2001/XMLSchema#string"
+	XSDBoolean = "http://www.w3.org/2001/XMLSchema#boolean"
+	XSDInteger = "http://www.w3.org/2001/XMLSchema#integer"
+	XSDDecimal = "http://www.w3.org/2001/XMLSchema#decimal"
+	XSDDouble  = "http://www.w3.org/2001/XMLSchema#double"
+)
+
+// TermKind is a kind of RDF term
+type TermKind int
+
+// kinds of RDF terms
+const (
+	IRI TermKind = iota
+	Blank
+	Literal
+)
+
+// Term is an RDF term. Value is the IRI, blank node label or
+// literal value, and Datatype and Lang are only set for literals.
+type Term struct {
+	Kind     TermKind
+	Value    string
+	Datatype string
+	Lang     string
+}
+
+func (t Term) String() string {
+	switch t.Kind {
+	case IRI:
+		return "<" + t.Value + ">"
+	case Blank:
+		return "_:" + t.Value
+	}
+	s := strconv.Quote(t.Value)
+	if t.Lang != "" {
+		return s + "@" + t.Lang
+	}
+	if t.Datatype != "" && t.Datatype != XSDString {
+		return s + "^^<" + t.Datatype + ">"
+	}
+	return s
+}
+
+// Float returns value of a numeric literal
+func (t Term) Float() (float64, bool) {
+	if t.Kind != Literal {
+		return 0, false
+	}
+	switch t.Datatype {
+	case XSDInteger, XSDDecimal, XSDDouble:
+	default:
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(t.Value, 64)
+	return v, err == nil
+}
+
+// Triple is a single RDF statement
+type Triple struct {
+	Subject   Term
+	Predicate Term
+	Object    Term
+}
+
+// SyntaxError is an error in Turtle document
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Turtle syntax error on line %v: %v", e.Line, e.Msg)
+}
+
+type parser struct {
+	src      []rune
+	pos      int
+	line     int
+	base     *url.URL
+	prefixes map[string]string
+	blanks   int
+	triples  []Triple
+}
+
+// Parse parses a Turtle document. Relative IRIs are resolved against
+// base, which is usually URL of the file the document was read from.
+func Parse(r io.Reader, base string) ([]Triple, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read Turtle document: %v", err)
+	}
+	p := &parser{
+		src:      []rune(string(data)),
+		line:     1,
+		prefixes: make(map[string]string),
+	}
+	if base != "" {
+		if p.base, err = url.Parse(base); err != nil {
+			return nil, fmt.Errorf("Invalid base IRI '%v': %v", base, err)
+		}
+	}
+	if err := p.document(); err != nil {
+		return nil, err
+	}
+	return p.triples, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{p.line, fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) next() rune {
+	r := p.src[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+// skip skips whitespace and comments
+func (p *parser) skip() {
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case r == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		case unicode.IsSpace(r):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) expect(r rune) error {
+	p.skip()
+	if p.eof() || p.peek() != r {
+		return p.errorf("expected '%c'", r)
+	}
+	p.next()
+	return nil
+}
+
+// keyword checks for a case-insensitive keyword followed by
+// whitespace, consuming it if it's there
+func (p *parser) keyword(kw string) bool {
+	n := len(kw)
+	if p.pos+n >= len(p.src) {
+		return false
+	}
+	if !strings.EqualFold(string(p.src[p.pos:p.pos+n]), kw) || !unicode.IsSpace(p.src[p.pos+n]) {
+		return false
+	}
+	p.pos += n
+	return true
+}
+
+func (p *parser) document() error {
+	for {
+		p.skip()
+		if p.eof() {
+			return nil
+		}
+		if err := p.statement(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) statement() error {
+	switch {
+	case p.peek() == '@':
+		p.next()
+		switch {
+		case p.keyword("prefix"):
+			if err := p.prefix(); err != nil {
+				return err
+			}
+		case p.keyword("base"):
+			if err := p.baseIRI(); err != nil {
+				return err
+			}
+		default:
+			return p.errorf("unknown directive")
+		}
+		return p.expect('.')
+	case p.keyword("PREFIX"):
+		return p.prefix()
+	case p.keyword("BASE"):
+		return p.baseIRI()
+	}
+	subject, err := p.subject()
+	if err != nil {
+		return err
+	}
+	p.skip()
+	// a blank node property list may stand on its own
+	if subject.Kind != Blank || p.peek() != '.' {
+		if err := p.predicateObjectList(subject); err != nil {
+			return err
+		}
+	}
+	return p.expect('.')
+}
+
+func (p *parser) prefix() error {
+	p.skip()
+	start := p.pos
+	for !p.eof() && p.peek() != ':' {
+		if unicode.IsSpace(p.peek()) {
+			return p.errorf("invalid prefix name")
+		}
+		p.next()
+	}
+	if p.eof() {
+		return p.errorf("expected ':'")
+	}
+	name := string(p.src[start:p.pos])
+	p.next()
+	p.skip()
+	iri, err := p.iriRef()
+	if err != nil {
+		return err
+	}
+	p.prefixes[name] = iri
+	return nil
+}
+
+func (p *parser) baseIRI() error {
+	p.skip()
+	iri, err := p.iriRef()
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(iri)
+	if err != nil {
+		return p.errorf("invalid base IRI '%v'", iri)
+	}
+	p.base = u
+	return nil
+}
+
+// resolve resolves relative IRI against base
+func (p *parser) resolve(iri string) string {
+	if p.base == nil {
+		return iri
+	}
+	u, err := url.Parse(iri)
+	if err != nil || u.IsAbs() {
+		return iri
+	}
+	res := p.base.ResolveReference(u).String()
+	// url drops empty fragments, which namespaces often end with
+	if strings.HasSuffix(iri, "#") && !strings.HasSuffix(res, "#") {
+		res += "#"
+	}
+	return res
+}
+
+func (p *parser) iriRef() (string, error) {
+	if p.peek() != '<' {
+		return "", p.errorf("expected IRI")
+	}
+	p.next()
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated IRI")
+		}
+		r := p.next()
+		if r == '>' {
+			break
+		}
+		if r == '\\' {
+			u, err := p.unicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			r = u
+		}
+		b.WriteRune(r)
+	}
+	return p.resolve(b.String()), nil
+}
+
+func (p *parser) unicodeEscape() (rune, error) {
+	if p.eof() {
+		return 0, p.errorf("invalid escape")
+	}
+	n := 0
+	switch p.next() {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		return 0, p.errorf("invalid escape")
+	}
+	if p.pos+n > len(p.src) {
+		return 0, p.errorf("invalid escape")
+	}
+	v, err := strconv.ParseUint(string(p.src[p.pos:p.pos+n]), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(v)) {
+		return 0, p.errorf("invalid escape")
+	}
+	p.pos += n
+	return rune(v), nil
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// name reads a prefixed name or a keyword
+func (p *parser) name() string {
+	start := p.pos
+	for !p.eof() && (isNameChar(p.peek()) || p.peek() == ':' || p.peek() == '%' || p.peek() == '\\') {
+		if p.peek() == '\\' {
+			p.next()
+		}
+		p.next()
+	}
+	// names can't end with a dot, which ends the statement
+	for p.pos > start && p.src[p.pos-1] == '.' {
+		p.pos--
+	}
+	return strings.ReplaceAll(string(p.src[start:p.pos]), "\\", "")
+}
+
+func (p *parser) prefixedName() (Term, error) {
+	name := p.name()
+	if name == "" {
+		return Term{}, p.errorf("expected a term")
+	}
+	i := strings.IndexByte(name, ':')
+	if i < 0 {
+		return Term{}, p.errorf("unexpected '%v'", name)
+	}
+	if name[:i] == "_" {
+		return Term{Kind: Blank, Value: "b" + name[i+1:]}, nil
+	}
+	ns, ok := p.prefixes[name[:i]]
+	if !ok {
+		return Term{}, p.errorf("undefined prefix '%v'", name[:i])
+	}
+	return Term{Kind: IRI, Value: ns + name[i+1:]}, nil
+}
+
+func (p *parser) newBlank() Term {
+	p.blanks++
+	return Term{Kind: Blank, Value: fmt.Sprintf("genid%v", p.blanks)}
+}
+
+func (p *parser) emit(s, pr, o Term) {
+	p.triples = append(p.triples, Triple{s, pr, o})
+}
+
+func (p *parser) subject() (Term, error) {
+	p.skip()
+	switch p.peek() {
+	case '<':
+		iri, err := p.iriRef()
+		return Term{Kind: IRI, Value: iri}, err
+	case '[':
+		return p.blankPropertyList()
+	case '(':
+		return p.collection()
+	}
+	return p.prefixedName()
+}
+
+func (p *parser) predicate() (Term, error) {
+	p.skip()
+	if p.peek() == 'a' && p.pos+1 < len(p.src) && !isNameChar(p.src[p.pos+1]) && p.src[p.pos+1] != ':' {
+		p.next()
+		return Term{Kind: IRI, Value: RDFType}, nil
+	}
+	if p.peek() == '<' {
+		iri, err := p.iriRef()
+		return Term{Kind: IRI, Value: iri}, err
+	}
+	return p.prefixedName()
+}
+
+func (p *parser) predicateObjectList(subject Term) error {
+	for {
+		pred, err := p.predicate()
+		if err != nil {
+			return err
+		}
+		for {
+			obj, err := p.object()
+			if err != nil {
+				return err
+			}
+			p.emit(subject, pred, obj)
+			p.skip()
+			if p.peek() != ',' {
+				break
+			}
+			p.next()
+		}
+		if p.peek() != ';' {
+			return nil
+		}
+		for p.peek() == ';' {
+			p.next()
+			p.skip()
+		}
+		// trailing semicolons are allowed
+		if p.eof() || p.peek() == '.' || p.peek() == ']' {
+			return nil
+		}
+	}
+}
+
+func (p *parser) blankPropertyList() (Term, error) {
+	p.next()
+	b := p.newBlank()
+	p.skip()
+	if p.peek() == ']' {
+		p.next()
+		return b, nil
+	}
+	if err := p.predicateObjectList(b); err != nil {
+		return b, err
+	}
+	return b, p.expect(']')
+}
+
+func (p *parser) collection() (Term, error) {
+	p.next()
+	items := make([]Term, 0)
+	for {
+		p.skip()
+		if p.eof() {
+			return Term{}, p.errorf("unterminated collection")
+		}
+		if p.peek() == ')' {
+			p.next()
+			break
+		}
+		item, err := p.object()
+		if err != nil {
+			return Term{}, err
+		}
+		items = append(items, item)
+	}
+	head := Term{Kind: IRI, Value: RDFNil}
+	for i := len(items) - 1; i >= 0; i-- {
+		b := p.newBlank()
+		p.emit(b, Term{Kind: IRI, Value: RDFFirst}, items[i])
+		p.emit(b, Term{Kind: IRI, Value: RDFRest}, head)
+		head = b
+	}
+	return head, nil
+}
+
+func (p *parser) object() (Term, error) {
+	p.skip()
+	r := p.peek()
+	switch {
+	case r == '<':
+		iri, err := p.iriRef()
+		return Term{Kind: IRI, Value: iri}, err
+	case r == '[':
+		return p.blankPropertyList()
+	case r == '(':
+		return p.collection()
+	case r == '"' || r == '\'':
+		return p.stringLiteral()
+	case r == '+' || r == '-' || unicode.IsDigit(r) ||
+		(r == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1])):
+		return p.numericLiteral()
+	}
+	if p.keyword("true") || p.ahead("true") {
+		return Term{Kind: Literal, Value: "true", Datatype: XSDBoolean}, nil
+	}
+	if p.keyword("false") || p.ahead("false") {
+		return Term{Kind: Literal, Value: "false", Datatype: XSDBoolean}, nil
+	}
+	return p.prefixedName()
+}
+
+// ahead checks for a keyword followed by punctuation, consuming it
+// if it's there
+func (p *parser) ahead(kw string) bool {
+	n := len(kw)
+	if p.pos+n > len(p.src) || string(p.src[p.pos:p.pos+n]) != kw {
+		return false
+	}
+	if p.pos+n < len(p.src) && (isNameChar(p.src[p.pos+n]) || p.src[p.pos+n] == ':') {
+		if p.src[p.pos+n] != '.' {
+			return false
+		}
+	}
+	p.pos += n
+	return true
+}
+
+func (p *parser) numericLiteral() (Term, error) {
+	start := p.pos
+	if p.peek() == '+' || p.peek() == '-' {
+		p.next()
+	}
+	datatype := XSDInteger
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case unicode.IsDigit(r):
+		case r == '.' && datatype == XSDInteger && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1]):
+			datatype = XSDDecimal
+		case r == 'e' || r == 'E':
+			datatype = XSDDouble
+			if p.pos+1 < len(p.src) && (p.src[p.pos+1] == '+' || p.src[p.pos+1] == '-') {
+				p.next()
+			}
+		default:
+			goto done
+		}
+		p.next()
+	}
+done:
+	value := string(p.src[start:p.pos])
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return Term{}, p.errorf("invalid number '%v'", value)
+	}
+	return Term{Kind: Literal, Value: value, Datatype: datatype}, nil
+}
+
+func (p *parser) stringLiteral() (Term, error) {
+	q := p.next()
+	long := false
+	if p.pos+1 < len(p.src) && p.src[p.pos] == q && p.src[p.pos+1] == q {
+		p.pos += 2
+		long = true
+	}
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return Term{}, p.errorf("unterminated string")
+		}
+		r := p.next()
+		if r == q {
+			if !long {
+				break
+			}
+			if p.pos+1 < len(p.src) && p.src[p.pos] == q && p.src[p.pos+1] == q {
+				p.pos += 2
+				break
+			}
+		}
+		if r == '\n' && !long {
+			return Term{}, p.errorf("unterminated string")
+		}
+		if r == '\\' {
+			if p.eof() {
+				return Term{}, p.errorf("unterminated string")
+			}
+			switch e := p.peek(); e {
+			case 't':
+				r = '\t'
+			case 'b':
+				r = '\b'
+			case 'n':
+				r = '\n'
+			case 'r':
+				r = '\r'
+			case 'f':
+				r = '\f'
+			case '"', '\'', '\\':
+				r = e
+			case 'u', 'U':
+				u, err := p.unicodeEscape()
+				if err != nil {
+					return Term{}, err
+				}
+				b.WriteRune(u)
+				continue
+			default:
+				return Term{}, p.errorf("invalid escape '\\%c'", e)
+			}
+			p.next()
+		}
+		b.WriteRune(r)
+	}
+	t := Term{Kind: Literal, Value: b.String(), Datatype: XSDString}
+	switch p.peek() {
+	case '@':
+		p.next()
+		start := p.pos
+		for !p.eof() && (unicode.IsLetter(p.peek()) || unicode.IsDigit(p.peek()) || p.peek() == '-') {
+			p.next()
+		}
+		t.Lang = string(p.src[start:p.pos])
+		t.Datatype = ""
+	case '^':
+		p.next()
+		if p.eof() || p.next() != '^' {
+			return Term{}, p.errorf("expected '^^'")
+		}
+		dt, err := p.predicate()
+		if err != nil {
+			return Term{}, err
+		}
+		t.Datatype = dt.Value
+	}
+	return t, nil
+}
@@ -0,0 +1,39 @@
+package lv2hostconfig
+
+// LV2PluginUI contains presentation hints for GUI hosts.
+// The config parser does not interpret any of these, it
+// only carries them along, so that hosts can persist their
+// layout alongside parameter data.
+type LV2PluginUI struct {
+	// Collapsed is whether plugin view is collapsed
+	Collapsed bool `yaml:"collapsed,omitempty"`
+	// Color is a host-defined color string, e.g. "#ff0000"
+	Color string `yaml:"color,omitempty"`
+	// Order is parameter display order, by LV2 symbol
+	Order []string `yaml:"order,omitempty"`
+	// Widgets maps LV2 symbols to host-defined widget hints
+	Widgets map[string]string `yaml:"widgets,omitempty"`
+}
+
+func (ui *LV2PluginUI) clone() *LV2PluginUI {
+	if ui == nil {
+		return nil
+	}
+	nui := LV2PluginUI{
+		ui.Collapsed,
+		ui.Color,
+		nil,
+		nil,
+	}
+	if ui.Order != nil {
+		nui.Order = make([]string, len(ui.Order))
+		copy(nui.Order, ui.Order)
+	}
+	if ui.Widgets != nil {
+		nui.Widgets = make(map[string]string)
+		for k, v := range ui.Widgets {
+			nui.Widgets[k] = v
+		}
+	}
+	return &nui
+}
@@ -0,0 +1,43 @@
+package lv2hostconfig
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// newUUID generates a random (version 4) UUID
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("Failed to generate UUID: %v", err)
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
+
+// AssignUUIDs assigns a UUID to every plugin that doesn't
+// have one, including plugins of named chains. Existing UUIDs are
+// never changed, so they stay the same across edits, reorders and
+// renames.
+func (c *LV2HostConfig) AssignUUIDs() error {
+	// assignment should be atomic, so generate UUIDs first
+	pcs := make([]*LV2PluginConfig, 0)
+	c.eachPlugin(func(pc *LV2PluginConfig) {
+		if pc.UUID == "" {
+			pcs = append(pcs, pc)
+		}
+	})
+	uuids := make([]string, len(pcs))
+	for i := range pcs {
+		u, err := newUUID()
+		if err != nil {
+			return err
+		}
+		uuids[i] = u
+	}
+	for i, pc := range pcs {
+		pc.UUID = uuids[i]
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package lv2hostconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ValidationMessage is a single complaint from a validator.
+// Plugin is identifier of plugin (name, UUID or URI) and
+// Symbol is parameter symbol, both are optional.
+type ValidationMessage struct {
+	Plugin  string `json:"plugin,omitempty"`
+	Symbol  string `json:"symbol,omitempty"`
+	Message string `json:"message"`
+}
+
+func (m ValidationMessage) String() string {
+	switch {
+	case m.Plugin != "" && m.Symbol != "":
+		return fmt.Sprintf("%v.%v: %v", m.Plugin, m.Symbol, m.Message)
+	case m.Plugin != "":
+		return fmt.Sprintf("%v: %v", m.Plugin, m.Message)
+	}
+	return m.Message
+}
+
+// ValidationError is returned when validators have vetoed
+// evaluation results
+type ValidationError struct {
+	Messages []ValidationMessage
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Messages))
+	for _, m := range e.Messages {
+		msgs = append(msgs, m.String())
+	}
+	return fmt.Sprintf("Config was rejected: %v", strings.Join(msgs, "; "))
+}
+
+// Validator checks evaluated config before it is considered
+// applied. Any messages returned will veto the config. Errors
+// are reserved for failures of validator itself.
+type Validator interface {
+	Validate(c *LV2HostConfig) ([]ValidationMessage, error)
+}
+
+// ValidatorFunc is an adapter to use ordinary functions as validators
+type ValidatorFunc func(c *LV2HostConfig) ([]ValidationMessage, error)
+
+// Validate implements Validator
+func (f ValidatorFunc) Validate(c *LV2HostConfig) ([]ValidationMessage, error) {
+	return f(c)
+}
+
+// AddValidator registers a validator with the config
+func (c *LV2HostConfig) AddValidator(v Validator) {
+	c.Validators = append(c.Validators, v)
+}
+
+// validate checks evaluated plugin data against schemas, and
+// runs validators over it
+func (c *LV2HostConfig) validate(pcs []LV2PluginConfig) error {
+	msgs := c.schemaMessages(pcs)
+	if len(c.Validators) == 0 {
+		if len(msgs) != 0 {
+			return &ValidationError{msgs}
+		}
+		return nil
+	}
+	// validators get to see evaluation results, not current config
+	nc := c.cloneEmpty()
+	nc.Plugins = pcs
+
+	for _, v := range c.Validators {
+		m, err := v.Validate(nc)
+		if err != nil {
+			return fmt.Errorf("Failed to validate config: %v", err)
+		}
+		msgs = append(msgs, m...)
+	}
+	if len(msgs) != 0 {
+		return &ValidationError{msgs}
+	}
+	return nil
+}
+
+// evaluatedPluginJSON is JSON representation of evaluated
+// plugin config, as sent to HTTP validators
+type evaluatedPluginJSON struct {
+	Name       string            `json:"name,omitempty"`
+	UUID       string            `json:"uuid,omitempty"`
+	Group      string            `json:"group,omitempty"`
+	URI        string            `json:"pluginUri"`
+	Parameters map[string]string `json:"parameters"`
+	CV         map[string]string `json:"cv,omitempty"`
+}
+
+type validationRequestJSON struct {
+	Plugins []evaluatedPluginJSON `json:"plugins"`
+}
+
+type validationResponseJSON struct {
+	Messages []ValidationMessage `json:"messages"`
+}
+
+// HTTPValidator is a validator backed by an HTTP endpoint.
+// Evaluated config is POSTed to URL as JSON, with values encoded as
+// strings, since JSON numbers can't be NaN ("NaN") or infinite
+// ("+Inf", "-Inf"):
+//
+//	{"plugins": [{"pluginUri": "...", "parameters": {"gain": "1.5"}}]}
+//
+// and the endpoint is expected to respond with messages, if any:
+//
+//	{"messages": [{"plugin": "...", "symbol": "gain", "message": "..."}]}
+//
+// If Client is nil, a client with 10 second timeout is used.
+type HTTPValidator struct {
+	URL    string
+	Client *http.Client
+}
+
+// Validate implements Validator
+func (hv *HTTPValidator) Validate(c *LV2HostConfig) ([]ValidationMessage, error) {
+	req := validationRequestJSON{make([]evaluatedPluginJSON, 0)}
+	for _, pc := range c.Plugins {
+		req.Plugins = append(req.Plugins, evaluatedPluginJSON{
+			pc.Name,
+			pc.UUID,
+			pc.Group,
+			pc.PluginURI,
+			formatValues(pc.Data),
+			formatValues(pc.CV),
+		})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize config: %v", err)
+	}
+
+	client := hv.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(hv.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to reach validator: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("Validator responded with '%v'", resp.Status)
+	}
+
+	var res validationResponseJSON
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("Failed to parse validator response: %v", err)
+	}
+	return res.Messages, nil
+}
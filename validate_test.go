@@ -0,0 +1,50 @@
+package lv2hostconfig_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+func TestHTTPValidatorNonFinite(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Plugins []struct {
+				Parameters map[string]string `json:"parameters"`
+			} `json:"plugins"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Plugins) != 1 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		got = req.Plugins[0].Parameters
+		w.Write([]byte(`{"messages": []}`))
+	}))
+	defer srv.Close()
+
+	pc := lv2hostconfig.NewLV2PluginConfig()
+	pc.PluginURI = "urn:test:eq"
+	pc.Data = map[string]float32{
+		"gain": 1.5,
+		"nan":  float32(math.NaN()),
+		"inf":  float32(math.Inf(1)),
+		"ninf": float32(math.Inf(-1)),
+	}
+	c := lv2hostconfigtest.Config(pc)
+	v := &lv2hostconfig.HTTPValidator{URL: srv.URL}
+	if _, err := v.Validate(c); err != nil {
+		t.Fatalf("Failed to validate config: %v", err)
+	}
+	want := map[string]string{"gain": "1.5", "nan": "NaN", "inf": "+Inf", "ninf": "-Inf"}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("Parameter '%v' was sent as '%v', want '%v'", k, got[k], w)
+		}
+	}
+}
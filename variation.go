@@ -0,0 +1,98 @@
+package lv2hostconfig
+
+import (
+	"fmt"
+	"math"
+)
+
+// LV2Variation is an alternative set of parameter values, which
+// is picked at random among all variations, with probability
+// proportional to its Weight. Parameters are same as those of
+// LV2Scene.
+type LV2Variation struct {
+	Name       string                       `yaml:"name,omitempty"`
+	Weight     float64                      `yaml:"weight"`
+	Parameters map[string]map[string]string `yaml:"parameters,omitempty"`
+}
+
+func (v LV2Variation) clone() LV2Variation {
+	s := LV2Scene{Parameters: v.Parameters}.clone()
+	return LV2Variation{v.Name, v.Weight, s.Parameters}
+}
+
+func copyVariations(variations []LV2Variation) []LV2Variation {
+	if len(variations) == 0 {
+		return nil
+	}
+	vs := make([]LV2Variation, 0, len(variations))
+	for _, v := range variations {
+		vs = append(vs, v.clone())
+	}
+	return vs
+}
+
+// seedFraction maps a seed to a number in [0, 1). This is done with
+// splitmix64 rather than math/rand, so that the same seed picks the
+// same variation regardless of Go version.
+func seedFraction(seed int64) float64 {
+	z := uint64(seed) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11) / (1 << 53)
+}
+
+// pickVariation picks index of a variation for a seed
+func pickVariation(variations []LV2Variation, seed int64) (int, error) {
+	total := 0.0
+	for i, v := range variations {
+		if v.Weight < 0 || math.IsNaN(v.Weight) || math.IsInf(v.Weight, 0) {
+			return -1, fmt.Errorf("Variation %v has invalid weight %v", i, v.Weight)
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return -1, fmt.Errorf("No variations to pick from")
+	}
+	x := seedFraction(seed) * total
+	last := -1
+	for i, v := range variations {
+		if v.Weight == 0 {
+			continue
+		}
+		if x < v.Weight {
+			return i, nil
+		}
+		x -= v.Weight
+		last = i
+	}
+	// rounding may leave x just past the last variation
+	return last, nil
+}
+
+// PickVariation picks one of variations at random, using seed (such
+// as current date) as the source of randomness, and applies it the
+// way ApplyScene applies scenes: formatted values of parameters are
+// replaced, except for frozen ones. Same seed always picks the same
+// variation. Applying is atomic. It returns index of the variation
+// picked. Config must be re-evaluated for new values to take effect.
+func (c *LV2HostConfig) PickVariation(seed int64) (int, error) {
+	i, err := pickVariation(c.Variations, seed)
+	if err != nil {
+		return -1, err
+	}
+	nc := c.Clone()
+	for plugin, params := range c.Variations[i].Parameters {
+		pc, err := nc.findPlugin(plugin)
+		if err != nil {
+			return -1, fmt.Errorf("Variation %v: %v", i, err)
+		}
+		for sym, value := range params {
+			if !pc.Frozen[sym] {
+				pc.DataFmt[sym] = value
+			}
+		}
+	}
+	c.Plugins = nc.Plugins
+	return i, nil
+}
@@ -0,0 +1,119 @@
+package lv2hostconfig_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burillo-se/lv2hostconfig"
+	"github.com/burillo-se/lv2hostconfig/lv2hostconfigtest"
+)
+
+// memorySource is a source kept in memory, which reports revisions
+// it is being waited on for to waiting
+type memorySource struct {
+	mu      sync.Mutex
+	data    string
+	rev     int
+	changed chan struct{}
+	waiting chan string
+}
+
+func newMemorySource(data string) *memorySource {
+	return &memorySource{data: data, changed: make(chan struct{}), waiting: make(chan string, 16)}
+}
+
+func (s *memorySource) set(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	s.rev++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *memorySource) Load(ctx context.Context) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return []byte(s.data), strconv.Itoa(s.rev), nil
+}
+
+func (s *memorySource) Wait(ctx context.Context, rev string) error {
+	s.waiting <- rev
+	for {
+		s.mu.Lock()
+		cur, changed := strconv.Itoa(s.rev), s.changed
+		s.mu.Unlock()
+		if cur != rev {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func gainConfig(gain int) string {
+	return fmt.Sprintf("plugins:\n  - pluginUri: urn:test:eq\n    parameters:\n      gain: %v\n", gain)
+}
+
+func TestWatchSourceMinInterval(t *testing.T) {
+	clock := lv2hostconfigtest.NewFakeClock(time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC))
+	c := lv2hostconfig.NewLV2HostConfig()
+	c.Clock = clock
+	src := newMemorySource(gainConfig(1))
+	applied := make(chan float32, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		policy := lv2hostconfig.ReloadPolicy{MinInterval: time.Minute, Burst: lv2hostconfig.BurstDrop}
+		done <- c.WatchSourceWith(ctx, src, lv2hostconfig.FormatYAML, policy, func(nc *lv2hostconfig.LV2HostConfig, err error) {
+			if err != nil {
+				t.Errorf("Failed to reload: %v", err)
+				return
+			}
+			applied <- nc.Plugins[0].Data["gain"]
+		})
+	}()
+	wait := func(rev string) {
+		t.Helper()
+		select {
+		case got := <-src.waiting:
+			if got != rev {
+				t.Fatalf("Source is waited on for revision %v, want %v", got, rev)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Source is not waited on for revision %v", rev)
+		}
+	}
+
+	// the first revision is applied right away
+	wait("0")
+	if got := <-applied; got != 1 {
+		t.Errorf("First revision has gain %v, want 1", got)
+	}
+	// too soon after the first one, so dropped
+	src.set(gainConfig(2))
+	wait("1")
+	clock.Advance(time.Minute)
+	src.set(gainConfig(3))
+	wait("2")
+	if got := <-applied; got != 3 {
+		t.Errorf("Revision after interval has gain %v, want 3", got)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Watching returned '%v', want '%v'", err, context.Canceled)
+	}
+	select {
+	case got := <-applied:
+		t.Errorf("Revision with gain %v was applied, want it dropped", got)
+	default:
+	}
+}